@@ -14,6 +14,7 @@ import (
 	"os"
 	"strings"
 
+	"upside-down-research.com/oss/agentic/internal/fsutil"
 	"upside-down-research.com/oss/agentic/internal/llm"
 )
 
@@ -70,7 +71,7 @@ type CodeDefinition struct {
 
 func (cd *CodeDefinition) WriteFile(superiorPath string) error {
 	dst := path.Join(superiorPath, cd.Filename)
-	return os.WriteFile(dst, []byte(cd.Content), 0644)
+	return fsutil.WriteFileAtomic(dst, []byte(cd.Content), 0644)
 }
 
 type ImplementedPlan struct {
@@ -118,12 +119,12 @@ func (runRecord *RunRecord) WriteFile(outputPath, runID string) {
 	}
 	// open file and write to
 	queryPath := runDirectory + "/query.txt"
-	err = os.WriteFile(queryPath, []byte(runRecord.Query), os.ModePerm)
+	err = fsutil.WriteFileAtomic(queryPath, []byte(runRecord.Query), 0644)
 	if err != nil {
 		log.Error("Failed to write query: ", err)
 	}
 	answerPath := runDirectory + "/answer.txt"
-	err = os.WriteFile(answerPath, []byte(runRecord.Answer), os.ModePerm)
+	err = fsutil.WriteFileAtomic(answerPath, []byte(runRecord.Answer), 0644)
 	if err != nil {
 		log.Error("Failed to write answer: ", err)
 	}
@@ -134,7 +135,7 @@ func (runRecord *RunRecord) WriteFile(outputPath, runID string) {
 		return
 	}
 	for idx, take := range runRecord.Takes {
-		err = os.WriteFile(fmt.Sprintf("%s/%d", analysisPath, idx), []byte(take), os.ModePerm)
+		err = fsutil.WriteFileAtomic(fmt.Sprintf("%s/%d", analysisPath, idx), []byte(take), 0644)
 		if err != nil {
 			log.Error("Failed to write analysis: ", err)
 		}
@@ -367,6 +368,11 @@ func main() {
 	}
 	log.Infof("Starting run, agent id: %v ", u)
 	run := NewRun(u.String(), CLI.Output)
+	runLock, err := fsutil.LockDir(path.Join(run.OutputPath, run.RunID))
+	if err != nil {
+		log.Fatal("Failed to lock run directory: ", err)
+	}
+	defer runLock.Unlock()
 	defer run.WriteData()
 
 	query := planner + "\n" + ticket
@@ -422,7 +428,7 @@ func main() {
 			log.Info("Code written to disk: ", "filename", code.Filename)
 		}
 	}
-	err = os.WriteFile(path.Join(run.OutputPath, run.RunID, "plan.txt"), plans.PrettyPrint(), 0644)
+	err = fsutil.WriteFileAtomic(path.Join(run.OutputPath, run.RunID, "plan.txt"), plans.PrettyPrint(), 0644)
 	if err != nil {
 		return
 	}
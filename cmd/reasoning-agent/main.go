@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/alecthomas/kong"
 	"github.com/charmbracelet/log"
+	"upside-down-research.com/oss/agentic/internal/fsutil"
 	"upside-down-research.com/oss/agentic/internal/goap"
 	goapactions "upside-down-research.com/oss/agentic/internal/goap/actions"
 )
@@ -16,13 +22,52 @@ import (
 // This agent orchestrates complex software engineering tasks through classical AI planning
 // and delegates content generation to LLMs.
 
+var CLI struct {
+	Explain      bool              `name:"explain" help:"Explain why the planner chose each action and which alternatives it rejected."`
+	MaxReplans   int               `name:"max-replans" help:"Replan a failed atomic goal from the actual state up to this many times (0 disables)." default:"0"`
+	MaxRerefines int               `name:"max-rerefines" help:"Re-refine a failed subtree with the failure as feedback up to this many times (0 disables)." default:"0"`
+	Workers      int               `name:"workers" help:"Number of independent subgoals to execute in parallel." default:"1"`
+	Store        string            `name:"store" help:"Plan graph store: json files per run, or a single bolt database file." enum:"json,bolt" default:"json"`
+	DryRun       bool              `name:"dry-run" help:"Simulate the plan: check preconditions and apply effects symbolically without executing actions."`
+	ActionsDir   string            `name:"actions-dir" help:"Directory of declarative action definitions (YAML or JSON) to add to the built-in actions." type:"existingdir"`
+	Goal         string            `name:"goal" help:"Name of the goal in the goal library to pursue (default: the first goal of --goal-file, else DeliverQualityFeature)."`
+	GoalFile     string            `name:"goal-file" help:"YAML or JSON file of goal definitions to add to the goal library." type:"existingfile"`
+	GoalsDir     string            `name:"goals-dir" help:"Directory of goal definitions to add to the goal library." type:"existingdir"`
+	Params       map[string]string `name:"param" short:"p" help:"Goal parameter as name=value; repeatable."`
+	MethodsDir   string            `name:"methods-dir" help:"Directory of goal decomposition methods (YAML or JSON) to add to the built-in methods." type:"existingdir"`
+
+	Run struct{} `cmd:"" default:"1" help:"Plan and execute the goal in a new run."`
+
+	Goals struct{} `cmd:"" help:"List the goals in the goal library and their parameters."`
+
+	Resume struct {
+		RunID string `arg:"" name:"run-id" help:"ID of the interrupted run to resume, e.g. run-1700000000."`
+	} `cmd:"" help:"Resume an interrupted run from its persisted plan graph and state checkpoint."`
+
+	Migrate struct{} `cmd:"" help:"Copy the plan graphs of existing JSON run directories into the bolt database."`
+
+	InvalidateRefinements struct {
+		GoalName string        `arg:"" optional:"" name:"goal-name" help:"Only invalidate the cached refinements of this goal."`
+		Expired  bool          `name:"expired" help:"Only remove refinements older than the cache TTL."`
+		TTL      time.Duration `name:"ttl" help:"Refinement cache TTL." default:"168h"`
+	} `cmd:"" help:"Remove cached goal refinements, so that goals are refined by the LLM again."`
+
+	Export struct {
+		RunID  string `arg:"" name:"run-id" help:"ID of the run whose plan graph to export."`
+		Format string `name:"format" help:"Output format." enum:"dot,mermaid,graphml" default:"dot"`
+		Out    string `name:"out" help:"File to write to (defaults to stdout)." type:"path"`
+	} `cmd:"" help:"Export a run's plan graph to Graphviz DOT, Mermaid or GraphML."`
+
+	Diff struct {
+		Before string `arg:"" name:"before-run-id" help:"ID of the earlier run."`
+		After  string `arg:"" name:"after-run-id" help:"ID of the later run."`
+		JSON   bool   `name:"json" help:"Print the diff as JSON."`
+	} `cmd:"" help:"Show how the plan changed between two runs."`
+}
+
 func main() {
 	log.SetLevel(log.InfoLevel)
-
-	fmt.Println()
-	fmt.Println("🧠 Agentic Reasoning Agent: Building a Feature with Quality Gates")
-	fmt.Println("    Philosophy: GOFAI for reasoning, LLMs for generation")
-	fmt.Println()
+	cliCtx := kong.Parse(&CLI)
 
 	// Get working directory
 	workDir, _ := os.Getwd()
@@ -35,56 +80,294 @@ func main() {
 	// PHASE 2: Create all our beautiful leaf node actions
 	availableActions := createRichActionSet(workDir)
 
-	// PHASE 3: Define our high-level goal
-	goal := goap.NewGoal(
-		"DeliverQualityFeature",
-		"Implement a feature with full quality gates: code, tests, coverage, lint, review",
-		goap.WorldState{
-			"feature_designed":         true,
-			"code_implemented":         true,
-			"tests_written":            true,
-			"go_tests_passed":          true,
-			"target_coverage_achieved": true,
-			"code_formatted":           true,
-			"lint_passed":              true,
-			"build_succeeded":          true,
-			"quality_gates_passed":     true,
-			"changes_committed":        true,
-		},
-		100.0, // High priority
-	)
+	// Declarative actions extend the repertoire without recompiling
+	actionCtx := &goapactions.ActionContext{}
+	if CLI.ActionsDir != "" {
+		declared, err := goapactions.LoadDeclarativeActions(CLI.ActionsDir, actionCtx, nil)
+		if err != nil {
+			log.Error("Failed to load declarative actions", "error", err)
+			os.Exit(1)
+		}
+		availableActions = append(availableActions, declared...)
+	}
+
+	// PHASE 3: Instantiate our high-level goal from the goal library
+	library, goalName, err := loadGoalLibrary()
+	if err != nil {
+		log.Error("Failed to load goal library", "error", err)
+		os.Exit(1)
+	}
+	if cliCtx.Command() == "goals" {
+		listGoals(library)
+		return
+	}
+
+	var goal *goap.Goal
+	if cliCtx.Command() == "run" {
+		instance, err := library.Instantiate(goalName, CLI.Params)
+		if err != nil {
+			log.Error("Failed to instantiate goal", "error", err)
+			os.Exit(1)
+		}
+		goal = instance.Goal
+		initialState.Apply(instance.State)
+		availableActions = append(availableActions, instance.Actions...)
+	}
 
 	// PHASE 4: Create the GOFAI planner (the reasoning monarch!)
 	planner := goap.NewPlanner(availableActions)
 
-	// PHASE 5: Create a simple refiner (in production, would use LLM-based goal refiner)
-	refiner := NewSimpleRefiner()
+	// PHASE 5: Decompose goals with hand-authored methods, and goals no method
+	// applies to along the dependency layers of the actions (in production,
+	// an LLM-based refiner can take the place of the dependency refiner)
+	refiner := goap.NewMethodRefiner(goap.NewDependencyRefiner(planner.Actions()))
+	if CLI.MethodsDir != "" {
+		if err := refiner.LoadDir(CLI.MethodsDir); err != nil {
+			log.Error("Failed to load methods", "error", err)
+			os.Exit(1)
+		}
+	}
 
 	// PHASE 6: Set up persistence for the reasoning agent's plan graphs
 	outputPath := "./output/reasoning-agent"
 	os.MkdirAll(outputPath, 0755)
 	persistence := goap.NewGraphPersistence(outputPath)
 
+	if CLI.Store == "bolt" || cliCtx.Command() == "migrate" {
+		store, err := goap.OpenBoltGraphStore(filepath.Join(outputPath, goap.BoltGraphFile))
+		if err != nil {
+			log.Error("Failed to open plan graph database", "error", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		persistence = goap.NewGraphPersistenceWithStore(outputPath, store)
+	}
+
+	if cliCtx.Command() == "migrate" {
+		migrated, err := goap.MigrateRuns(goap.NewJSONGraphStore(outputPath), persistence.Store())
+		if err != nil {
+			log.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		log.Info("✓ Migrated run directories", "runs", len(migrated), "database", filepath.Join(outputPath, goap.BoltGraphFile))
+		return
+	}
+
+	if strings.HasPrefix(cliCtx.Command(), "invalidate-refinements") {
+		err := invalidateRefinements(goap.NewRefinementCache(filepath.Join(outputPath, goap.RefinementCacheDir), CLI.InvalidateRefinements.TTL))
+		if err != nil {
+			log.Error("Invalidation failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cliCtx.Command() == "export <run-id>" {
+		err := exportGraph(persistence, CLI.Export.RunID, goap.GraphFormat(CLI.Export.Format), CLI.Export.Out)
+		if err != nil {
+			log.Error("Export failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cliCtx.Command() == "diff <before-run-id> <after-run-id>" {
+		err := diffRuns(persistence, CLI.Diff.Before, CLI.Diff.After, CLI.Diff.JSON)
+		if err != nil {
+			log.Error("Diff failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("🧠 Agentic Reasoning Agent: Building a Feature with Quality Gates")
+	fmt.Println("    Philosophy: GOFAI for reasoning, LLMs for generation")
+	fmt.Println()
+
 	// PHASE 7: Create the orchestrator - where GOFAI reasoning meets LLM generation
 	orchestrator := goap.NewOrchestrator(planner, refiner, persistence, 5)
+	orchestrator.SetExplain(CLI.Explain)
+	orchestrator.SetReplanLimit(CLI.MaxReplans)
+	orchestrator.SetRerefineLimit(CLI.MaxRerefines)
+	orchestrator.SetMaxWorkers(CLI.Workers)
+	orchestrator.SetDryRun(CLI.DryRun)
+	orchestrator.SetSensor(senseWorkspace(workDir))
+
+	// Rebuild serialized actions when resuming, so that actions sharing a
+	// name each run as planned
+	registry := goap.NewActionRegistry()
+	goapactions.RegisterFactories(registry, actionCtx, nil)
+	orchestrator.SetActionRegistry(registry)
 
 	// PHASE 8: Execute! GOFAI reasons the plan, LLMs generate content
 	ctx := context.Background()
 	runID := fmt.Sprintf("run-%d", time.Now().Unix())
 
-	err := orchestrator.ExecuteGoal(ctx, initialState, goal, runID)
+	switch cliCtx.Command() {
+	case "resume <run-id>":
+		runID = CLI.Resume.RunID
+		err = orchestrator.ResumeGoal(ctx, runID)
+	default:
+		err = orchestrator.ExecuteGoal(ctx, initialState, goal, runID)
+	}
 
 	if err != nil {
 		log.Error("Reasoning agent execution failed", "error", err)
 		os.Exit(1)
 	}
 
+	if CLI.DryRun {
+		log.Info("🎉 Dry run found no failing nodes")
+		return
+	}
+
 	log.Info("🎉 Reasoning agent completed successfully!")
 	fmt.Println()
-	fmt.Println("📁 Plan graph persisted at:", outputPath+"/"+runID+"/graph/")
+	if CLI.Store == "bolt" {
+		fmt.Println("📁 Plan graph persisted in:", filepath.Join(outputPath, goap.BoltGraphFile), "as run", runID)
+	} else {
+		fmt.Println("📁 Plan graph persisted at:", outputPath+"/"+runID+"/graph/")
+	}
 	fmt.Println()
 }
 
+// invalidateRefinements removes cached refinements: the expired ones, those
+// of one goal, or all of them.
+func invalidateRefinements(cache *goap.RefinementCache) error {
+	var removed int
+	var err error
+	if CLI.InvalidateRefinements.Expired && CLI.InvalidateRefinements.GoalName != "" {
+		return fmt.Errorf("--expired removes expired refinements of all goals and cannot be combined with a goal name")
+	}
+	if CLI.InvalidateRefinements.Expired {
+		removed, err = cache.Prune()
+	} else {
+		removed, err = cache.Invalidate(CLI.InvalidateRefinements.GoalName)
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("✓ Invalidated cached refinements", "removed", removed, "cache", cache.Dir())
+	return nil
+}
+
+// exportGraph writes a run's plan graph in the given format to outPath, or
+// to stdout when outPath is empty.
+func exportGraph(persistence *goap.GraphPersistence, runID string, format goap.GraphFormat, outPath string) error {
+	graph, err := persistence.LoadGraph(runID)
+	if err != nil {
+		return err
+	}
+
+	if outPath == "" {
+		return goap.ExportGraph(os.Stdout, graph, format)
+	}
+
+	var buf bytes.Buffer
+	err = goap.ExportGraph(&buf, graph, format)
+	if err != nil {
+		return err
+	}
+	err = fsutil.WriteFileAtomic(outPath, buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	log.Info("✓ Plan graph exported", "runID", runID, "format", format, "file", outPath)
+	return nil
+}
+
+// diffRuns prints how the plan changed between two runs.
+func diffRuns(persistence *goap.GraphPersistence, beforeRunID, afterRunID string, asJSON bool) error {
+	before, err := persistence.LoadGraph(beforeRunID)
+	if err != nil {
+		return err
+	}
+	after, err := persistence.LoadGraph(afterRunID)
+	if err != nil {
+		return err
+	}
+
+	diff := goap.DiffGraphs(before, after)
+	if !asJSON {
+		fmt.Print(diff)
+		return nil
+	}
+
+	diffJSON, err := diff.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal diff: %w", err)
+	}
+	fmt.Println(string(diffJSON))
+	return nil
+}
+
+// loadGoalLibrary returns the built-in goal library extended with the goals
+// of --goal-file and --goals-dir, and the name of the goal to pursue: --goal,
+// else the first goal of --goal-file, else DeliverQualityFeature.
+func loadGoalLibrary() (*goap.GoalLibrary, string, error) {
+	library := goap.NewGoalLibrary()
+	name := "DeliverQualityFeature"
+	if CLI.GoalsDir != "" {
+		if err := library.LoadDir(CLI.GoalsDir); err != nil {
+			return nil, "", err
+		}
+	}
+	if CLI.GoalFile != "" {
+		definitions, err := goap.LoadGoalFile(CLI.GoalFile)
+		if err != nil {
+			return nil, "", err
+		}
+		for i, definition := range definitions {
+			if err := library.Add(definition); err != nil {
+				return nil, "", err
+			}
+			if i == 0 {
+				name = definition.Name
+			}
+		}
+	}
+	if CLI.Goal != "" {
+		name = CLI.Goal
+	}
+	return library, name, nil
+}
+
+// listGoals prints the goals in the library and their parameters.
+func listGoals(library *goap.GoalLibrary) {
+	for _, name := range library.Names() {
+		definition, _ := library.Get(name)
+		fmt.Printf("%s\n    %s\n", name, definition.Description)
+		for _, parameter := range definition.Parameters {
+			requirement := "required"
+			if parameter.Default != nil {
+				requirement = fmt.Sprintf("default %q", *parameter.Default)
+			}
+			fmt.Printf("    -p %s=...  %s (%s)\n", parameter.Name, parameter.Description, requirement)
+		}
+	}
+}
+
+// senseWorkspace re-observes the facts of the working directory that can be
+// checked cheaply before a failed node is replanned, retracting beliefs that
+// no longer hold, such as formatted code or committed changes.
+func senseWorkspace(workDir string) goap.StateSensor {
+	return func(ctx context.Context, believed goap.WorldState) (goap.WorldState, error) {
+		sensed := believed.Clone()
+
+		unformatted, err := exec.CommandContext(ctx, "gofmt", "-l", workDir).Output()
+		if err == nil && len(bytes.TrimSpace(unformatted)) > 0 {
+			sensed.Set("code_formatted", false)
+		}
+
+		status, err := exec.CommandContext(ctx, "git", "-C", workDir, "status", "--porcelain").Output()
+		if err == nil && len(bytes.TrimSpace(status)) > 0 {
+			sensed.Set("changes_committed", false)
+		}
+		return sensed, nil
+	}
+}
+
 // createRichActionSet creates all our beautiful leaf nodes
 func createRichActionSet(workDir string) []goap.Action {
 	actions := []goap.Action{}
@@ -136,11 +419,15 @@ func createRichActionSet(workDir string) []goap.Action {
 
 	// Run tests (tool execution)
 	actions = append(actions, goapactions.NewRunGoTestsAction(workDir, "./...", true))
+	actions = append(actions, goapactions.NewRunFailingTestAction(workDir, "./..."))
 
 	// Coverage improvement (iterative GOFAI + LLM)
 	actionCtx := &goapactions.ActionContext{} // Simplified context for this example
 	actions = append(actions, goapactions.NewImproveCoverageAction(actionCtx, workDir, "./...", 70.0, 3))
 
+	// Endpoint scaffolding (template generation) for the AddHTTPEndpoint goal
+	actions = append(actions, goapactions.NewGenerateAPIEndpointAction(actionCtx, "/", "GET", "go"))
+
 	// Format code (tool execution)
 	actions = append(actions, goapactions.NewGoFmtAction(workDir, []string{"./..."}))
 
@@ -208,23 +495,3 @@ func createRichActionSet(workDir string) []goap.Action {
 
 	return actions
 }
-
-// SimpleRefiner is a basic goal refiner for the reasoning agent.
-// In production, replace with LLMGoalRefiner for intelligent decomposition.
-type SimpleRefiner struct{}
-
-func NewSimpleRefiner() *SimpleRefiner {
-	return &SimpleRefiner{}
-}
-
-func (r *SimpleRefiner) Refine(ctx context.Context, goal *goap.Goal, current goap.WorldState) ([]*goap.Goal, error) {
-	// Simple refiner doesn't decompose goals
-	// In production, LLM-based refiner would intelligently decompose complex goals
-	return nil, nil
-}
-
-func (r *SimpleRefiner) IsAtomic(goal *goap.Goal, current goap.WorldState) bool {
-	// All goals are atomic in simple mode
-	// Production LLM refiner would determine atomicity based on goal complexity
-	return true
-}
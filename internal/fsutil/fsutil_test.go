@@ -0,0 +1,142 @@
+package fsutil
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+
+	t.Run("CreatesAndReplaces", func(t *testing.T) {
+		if err := WriteFileAtomic(path, []byte("first"), 0600); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		if err := WriteFileAtomic(path, []byte("second"), 0644); err != nil {
+			t.Fatalf("Failed to replace: %v", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		if string(data) != "second" {
+			t.Errorf("Expected second, got %s", data)
+		}
+
+		info, _ := os.Stat(path)
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected the existing mode 0600 to be kept, got %v", info.Mode().Perm())
+		}
+	})
+
+	t.Run("NoTemporaryFilesLeft", func(t *testing.T) {
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 1 {
+			t.Errorf("Expected only the written file, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("MissingDirectory", func(t *testing.T) {
+		if err := WriteFileAtomic(filepath.Join(dir, "missing", "out.json"), []byte("x"), 0644); err == nil {
+			t.Error("Writing into a missing directory should fail")
+		}
+	})
+}
+
+func TestLockDir(t *testing.T) {
+	t.Run("ExcludesSecondLock", func(t *testing.T) {
+		dir := t.TempDir()
+		lock, err := LockDir(dir)
+		if err != nil {
+			t.Fatalf("Failed to lock: %v", err)
+		}
+
+		_, err = LockDir(dir)
+		var locked *LockedError
+		if !errors.As(err, &locked) {
+			t.Fatalf("Expected LockedError, got %v", err)
+		}
+		if locked.Holder.PID != os.Getpid() {
+			t.Errorf("Expected holder pid %d, got %d", os.Getpid(), locked.Holder.PID)
+		}
+
+		if err := lock.Unlock(); err != nil {
+			t.Fatalf("Failed to unlock: %v", err)
+		}
+		relock, err := LockDir(dir)
+		if err != nil {
+			t.Fatalf("Failed to lock after unlock: %v", err)
+		}
+		relock.Unlock()
+	})
+
+	t.Run("TakesOverStaleLock", func(t *testing.T) {
+		dir := t.TempDir()
+		hostname, _ := os.Hostname()
+
+		// A pid far above any real process on this host
+		stale, _ := json.Marshal(LockInfo{PID: 1 << 30, Hostname: hostname})
+		os.WriteFile(filepath.Join(dir, LockFileName), stale, 0644)
+
+		lock, err := LockDir(dir)
+		if err != nil {
+			t.Fatalf("Stale lock should be taken over, got %v", err)
+		}
+		lock.Unlock()
+	})
+
+	t.Run("TakesOverCorruptLock", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, LockFileName), []byte("{"), 0644)
+
+		lock, err := LockDir(dir)
+		if err != nil {
+			t.Fatalf("Corrupt lock should be taken over, got %v", err)
+		}
+		lock.Unlock()
+	})
+
+	t.Run("OneTakeoverOfStaleLock", func(t *testing.T) {
+		hostname, _ := os.Hostname()
+		stale, _ := json.Marshal(LockInfo{PID: 1 << 30, Hostname: hostname})
+
+		for round := 0; round < 20; round++ {
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, LockFileName), stale, 0644)
+
+			var acquired atomic.Int32
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					if _, err := LockDir(dir); err == nil {
+						acquired.Add(1)
+					}
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			if acquired.Load() != 1 {
+				t.Fatalf("Expected exactly 1 takeover of the stale lock, got %d", acquired.Load())
+			}
+		}
+	})
+
+	t.Run("KeepsRemoteLock", func(t *testing.T) {
+		dir := t.TempDir()
+		remote, _ := json.Marshal(LockInfo{PID: 1 << 30, Hostname: "some-other-host"})
+		os.WriteFile(filepath.Join(dir, LockFileName), remote, 0644)
+
+		if _, err := LockDir(dir); err == nil {
+			t.Error("A lock held from another host should not be taken over")
+		}
+	})
+}
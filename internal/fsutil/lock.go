@@ -0,0 +1,176 @@
+package fsutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// LockFileName is the name of the lock file LockDir creates in a directory.
+const LockFileName = ".lock"
+
+// LockInfo identifies the process holding a directory lock.
+type LockInfo struct {
+	PID       int    `json:"pid"`
+	Hostname  string `json:"hostname"`
+	CreatedAt string `json:"created_at"`
+}
+
+// LockedError is returned by LockDir when another live process holds the lock.
+type LockedError struct {
+	Dir    string
+	Holder LockInfo
+}
+
+// Error implements the error interface.
+func (le *LockedError) Error() string {
+	return fmt.Sprintf("%s is locked by process %d on %s since %s",
+		le.Dir, le.Holder.PID, le.Holder.Hostname, le.Holder.CreatedAt)
+}
+
+// DirLock is an advisory lock on a directory, held by creating a lock file in
+// it. Cooperating processes that lock the same directory exclude each other;
+// nothing stops a process that does not take the lock.
+type DirLock struct {
+	path string
+}
+
+// LockDir locks dir, creating it if needed. If the directory is already
+// locked by a live process it returns a *LockedError. A lock left behind by a
+// process that no longer runs on this host, or an unreadable lock file, is
+// stale and is taken over.
+func LockDir(dir string) (*DirLock, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	hostname, _ := os.Hostname()
+	info := LockInfo{
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	infoJSON, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lock info: %w", err)
+	}
+
+	path := filepath.Join(dir, LockFileName)
+	for attempt := 0; attempt < 3; attempt++ {
+		err := createLock(path, infoJSON)
+		if err == nil {
+			return &DirLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file %s: %w", path, err)
+		}
+
+		holder, stale := readLock(path, hostname)
+		if !stale {
+			return nil, &LockedError{Dir: dir, Holder: holder}
+		}
+
+		// Take over the stale lock and try again
+		holder, taken, err := takeOver(path, hostname)
+		if err != nil {
+			return nil, err
+		}
+		if !taken {
+			return nil, &LockedError{Dir: dir, Holder: holder}
+		}
+		log.Warn("Removed stale lock", "dir", dir, "pid", holder.PID, "since", holder.CreatedAt)
+	}
+
+	return nil, fmt.Errorf("failed to lock %s: lock file keeps reappearing", dir)
+}
+
+// Unlock releases the lock by removing the lock file.
+func (dl *DirLock) Unlock() error {
+	err := os.Remove(dl.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file %s: %w", dl.path, err)
+	}
+	return nil
+}
+
+// createLock creates the lock file at path holding info, failing with an
+// os.IsExist error if it already exists. The info is written to a temporary
+// file that is then linked into place, so other processes never see a lock
+// file that exists but is still empty.
+func createLock(path string, info []byte) error {
+	tmp := fmt.Sprintf("%s.new.%d.%d", path, os.Getpid(), time.Now().UnixNano())
+	err := os.WriteFile(tmp, info, 0644)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Link(tmp, path)
+}
+
+// takeOver removes a lock file that was found stale. Between that check and
+// the removal another process may have taken the lock over itself, so the
+// file is first renamed to a name unique to this process and checked again:
+// only a lock that is still stale is removed, and a live one is linked back in
+// place. It reports the holder of the moved lock and whether the lock can be
+// retaken. A lock that has already disappeared counts as taken over.
+func takeOver(path, hostname string) (LockInfo, bool, error) {
+	moved := fmt.Sprintf("%s.stale.%d.%d", path, os.Getpid(), time.Now().UnixNano())
+	err := os.Rename(path, moved)
+	if os.IsNotExist(err) {
+		return LockInfo{}, true, nil
+	}
+	if err != nil {
+		return LockInfo{}, false, fmt.Errorf("failed to move stale lock file %s: %w", path, err)
+	}
+
+	holder, stale := readLock(moved, hostname)
+	if !stale {
+		// Link rather than rename, so a lock created meanwhile is not clobbered
+		if err := os.Link(moved, path); err != nil && !os.IsExist(err) {
+			return holder, false, fmt.Errorf("failed to restore lock file %s: %w", path, err)
+		}
+		os.Remove(moved)
+		return holder, false, nil
+	}
+
+	if err := os.Remove(moved); err != nil && !os.IsNotExist(err) {
+		return holder, false, fmt.Errorf("failed to remove stale lock file %s: %w", moved, err)
+	}
+	return holder, true, nil
+}
+
+// readLock reads a lock file and reports whether the lock is stale. Locks
+// held from other hosts are never considered stale, since their process
+// cannot be checked.
+func readLock(path, hostname string) (LockInfo, bool) {
+	var info LockInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, os.IsNotExist(err)
+	}
+	if err := json.Unmarshal(data, &info); err != nil || info.PID <= 0 {
+		return info, true
+	}
+	if info.Hostname != hostname {
+		return info, false
+	}
+	return info, !processAlive(info.PID)
+}
+
+// processAlive reports whether a process with the given PID is running.
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
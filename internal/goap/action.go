@@ -37,6 +37,52 @@ type Action interface {
 	Clone() Action
 }
 
+// DynamicCostAction is an optional capability for actions whose cost depends
+// on the state they would run in. For example, running tests is cheap when
+// nothing changed, and an LLM call is expensive when the ticket is large.
+// The Planner uses CostIn when an action implements it and Cost otherwise.
+type DynamicCostAction interface {
+	// CostIn returns the estimated cost of executing this action in the given state
+	CostIn(state WorldState) float64
+}
+
+// ActionCost returns the cost of an action in the given state, using CostIn
+// when the action implements DynamicCostAction and the static Cost otherwise.
+func ActionCost(action Action, state WorldState) float64 {
+	if dynamic, ok := action.(DynamicCostAction); ok {
+		return dynamic.CostIn(state)
+	}
+	return action.Cost()
+}
+
+// InvalidatingAction is an optional capability for actions that make
+// earlier results stale. For example, editing code invalidates
+// "go_tests_passed" and "build_succeeded". Both the Planner and the executors
+// remove the listed keys from the state after applying the action's effects.
+type InvalidatingAction interface {
+	// Invalidates returns the state keys this action makes stale
+	Invalidates() []string
+}
+
+// ActionInvalidates returns the keys an action invalidates, or nil if it does
+// not implement InvalidatingAction.
+func ActionInvalidates(action Action) []string {
+	if invalidating, ok := action.(InvalidatingAction); ok {
+		return invalidating.Invalidates()
+	}
+	return nil
+}
+
+// ApplyEffects applies an action's declared effects to the state and then
+// removes the keys the action invalidates.
+func ApplyEffects(state WorldState, action Action) {
+	state.Apply(action.Effects())
+	state.Invalidate(ActionInvalidates(action))
+}
+
+// CostFunc computes a state-dependent action cost.
+type CostFunc func(state WorldState) float64
+
 // BaseAction provides a default implementation of common Action methods.
 // Concrete actions can embed this to avoid boilerplate.
 type BaseAction struct {
@@ -45,6 +91,8 @@ type BaseAction struct {
 	preconditions WorldState
 	effects       WorldState
 	cost          float64
+	costFunc      CostFunc
+	invalidates   []string
 }
 
 // NewBaseAction creates a new BaseAction with the given parameters.
@@ -78,6 +126,46 @@ func (a *BaseAction) Cost() float64 {
 	return a.cost
 }
 
+// SetCostFunc makes the action's cost depend on the planning state.
+// Passing nil restores the static cost.
+func (a *BaseAction) SetCostFunc(fn CostFunc) {
+	a.costFunc = fn
+}
+
+// HasCostFunc reports whether a CostFunc is set. A cost function cannot be
+// serialized, so actions that have one cannot be rebuilt from a spec.
+func (a *BaseAction) HasCostFunc() bool {
+	return a.costFunc != nil
+}
+
+// CostIn returns the state-dependent cost if a CostFunc is set, and the
+// static cost otherwise.
+func (a *BaseAction) CostIn(state WorldState) float64 {
+	if a.costFunc != nil {
+		return a.costFunc(state)
+	}
+	return a.cost
+}
+
+// SetInvalidates declares the state keys this action makes stale.
+func (a *BaseAction) SetInvalidates(keys ...string) {
+	a.invalidates = keys
+}
+
+// Invalidates returns the state keys this action makes stale.
+func (a *BaseAction) Invalidates() []string {
+	return a.invalidates
+}
+
+// cloneBase returns a copy of the BaseAction, including its cost function
+// and invalidation list.
+func (a *BaseAction) cloneBase() *BaseAction {
+	clone := NewBaseAction(a.name, a.description, a.preconditions.Clone(), a.effects.Clone(), a.cost)
+	clone.costFunc = a.costFunc
+	clone.invalidates = append([]string(nil), a.invalidates...)
+	return clone
+}
+
 func (a *BaseAction) CanExecute(current WorldState) bool {
 	return current.Matches(a.preconditions)
 }
@@ -116,14 +204,14 @@ func (a *SimpleAction) Execute(ctx context.Context, current WorldState) error {
 	}
 
 	// Apply effects to the current world state
-	current.Apply(a.effects)
+	ApplyEffects(current, a)
 
 	return nil
 }
 
 func (a *SimpleAction) Clone() Action {
 	return &SimpleAction{
-		BaseAction:  NewBaseAction(a.name, a.description, a.preconditions.Clone(), a.effects.Clone(), a.cost),
+		BaseAction:  a.cloneBase(),
 		executeFunc: a.executeFunc,
 	}
 }
@@ -172,7 +260,7 @@ func (a *CompositeAction) Execute(ctx context.Context, current WorldState) error
 	}
 
 	// Apply the composite action's effects
-	current.Apply(a.effects)
+	ApplyEffects(current, a)
 
 	return nil
 }
@@ -184,7 +272,7 @@ func (a *CompositeAction) Clone() Action {
 	}
 
 	return &CompositeAction{
-		BaseAction: NewBaseAction(a.name, a.description, a.preconditions.Clone(), a.effects.Clone(), a.cost),
+		BaseAction: a.cloneBase(),
 		subactions: clonedSubactions,
 	}
 }
@@ -0,0 +1,163 @@
+package goap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+// ActionSpec is the serialized form of an action: a stable type ID and the
+// parameters its constructor was called with. Plan graphs store the spec of
+// every serializable action next to its name, so that a process other than
+// the one that planned can rebuild the exact action instances, even when
+// several of them share a name.
+type ActionSpec struct {
+	Type   string          `json:"type,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// ErrNotSerializable is returned for actions that cannot be rebuilt from a
+// spec and so must be resolved by name.
+var ErrNotSerializable = errors.New("action is not serializable")
+
+// NewActionSpec creates a spec from a type ID and the constructor
+// parameters, which must marshal to JSON.
+func NewActionSpec(typeID string, params interface{}) (ActionSpec, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ActionSpec{}, fmt.Errorf("failed to marshal parameters of action type %s: %w", typeID, err)
+	}
+	return ActionSpec{Type: typeID, Params: data}, nil
+}
+
+// IsZero reports whether the spec is empty, i.e. describes an action that
+// cannot be rebuilt.
+func (as ActionSpec) IsZero() bool {
+	return as.Type == ""
+}
+
+// key identifies the action instance the spec describes. The parameters are
+// compacted, since stores may re-indent them.
+func (as ActionSpec) key() string {
+	var params bytes.Buffer
+	if err := json.Compact(&params, as.Params); err != nil {
+		return as.Type + "\x00" + string(as.Params)
+	}
+	return as.Type + "\x00" + params.String()
+}
+
+// SerializableAction is an optional capability for actions that can be
+// rebuilt from their spec by an ActionRegistry. Actions holding functions or
+// other state that cannot be serialized, such as SimpleAction, do not
+// implement it and are resolved by name instead.
+type SerializableAction interface {
+	// Spec returns the action's type ID and constructor parameters, or an
+	// error wrapping ErrNotSerializable if this instance cannot be
+	// serialized, e.g. because it holds a function
+	Spec() (ActionSpec, error)
+}
+
+// ActionSpecOf returns the action's spec, or an error wrapping
+// ErrNotSerializable if the action cannot be serialized. Actions with a
+// CostFunc cannot be, since the function would be lost.
+func ActionSpecOf(action Action) (ActionSpec, error) {
+	serializable, ok := action.(SerializableAction)
+	if !ok {
+		return ActionSpec{}, ErrNotSerializable
+	}
+	if costed, ok := action.(interface{ HasCostFunc() bool }); ok && costed.HasCostFunc() {
+		return ActionSpec{}, fmt.Errorf("action %s has a cost function: %w", action.Name(), ErrNotSerializable)
+	}
+	return serializable.Spec()
+}
+
+// actionSpecs returns the specs of a sequence of actions, with empty specs
+// for actions that are not serializable. It returns nil if none is. An
+// action that is serializable in general but not this instance is logged,
+// since it can only be resolved by name in another process.
+func actionSpecs(actions []Action) []ActionSpec {
+	specs := make([]ActionSpec, len(actions))
+	serializable := false
+	for i, action := range actions {
+		spec, err := ActionSpecOf(action)
+		if err != nil {
+			if _, ok := action.(SerializableAction); ok {
+				log.Warn("Action cannot be serialized, it will be resolved by name", "action", action.Name(), "error", err)
+			}
+			continue
+		}
+		specs[i] = spec
+		serializable = true
+	}
+	if !serializable {
+		return nil
+	}
+	return specs
+}
+
+// specAt returns the i-th spec, or an empty spec if there is none.
+func specAt(specs []ActionSpec, i int) ActionSpec {
+	if i < len(specs) {
+		return specs[i]
+	}
+	return ActionSpec{}
+}
+
+// ActionFactory rebuilds an action from the parameters in its spec.
+type ActionFactory func(params json.RawMessage) (Action, error)
+
+// ActionRegistry maps action type IDs to the factories that rebuild them.
+// Factories are closures, so they can capture dependencies that are not
+// serialized, such as LLM clients. It is safe for concurrent use.
+type ActionRegistry struct {
+	mu        sync.RWMutex
+	factories map[string]ActionFactory
+}
+
+// NewActionRegistry creates an empty action registry.
+func NewActionRegistry() *ActionRegistry {
+	return &ActionRegistry{
+		factories: make(map[string]ActionFactory),
+	}
+}
+
+// Register registers the factory for a type ID, replacing any previous one.
+func (ar *ActionRegistry) Register(typeID string, factory ActionFactory) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	ar.factories[typeID] = factory
+}
+
+// Types returns the registered type IDs, sorted.
+func (ar *ActionRegistry) Types() []string {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	types := make([]string, 0, len(ar.factories))
+	for typeID := range ar.factories {
+		types = append(types, typeID)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Build rebuilds the action a spec describes.
+func (ar *ActionRegistry) Build(spec ActionSpec) (Action, error) {
+	ar.mu.RLock()
+	factory, exists := ar.factories[spec.Type]
+	ar.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("no factory registered for action type %q", spec.Type)
+	}
+
+	action, err := factory(spec.Params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build action of type %s: %w", spec.Type, err)
+	}
+	return action, nil
+}
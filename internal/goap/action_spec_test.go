@@ -0,0 +1,166 @@
+package goap
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// fileAction is a serializable test action whose behavior depends on its
+// constructor parameter, like many real actions sharing a name.
+type fileAction struct {
+	*BaseAction
+	path string
+	ran  *[]string
+	mu   *sync.Mutex
+}
+
+func newFileAction(path string, ran *[]string, mu *sync.Mutex) *fileAction {
+	return &fileAction{
+		BaseAction: NewBaseAction("WriteFile", "Write "+path, WorldState{}, WorldState{"wrote_" + path: true}, 1.0),
+		path:       path,
+		ran:        ran,
+		mu:         mu,
+	}
+}
+
+func (a *fileAction) Execute(ctx context.Context, current WorldState) error {
+	a.mu.Lock()
+	*a.ran = append(*a.ran, a.path)
+	a.mu.Unlock()
+	ApplyEffects(current, a)
+	return nil
+}
+
+func (a *fileAction) Clone() Action {
+	return newFileAction(a.path, a.ran, a.mu)
+}
+
+func (a *fileAction) Spec() (ActionSpec, error) {
+	return NewActionSpec("test.WriteFile", map[string]string{"path": a.path})
+}
+
+func TestActionSpecs(t *testing.T) {
+	newPlan := func(a, b Action) *HierarchicalPlan {
+		return &HierarchicalPlan{
+			Goal: NewGoal("Root", "Root", WorldState{"wrote_a": true, "wrote_b": true}, 1.0),
+			Subplans: []*HierarchicalPlan{
+				{Goal: NewGoal("A", "A", WorldState{"wrote_a": true}, 1.0), Actions: []Action{a}, Depth: 1},
+				{Goal: NewGoal("B", "B", WorldState{"wrote_b": true}, 1.0), Actions: []Action{b}, Depth: 1},
+			},
+		}
+	}
+
+	t.Run("Registry", func(t *testing.T) {
+		registry := NewActionRegistry()
+		var ran []string
+		var mu sync.Mutex
+		registry.Register("test.WriteFile", func(params json.RawMessage) (Action, error) {
+			var p struct{ Path string }
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, err
+			}
+			return newFileAction(p.Path, &ran, &mu), nil
+		})
+
+		if types := registry.Types(); len(types) != 1 || types[0] != "test.WriteFile" {
+			t.Errorf("Expected [test.WriteFile], got %v", types)
+		}
+
+		original := newFileAction("a", &ran, &mu)
+		spec, err := original.Spec()
+		if err != nil {
+			t.Fatalf("Failed to serialize action: %v", err)
+		}
+		rebuilt, err := registry.Build(spec)
+		if err != nil {
+			t.Fatalf("Failed to rebuild action: %v", err)
+		}
+		if rebuilt.Description() != original.Description() {
+			t.Errorf("Expected %q, got %q", original.Description(), rebuilt.Description())
+		}
+
+		_, err = registry.Build(ActionSpec{Type: "test.Unknown"})
+		if err == nil {
+			t.Error("Expected an error for an unknown type")
+		}
+	})
+
+	t.Run("SpecsPersistedInGraph", func(t *testing.T) {
+		var ran []string
+		var mu sync.Mutex
+		simple := NewSimpleAction("Simple", "Simple", WorldState{}, WorldState{"wrote_b": true}, 1.0, nil)
+		graph := BuildGraphFromPlan(newPlan(newFileAction("a", &ran, &mu), simple), "test-agent")
+
+		if specs := nodeByGoal(t, graph, "A").ActionSpecs; len(specs) != 1 || specs[0].Type != "test.WriteFile" {
+			t.Errorf("Expected the serializable action's spec, got %v", specs)
+		}
+		if specs := nodeByGoal(t, graph, "B").ActionSpecs; specs != nil {
+			t.Errorf("Expected no specs for a SimpleAction, got %v", specs)
+		}
+	})
+
+	t.Run("SameNameActionsResolveToTheirInstances", func(t *testing.T) {
+		persistence := NewGraphPersistence(t.TempDir())
+		runID := "test-same-name"
+
+		var ran []string
+		var mu sync.Mutex
+		a := newFileAction("a", &ran, &mu)
+		b := newFileAction("b", &ran, &mu)
+		if err := persistence.SaveGraph(BuildGraphFromPlan(newPlan(a, b), "test-agent"), runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterActions([]Action{a, b})
+		if err := executor.Execute(context.Background(), NewWorldState()); err != nil {
+			t.Fatalf("Execution failed: %v", err)
+		}
+
+		if len(ran) != 2 || ran[0] != "a" || ran[1] != "b" {
+			t.Errorf("Expected both WriteFile instances to run in order, got %v", ran)
+		}
+	})
+
+	t.Run("RebuildsActionsInAnotherProcess", func(t *testing.T) {
+		persistence := NewGraphPersistence(t.TempDir())
+		runID := "test-rebuild"
+
+		var planned []string
+		var plannedMu sync.Mutex
+		plan := newPlan(newFileAction("a", &planned, &plannedMu), newFileAction("b", &planned, &plannedMu))
+		if err := persistence.SaveGraph(BuildGraphFromPlan(plan, "test-agent"), runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		// The executing process registers no actions, only factories
+		var ran []string
+		var mu sync.Mutex
+		registry := NewActionRegistry()
+		registry.Register("test.WriteFile", func(params json.RawMessage) (Action, error) {
+			var p struct{ Path string }
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, err
+			}
+			return newFileAction(p.Path, &ran, &mu), nil
+		})
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.SetActionRegistry(registry)
+		executor.SetMaxWorkers(2)
+		if err := executor.Execute(context.Background(), NewWorldState()); err != nil {
+			t.Fatalf("Execution failed: %v", err)
+		}
+
+		sort.Strings(ran)
+		if len(ran) != 2 || ran[0] != "a" || ran[1] != "b" {
+			t.Errorf("Expected both rebuilt actions to run, got %v", ran)
+		}
+		if len(planned) != 0 {
+			t.Errorf("Expected the planning process's instances not to run, got %v", planned)
+		}
+	})
+}
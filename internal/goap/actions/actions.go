@@ -8,6 +8,7 @@ import (
 	"path"
 
 	"github.com/charmbracelet/log"
+	"upside-down-research.com/oss/agentic/internal/fsutil"
 	"upside-down-research.com/oss/agentic/internal/goap"
 	"upside-down-research.com/oss/agentic/internal/llm"
 )
@@ -76,6 +77,10 @@ func (a *ReadTicketAction) Clone() goap.Action {
 	return NewReadTicketAction(a.ctx, a.ticketPath)
 }
 
+// ticketCharsPerCostUnit is how many ticket characters add one unit of cost
+// to LLM actions that consume the whole ticket.
+const ticketCharsPerCostUnit = 4000.0
+
 // GeneratePlanAction generates a plan using LLM with review quality gate.
 // Complexity: High (multiple LLM calls with review loop)
 type GeneratePlanAction struct {
@@ -136,6 +141,13 @@ func (a *GeneratePlanAction) Execute(ctx context.Context, current goap.WorldStat
 	return nil
 }
 
+// CostIn scales the planning cost with the size of the ticket, since larger
+// tickets mean longer prompts and more review rounds.
+func (a *GeneratePlanAction) CostIn(state goap.WorldState) float64 {
+	ticketContent, _ := state.Get("ticket_content").(string)
+	return a.Cost() + float64(len(ticketContent))/ticketCharsPerCostUnit
+}
+
 func (a *GeneratePlanAction) Clone() goap.Action {
 	return NewGeneratePlanAction(a.ctx, a.plannerPrompt)
 }
@@ -294,7 +306,7 @@ func (a *WriteCodeAction) Execute(ctx context.Context, current goap.WorldState)
 
 	for _, code := range implementation.Code {
 		filePath := path.Join(outputDir, code.Filename)
-		err := os.WriteFile(filePath, []byte(code.Content), 0644)
+		err := fsutil.WriteFileAtomic(filePath, []byte(code.Content), 0644)
 		if err != nil {
 			return fmt.Errorf("failed to write file %s: %w", code.Filename, err)
 		}
@@ -347,7 +359,7 @@ func (a *WritePlanAction) Execute(ctx context.Context, current goap.WorldState)
 	}
 
 	outputPath := path.Join(a.ctx.OutputPath, a.runID, "plan.txt")
-	err = os.WriteFile(outputPath, planJSON, 0644)
+	err = fsutil.WriteFileAtomic(outputPath, planJSON, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write plan: %w", err)
 	}
@@ -0,0 +1,593 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+	"upside-down-research.com/oss/agentic/internal/goap"
+	"upside-down-research.com/oss/agentic/internal/goap/templates"
+	"upside-down-research.com/oss/agentic/internal/llm"
+)
+
+// ActionDefinition describes an action declaratively, so that new actions can
+// be added from YAML or JSON files without recompiling the agent. Exactly one
+// of Shell, LLM and HTTP is set; it is the action's body.
+//
+// String fields of the body are Go templates executed against the current
+// WorldState, e.g. "go test {{.package}}". Referencing a key that is not in
+// the state fails the action. Besides the text/template built-ins, templates
+// can use json, to marshal a value, and shellquote, to quote a value for a
+// shell command.
+//
+// The state holds LLM output, so every value a shell command template
+// outputs is quoted as a single shell word, unless its pipeline ends in raw,
+// e.g. "{{.flags | raw}}". Raw values are injected into the command as is;
+// pass untrusted values through Env instead.
+type ActionDefinition struct {
+	Name          string          `yaml:"name" json:"name"`
+	Description   string          `yaml:"description,omitempty" json:"description,omitempty"`
+	Preconditions goap.WorldState `yaml:"preconditions,omitempty" json:"preconditions,omitempty"`
+	Effects       goap.WorldState `yaml:"effects,omitempty" json:"effects,omitempty"`
+	// Cost defaults to 1
+	Cost float64 `yaml:"cost,omitempty" json:"cost,omitempty"`
+	// Invalidates lists the state keys the action makes stale
+	Invalidates []string `yaml:"invalidates,omitempty" json:"invalidates,omitempty"`
+
+	Shell *ShellBody `yaml:"shell,omitempty" json:"shell,omitempty"`
+	LLM   *LLMBody   `yaml:"llm,omitempty" json:"llm,omitempty"`
+	HTTP  *HTTPBody  `yaml:"http,omitempty" json:"http,omitempty"`
+}
+
+// ShellBody runs a command with sh -c.
+type ShellBody struct {
+	Command string            `yaml:"command" json:"command"`
+	Dir     string            `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	// Timeout is a Go duration such as "30s"; empty means no timeout
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Result is the state key the trimmed output is stored under, if any
+	Result string `yaml:"result,omitempty" json:"result,omitempty"`
+}
+
+// LLMBody generates content with the LLM, from either an inline prompt or a
+// named template of the template registry.
+type LLMBody struct {
+	Prompt   string `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+	// Data is rendered against the state and passed to Template. If it is
+	// empty, the template gets the state itself.
+	Data   map[string]string `yaml:"data,omitempty" json:"data,omitempty"`
+	Result string            `yaml:"result,omitempty" json:"result,omitempty"`
+}
+
+// HTTPBody makes an HTTP request. Responses outside 2xx fail the action.
+type HTTPBody struct {
+	Method  string            `yaml:"method,omitempty" json:"method,omitempty"`
+	URL     string            `yaml:"url" json:"url"`
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty" json:"body,omitempty"`
+	Timeout string            `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Result is the state key the response body is stored under, if any;
+	// the status code goes under Result + "_status"
+	Result string `yaml:"result,omitempty" json:"result,omitempty"`
+}
+
+// Validate checks that the definition is complete and its templates parse.
+func (d *ActionDefinition) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("action has no name")
+	}
+	if d.Cost < 0 {
+		return fmt.Errorf("action %s has negative cost %v", d.Name, d.Cost)
+	}
+
+	bodies := 0
+	var fields []string
+	var timeout string
+	if d.Shell != nil {
+		bodies++
+		if d.Shell.Command == "" {
+			return fmt.Errorf("action %s has a shell body without a command", d.Name)
+		}
+		if _, err := parseShellTemplate(d.Name, d.Shell.Command); err != nil {
+			return err
+		}
+		fields = append(fields, d.Shell.Dir)
+		fields = append(fields, mapValues(d.Shell.Env)...)
+		timeout = d.Shell.Timeout
+	}
+	if d.LLM != nil {
+		bodies++
+		if (d.LLM.Prompt == "") == (d.LLM.Template == "") {
+			return fmt.Errorf("action %s needs exactly one of prompt and template in its llm body", d.Name)
+		}
+		fields = append(fields, d.LLM.Prompt)
+		fields = append(fields, mapValues(d.LLM.Data)...)
+	}
+	if d.HTTP != nil {
+		bodies++
+		if d.HTTP.URL == "" {
+			return fmt.Errorf("action %s has an http body without a url", d.Name)
+		}
+		fields = append(fields, d.HTTP.URL, d.HTTP.Body)
+		fields = append(fields, mapValues(d.HTTP.Headers)...)
+		timeout = d.HTTP.Timeout
+	}
+	if bodies != 1 {
+		return fmt.Errorf("action %s needs exactly one of shell, llm and http, has %d", d.Name, bodies)
+	}
+
+	if timeout != "" {
+		if _, err := time.ParseDuration(timeout); err != nil {
+			return fmt.Errorf("action %s has invalid timeout: %w", d.Name, err)
+		}
+	}
+	for _, field := range fields {
+		if _, err := parseStateTemplate(d.Name, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadActionDefinitions reads the action definitions of every .yaml, .yml and
+// .json file in dir, in file name order. A file holds either one definition
+// or a list of them.
+func LoadActionDefinitions(dir string) ([]ActionDefinition, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read action directory: %w", err)
+	}
+
+	definitions := []ActionDefinition{}
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		fileDefinitions, err := loadActionFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, definition := range fileDefinitions {
+			if err := definition.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid action in %s: %w", path, err)
+			}
+			if other, exists := seen[definition.Name]; exists {
+				return nil, fmt.Errorf("action %s in %s is already defined in %s", definition.Name, path, other)
+			}
+			seen[definition.Name] = path
+			definitions = append(definitions, definition)
+		}
+	}
+	return definitions, nil
+}
+
+// loadActionFile decodes one file. YAML is a superset of JSON, so both go
+// through the YAML decoder.
+func loadActionFile(path string) ([]ActionDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read action file: %w", err)
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(node.Content) == 0 {
+		return nil, nil
+	}
+
+	var definitions []ActionDefinition
+	if node.Content[0].Kind == yaml.SequenceNode {
+		err = node.Content[0].Decode(&definitions)
+	} else {
+		var definition ActionDefinition
+		err = node.Content[0].Decode(&definition)
+		definitions = []ActionDefinition{definition}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return definitions, nil
+}
+
+// LoadDeclarativeActions loads the action definitions in dir and builds an
+// action from each. LLM bodies use ctx for the LLM and tmpl for named
+// templates, or the built-in templates if tmpl is nil.
+func LoadDeclarativeActions(dir string, ctx *ActionContext, tmpl *templates.TemplateRegistry) ([]goap.Action, error) {
+	definitions, err := LoadActionDefinitions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]goap.Action, 0, len(definitions))
+	for _, definition := range definitions {
+		action, err := NewDeclarativeAction(ctx, tmpl, definition)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	log.Info("Loaded declarative actions", "dir", dir, "count", len(actions))
+	return actions, nil
+}
+
+// DeclarativeAction executes an ActionDefinition.
+type DeclarativeAction struct {
+	*goap.BaseAction
+	ctx        *ActionContext
+	tmpl       *templates.TemplateRegistry
+	definition ActionDefinition
+}
+
+// NewDeclarativeAction validates a definition and creates its action.
+func NewDeclarativeAction(ctx *ActionContext, tmpl *templates.TemplateRegistry, definition ActionDefinition) (*DeclarativeAction, error) {
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+	if tmpl == nil {
+		tmpl = templates.NewTemplateRegistry()
+	}
+	if definition.LLM != nil && definition.LLM.Template != "" {
+		if _, err := tmpl.Get(definition.LLM.Template); err != nil {
+			return nil, fmt.Errorf("action %s: %w", definition.Name, err)
+		}
+	}
+
+	return newDeclarativeAction(ctx, tmpl, definition), nil
+}
+
+// newDeclarativeAction builds an action from a definition that has already
+// been validated against tmpl.
+func newDeclarativeAction(ctx *ActionContext, tmpl *templates.TemplateRegistry, definition ActionDefinition) *DeclarativeAction {
+	cost := definition.Cost
+	if cost == 0 {
+		cost = 1.0
+	}
+	preconditions := definition.Preconditions
+	if preconditions == nil {
+		preconditions = goap.NewWorldState()
+	}
+	effects := definition.Effects
+	if effects == nil {
+		effects = goap.NewWorldState()
+	}
+
+	base := goap.NewBaseAction(definition.Name, definition.Description, preconditions.Clone(), effects.Clone(), cost)
+	base.SetInvalidates(definition.Invalidates...)
+
+	return &DeclarativeAction{
+		BaseAction: base,
+		ctx:        ctx,
+		tmpl:       tmpl,
+		definition: definition,
+	}
+}
+
+func (a *DeclarativeAction) Execute(ctx context.Context, current goap.WorldState) error {
+	if !a.CanExecute(current) {
+		return fmt.Errorf("preconditions not met for %s", a.Name())
+	}
+
+	var err error
+	switch {
+	case a.definition.Shell != nil:
+		err = a.runShell(ctx, current)
+	case a.definition.LLM != nil:
+		err = a.runLLM(current)
+	case a.definition.HTTP != nil:
+		err = a.runHTTP(ctx, current)
+	}
+	if err != nil {
+		return err
+	}
+
+	goap.ApplyEffects(current, a)
+	return nil
+}
+
+func (a *DeclarativeAction) runShell(ctx context.Context, current goap.WorldState) error {
+	body := a.definition.Shell
+	tmpl, err := parseShellTemplate(a.Name(), body.Command)
+	if err != nil {
+		return err
+	}
+	command, err := executeStateTemplate(a.Name(), tmpl, current)
+	if err != nil {
+		return err
+	}
+	dir, err := a.render(body.Dir, current)
+	if err != nil {
+		return err
+	}
+
+	if body.Timeout != "" {
+		timeout, _ := time.ParseDuration(body.Timeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	if len(body.Env) > 0 {
+		cmd.Env = os.Environ()
+		for _, key := range sortedStringKeys(body.Env) {
+			value, err := a.render(body.Env[key], current)
+			if err != nil {
+				return err
+			}
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+
+	log.Info("Running declarative shell action", "action", a.Name(), "command", command)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w\nOutput:\n%s", a.Name(), err, output)
+	}
+
+	if body.Result != "" {
+		current.Set(body.Result, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (a *DeclarativeAction) runLLM(current goap.WorldState) error {
+	body := a.definition.LLM
+	if a.ctx == nil || a.ctx.LLM == nil {
+		return fmt.Errorf("%s needs an LLM, but none is configured", a.Name())
+	}
+
+	var prompt string
+	var err error
+	if body.Template != "" {
+		prompt, err = a.renderNamedTemplate(current)
+	} else {
+		prompt, err = a.render(body.Prompt, current)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info("Generating content via LLM", "action", a.Name())
+	answer, err := llm.AnswerMe(&llm.AnswerMeParams{
+		LLM:     a.ctx.LLM,
+		Jobname: a.ctx.Jobname,
+		AgentId: a.ctx.AgentID,
+		Query:   prompt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate content for %s: %w", a.Name(), err)
+	}
+	if a.ctx.Run != nil {
+		a.ctx.Run.AppendRecord(prompt, answer, nil)
+	}
+
+	if body.Result != "" {
+		current.Set(body.Result, answer)
+	}
+	return nil
+}
+
+// renderNamedTemplate renders the LLM body's template with its rendered data,
+// or with the state if it has none.
+func (a *DeclarativeAction) renderNamedTemplate(current goap.WorldState) (string, error) {
+	body := a.definition.LLM
+	template, err := a.tmpl.Get(body.Template)
+	if err != nil {
+		return "", err
+	}
+
+	var data interface{} = map[string]interface{}(current)
+	if len(body.Data) > 0 {
+		rendered := make(map[string]string, len(body.Data))
+		for key, value := range body.Data {
+			rendered[key], err = a.render(value, current)
+			if err != nil {
+				return "", err
+			}
+		}
+		data = rendered
+	}
+	return template.RenderWithExamples(data)
+}
+
+func (a *DeclarativeAction) runHTTP(ctx context.Context, current goap.WorldState) error {
+	body := a.definition.HTTP
+	url, err := a.render(body.URL, current)
+	if err != nil {
+		return err
+	}
+	requestBody, err := a.render(body.Body, current)
+	if err != nil {
+		return err
+	}
+	method := body.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	timeout := 30 * time.Second
+	if body.Timeout != "" {
+		timeout, _ = time.ParseDuration(body.Timeout)
+	}
+	client := &http.Client{Timeout: timeout}
+
+	var reader io.Reader
+	if requestBody != "" {
+		reader = strings.NewReader(requestBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range body.Headers {
+		rendered, err := a.render(value, current)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(key, rendered)
+	}
+
+	log.Info("Making declarative HTTP request", "action", a.Name(), "method", method, "url", url)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if body.Result != "" {
+		current.Set(body.Result, string(respBody))
+		current.Set(body.Result+"_status", resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// render executes a body field as a template against the state.
+func (a *DeclarativeAction) render(text string, current goap.WorldState) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := parseStateTemplate(a.Name(), text)
+	if err != nil {
+		return "", err
+	}
+	return executeStateTemplate(a.Name(), tmpl, current)
+}
+
+func executeStateTemplate(name string, tmpl *template.Template, current goap.WorldState) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]interface{}(current)); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func (a *DeclarativeAction) Clone() goap.Action {
+	// The definition was validated when the action was created
+	return newDeclarativeAction(a.ctx, a.tmpl, a.definition)
+}
+
+// stateTemplateFuncs are the functions available to body templates.
+var stateTemplateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"shellquote": shellQuote,
+	// raw marks a shell command value as not to be quoted
+	"raw": func(value interface{}) interface{} {
+		return value
+	},
+}
+
+func shellQuote(value interface{}) string {
+	return "'" + strings.ReplaceAll(fmt.Sprint(value), "'", `'\''`) + "'"
+}
+
+func parseStateTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(stateTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template of action %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// parseShellTemplate parses a shell command template and, like html/template
+// does with its escapers, appends shellquote to every pipeline whose output
+// goes into the command, unless it already ends in shellquote or raw.
+func parseShellTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := parseStateTemplate(name, text)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			quoteActions(t.Tree, t.Tree.Root)
+		}
+	}
+	return tmpl, nil
+}
+
+func quoteActions(tree *parse.Tree, node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			quoteActions(tree, child)
+		}
+	case *parse.IfNode:
+		quoteActions(tree, node.List)
+		quoteActions(tree, node.ElseList)
+	case *parse.RangeNode:
+		quoteActions(tree, node.List)
+		quoteActions(tree, node.ElseList)
+	case *parse.WithNode:
+		quoteActions(tree, node.List)
+		quoteActions(tree, node.ElseList)
+	case *parse.ActionNode:
+		pipe := node.Pipe
+		if len(pipe.Decl) > 0 || len(pipe.Cmds) == 0 {
+			return
+		}
+		last := pipe.Cmds[len(pipe.Cmds)-1]
+		if len(last.Args) > 0 {
+			if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && (ident.Ident == "shellquote" || ident.Ident == "raw") {
+				return
+			}
+		}
+		ident := parse.NewIdentifier("shellquote").SetTree(tree).SetPos(node.Pos)
+		pipe.Cmds = append(pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: node.Pos, Args: []parse.Node{ident}})
+	}
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, key := range sortedStringKeys(m) {
+		values = append(values, m[key])
+	}
+	return values
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,241 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"upside-down-research.com/oss/agentic/internal/goap"
+)
+
+func TestActionDefinitions(t *testing.T) {
+	t.Run("LoadsDirectory", func(t *testing.T) {
+		dir := t.TempDir()
+		yamlActions := `
+- name: Vet
+  preconditions:
+    code_written: true
+  effects:
+    vet_passed: true
+  shell:
+    command: go vet {{.package}}
+- name: Notify
+  http:
+    url: "{{.webhook}}"
+`
+		jsonAction := `{"name": "Summarize", "llm": {"prompt": "Summarize {{.diff}}"}, "cost": 5}`
+		os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(yamlActions), 0644)
+		os.WriteFile(filepath.Join(dir, "b.json"), []byte(jsonAction), 0644)
+		os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an action"), 0644)
+
+		definitions, err := LoadActionDefinitions(dir)
+		if err != nil {
+			t.Fatalf("Failed to load actions: %v", err)
+		}
+		names := make([]string, len(definitions))
+		for i, definition := range definitions {
+			names[i] = definition.Name
+		}
+		if strings.Join(names, ",") != "Vet,Notify,Summarize" {
+			t.Errorf("Expected actions in file order, got %v", names)
+		}
+
+		actions, err := LoadDeclarativeActions(dir, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to build actions: %v", err)
+		}
+		if actions[0].Cost() != 1.0 || actions[2].Cost() != 5.0 {
+			t.Errorf("Expected default and declared costs, got %v and %v", actions[0].Cost(), actions[2].Cost())
+		}
+		if !actions[0].Effects().Matches(goap.WorldState{"vet_passed": true}) {
+			t.Errorf("Expected declared effects, got %v", actions[0].Effects())
+		}
+	})
+
+	t.Run("RejectsDuplicateNames", func(t *testing.T) {
+		dir := t.TempDir()
+		action := `{"name": "Vet", "shell": {"command": "go vet"}}`
+		os.WriteFile(filepath.Join(dir, "a.json"), []byte(action), 0644)
+		os.WriteFile(filepath.Join(dir, "b.json"), []byte(action), 0644)
+
+		_, err := LoadActionDefinitions(dir)
+		if err == nil || !strings.Contains(err.Error(), "already defined") {
+			t.Errorf("Expected duplicate name error, got %v", err)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		cases := map[string]ActionDefinition{
+			"no name":        {Shell: &ShellBody{Command: "true"}},
+			"no body":        {Name: "None"},
+			"two bodies":     {Name: "Two", Shell: &ShellBody{Command: "true"}, HTTP: &HTTPBody{URL: "http://x"}},
+			"no command":     {Name: "Empty", Shell: &ShellBody{}},
+			"prompt and tpl": {Name: "Both", LLM: &LLMBody{Prompt: "p", Template: "t"}},
+			"bad timeout":    {Name: "Slow", Shell: &ShellBody{Command: "true", Timeout: "soon"}},
+			"bad template":   {Name: "Broken", Shell: &ShellBody{Command: "echo {{.x"}},
+			"negative cost":  {Name: "Cheap", Cost: -1, Shell: &ShellBody{Command: "true"}},
+		}
+		for name, definition := range cases {
+			if err := definition.Validate(); err == nil {
+				t.Errorf("Expected validation error for %s", name)
+			}
+		}
+	})
+}
+
+func TestDeclarativeAction(t *testing.T) {
+	newShellAction := func(t *testing.T, body ShellBody) *DeclarativeAction {
+		action, err := NewDeclarativeAction(nil, nil, ActionDefinition{
+			Name:    "Shell",
+			Effects: goap.WorldState{"ran": true},
+			Shell:   &body,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create action: %v", err)
+		}
+		return action
+	}
+
+	t.Run("RunsShell", func(t *testing.T) {
+		action := newShellAction(t, ShellBody{
+			Command: "echo {{.greeting}} $NAME",
+			Env:     map[string]string{"NAME": "{{.name}}"},
+			Result:  "output",
+		})
+		state := goap.WorldState{"greeting": "hello", "name": "world"}
+
+		if err := action.Execute(context.Background(), state); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if state.Get("output") != "hello world" {
+			t.Errorf("Expected output %q, got %q", "hello world", state.Get("output"))
+		}
+		if state.Get("ran") != true {
+			t.Error("Expected effects to be applied")
+		}
+	})
+
+	t.Run("HonoursInvalidates", func(t *testing.T) {
+		action, err := NewDeclarativeAction(nil, nil, ActionDefinition{
+			Name:        "Edit",
+			Effects:     goap.WorldState{"edited": true},
+			Invalidates: []string{"tests_passed"},
+			Shell:       &ShellBody{Command: "true"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create action: %v", err)
+		}
+
+		for _, candidate := range []goap.Action{action, action.Clone()} {
+			state := goap.WorldState{"tests_passed": true}
+			if err := candidate.Execute(context.Background(), state); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if state.Has("tests_passed") {
+				t.Error("Expected tests_passed to be invalidated")
+			}
+			if state.Get("edited") != true {
+				t.Error("Expected effects to be applied")
+			}
+		}
+	})
+
+	t.Run("QuotesShellValues", func(t *testing.T) {
+		dir := t.TempDir()
+		marker := filepath.Join(dir, "injected")
+		hostile := "'; touch " + marker + "; echo '$(touch " + marker + ")"
+		state := goap.WorldState{"message": hostile, "work_dir": dir}
+
+		for _, command := range []string{"printf %s {{.message}}", "printf %s {{shellquote .message}}", "printf %s {{.message | json}}"} {
+			action := newShellAction(t, ShellBody{Command: command, Dir: "{{.work_dir}}", Result: "output"})
+			if err := action.Execute(context.Background(), state); err != nil {
+				t.Fatalf("Unexpected error for %s: %v", command, err)
+			}
+			if _, err := os.Stat(marker); err == nil {
+				t.Fatalf("Expected %s not to run injected commands", command)
+			}
+			if !strings.Contains(state.Get("output").(string), "touch") {
+				t.Errorf("Expected %s to print the value literally, got %q", command, state.Get("output"))
+			}
+		}
+		if state.Get("output") != `"`+strings.ReplaceAll(hostile, `"`, `\"`)+`"` {
+			t.Errorf("Expected json output to be quoted once, got %q", state.Get("output"))
+		}
+	})
+
+	t.Run("RawOptsOut", func(t *testing.T) {
+		action := newShellAction(t, ShellBody{Command: "echo {{.words | raw}}", Result: "output"})
+		state := goap.WorldState{"words": "a   b"}
+		if err := action.Execute(context.Background(), state); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if state.Get("output") != "a b" {
+			t.Errorf("Expected raw value to be split by the shell, got %q", state.Get("output"))
+		}
+	})
+
+	t.Run("MissingKeyFails", func(t *testing.T) {
+		action := newShellAction(t, ShellBody{Command: "echo {{.missing}}"})
+		state := goap.NewWorldState()
+		if err := action.Execute(context.Background(), state); err == nil {
+			t.Error("Expected error for a key missing from the state")
+		}
+		if state.Has("ran") {
+			t.Error("Expected effects not to be applied when the body fails")
+		}
+	})
+
+	t.Run("FailingCommand", func(t *testing.T) {
+		action := newShellAction(t, ShellBody{Command: "echo broken; exit 3"})
+		err := action.Execute(context.Background(), goap.NewWorldState())
+		if err == nil || !strings.Contains(err.Error(), "broken") {
+			t.Errorf("Expected error with the command output, got %v", err)
+		}
+	})
+
+	t.Run("RunsHTTP", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Header.Get("X-Ticket") != "T-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("created"))
+		}))
+		defer server.Close()
+
+		action, err := NewDeclarativeAction(nil, nil, ActionDefinition{
+			Name: "Post",
+			HTTP: &HTTPBody{
+				Method:  http.MethodPost,
+				URL:     "{{.url}}/tickets",
+				Headers: map[string]string{"X-Ticket": "{{.ticket}}"},
+				Body:    `{"ticket": {{json .ticket}}}`,
+				Result:  "response",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create action: %v", err)
+		}
+		state := goap.WorldState{"url": server.URL, "ticket": "T-1"}
+		if err := action.Execute(context.Background(), state); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if state.Get("response") != "created" || state.Get("response_status") != http.StatusOK {
+			t.Errorf("Expected response and status, got %v and %v", state.Get("response"), state.Get("response_status"))
+		}
+	})
+
+	t.Run("LLMNeedsServer", func(t *testing.T) {
+		action, err := NewDeclarativeAction(nil, nil, ActionDefinition{Name: "Ask", LLM: &LLMBody{Prompt: "Hi"}})
+		if err != nil {
+			t.Fatalf("Failed to create action: %v", err)
+		}
+		if err := action.Execute(context.Background(), goap.NewWorldState()); err == nil {
+			t.Error("Expected error without an LLM")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"go/ast"
@@ -11,9 +12,31 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/log"
+	"upside-down-research.com/oss/agentic/internal/fsutil"
 	"upside-down-research.com/oss/agentic/internal/goap"
 )
 
+// codeChangeInvalidates lists the state keys that any edit to source code
+// makes stale: test, build and quality gate results from before the edit no
+// longer describe the code.
+var codeChangeInvalidates = []string{
+	"tests_passed",
+	"go_tests_passed",
+	"failing_test_passed",
+	"target_coverage_achieved",
+	"build_succeeded",
+	"lint_passed",
+	"code_formatted",
+	"quality_gates_passed",
+}
+
+// codeEdit marks an edit action's base as invalidating earlier test, build
+// and quality gate results.
+func codeEdit(base *goap.BaseAction) *goap.BaseAction {
+	base.SetInvalidates(codeChangeInvalidates...)
+	return base
+}
+
 // FileEditAction performs text-based file edits (fallback for non-AST languages)
 type FileEditAction struct {
 	*goap.BaseAction
@@ -29,13 +52,13 @@ type TextEdit struct {
 
 func NewFileEditAction(filePath string, edits []TextEdit) *FileEditAction {
 	return &FileEditAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"FileEdit",
 			fmt.Sprintf("Edit file: %s (%d edits)", filePath, len(edits)),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"file_edited": true},
 			3.0, // Text edits are simpler than AST
-		),
+		)),
 		filePath: filePath,
 		edits:    edits,
 	}
@@ -67,7 +90,7 @@ func (a *FileEditAction) Execute(ctx context.Context, current goap.WorldState) e
 	}
 
 	// Write back
-	err = os.WriteFile(a.filePath, []byte(result), 0644)
+	err = fsutil.WriteFileAtomic(a.filePath, []byte(result), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
@@ -100,13 +123,13 @@ type ASTEdit interface {
 
 func NewGoASTEditAction(filePath string, edits []ASTEdit) *GoASTEditAction {
 	return &GoASTEditAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"GoASTEdit",
 			fmt.Sprintf("AST-based edit of Go file: %s", filePath),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"go_ast_edited": true},
 			5.0, // AST editing is more complex but safer
-		),
+		)),
 		filePath: filePath,
 		edits:    edits,
 	}
@@ -136,15 +159,15 @@ func (a *GoASTEditAction) Execute(ctx context.Context, current goap.WorldState)
 	}
 
 	// Format and write back
-	f, err := os.Create(a.filePath)
+	var buf bytes.Buffer
+	err = format.Node(&buf, fset, file)
 	if err != nil {
-		return fmt.Errorf("failed to open file for writing: %w", err)
+		return fmt.Errorf("failed to format AST: %w", err)
 	}
-	defer f.Close()
 
-	err = format.Node(f, fset, file)
+	err = fsutil.WriteFileAtomic(a.filePath, buf.Bytes(), 0644)
 	if err != nil {
-		return fmt.Errorf("failed to format AST: %w", err)
+		return fmt.Errorf("failed to write file: %w", err)
 	}
 
 	current.Set("go_ast_edited", true)
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/log"
+	"upside-down-research.com/oss/agentic/internal/fsutil"
 	"upside-down-research.com/oss/agentic/internal/goap"
 )
 
@@ -22,13 +23,13 @@ type WholesaleFileReplaceAction struct {
 
 func NewWholesaleFileReplaceAction(filePath, newContent string) *WholesaleFileReplaceAction {
 	return &WholesaleFileReplaceAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"WholesaleReplace",
 			fmt.Sprintf("Replace entire file: %s", filePath),
 			goap.WorldState{},
 			goap.WorldState{"file_replaced": true},
 			2.0,
-		),
+		)),
 		filePath:   filePath,
 		newContent: newContent,
 	}
@@ -37,7 +38,7 @@ func NewWholesaleFileReplaceAction(filePath, newContent string) *WholesaleFileRe
 func (a *WholesaleFileReplaceAction) Execute(ctx context.Context, current goap.WorldState) error {
 	log.Info("Wholesale file replacement", "file", a.filePath)
 
-	err := os.WriteFile(a.filePath, []byte(a.newContent), 0644)
+	err := fsutil.WriteFileAtomic(a.filePath, []byte(a.newContent), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to replace file: %w", err)
 	}
@@ -66,13 +67,13 @@ type PartialBlockEditAction struct {
 
 func NewPartialBlockEditAction(filePath, startMarker, endMarker, newContent string) *PartialBlockEditAction {
 	return &PartialBlockEditAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"PartialBlockEdit",
 			fmt.Sprintf("Edit block in %s between markers", filePath),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"block_edited": true},
 			3.0,
-		),
+		)),
 		filePath:    filePath,
 		startMarker: startMarker,
 		endMarker:   endMarker,
@@ -105,7 +106,7 @@ func (a *PartialBlockEditAction) Execute(ctx context.Context, current goap.World
 	// Replace block
 	result := text[:startIdx+len(a.startMarker)] + "\n" + a.newContent + "\n" + text[endIdx:]
 
-	err = os.WriteFile(a.filePath, []byte(result), 0644)
+	err = fsutil.WriteFileAtomic(a.filePath, []byte(result), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
@@ -138,13 +139,13 @@ type LineEdit struct {
 
 func NewLineBasedEditAction(filePath string, edits []LineEdit) *LineBasedEditAction {
 	return &LineBasedEditAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"LineBasedEdit",
 			fmt.Sprintf("Edit %d lines in %s", len(edits), filePath),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"lines_edited": true},
 			4.0,
-		),
+		)),
 		filePath: filePath,
 		edits:    edits,
 	}
@@ -209,7 +210,7 @@ func (a *LineBasedEditAction) Execute(ctx context.Context, current goap.WorldSta
 
 	// Write back
 	result := strings.Join(lines, "\n") + "\n"
-	err = os.WriteFile(a.filePath, []byte(result), 0644)
+	err = fsutil.WriteFileAtomic(a.filePath, []byte(result), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
@@ -242,13 +243,13 @@ type CharEdit struct {
 
 func NewCharacterBasedEditAction(filePath string, edits []CharEdit) *CharacterBasedEditAction {
 	return &CharacterBasedEditAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"CharacterBasedEdit",
 			fmt.Sprintf("Character-level edit of %s (%d edits)", filePath, len(edits)),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"chars_edited": true},
 			5.0, // Most precise, highest complexity
-		),
+		)),
 		filePath: filePath,
 		edits:    edits,
 	}
@@ -295,7 +296,7 @@ func (a *CharacterBasedEditAction) Execute(ctx context.Context, current goap.Wor
 	}
 
 	// Write back
-	err = os.WriteFile(a.filePath, []byte(text), 0644)
+	err = fsutil.WriteFileAtomic(a.filePath, []byte(text), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
@@ -329,13 +330,13 @@ type Position struct {
 
 func NewRangeEditAction(filePath string, start, end Position, newText string) *RangeEditAction {
 	return &RangeEditAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"RangeEdit",
 			fmt.Sprintf("Edit range in %s (%d:%d to %d:%d)", filePath, start.Line, start.Column, end.Line, end.Column),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"range_edited": true},
 			4.0,
-		),
+		)),
 		filePath: filePath,
 		start:    start,
 		end:      end,
@@ -398,7 +399,7 @@ func (a *RangeEditAction) Execute(ctx context.Context, current goap.WorldState)
 
 	// Write back
 	result := strings.Join(lines, "\n") + "\n"
-	err = os.WriteFile(a.filePath, []byte(result), 0644)
+	err = fsutil.WriteFileAtomic(a.filePath, []byte(result), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/log"
+	"upside-down-research.com/oss/agentic/internal/fsutil"
 	"upside-down-research.com/oss/agentic/internal/goap"
 )
 
@@ -32,13 +33,13 @@ type LSPEdit struct {
 
 func NewLSPEditAction(language, filePath string, edits []LSPEdit, lspCommand string) *LSPEditAction {
 	return &LSPEditAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"LSPEdit",
 			fmt.Sprintf("LSP-based edit of %s (%s)", filePath, language),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"lsp_edited": true},
 			6.0, // LSP operations are sophisticated
-		),
+		)),
 		language:   language,
 		filePath:   filePath,
 		edits:      edits,
@@ -209,13 +210,13 @@ type LSPRenameAction struct {
 
 func NewLSPRenameAction(language, filePath string, pos Position, oldName, newName string) *LSPRenameAction {
 	return &LSPRenameAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"LSPRename",
 			fmt.Sprintf("LSP rename %s -> %s", oldName, newName),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"symbol_renamed": true},
 			7.0, // Complex operation - needs semantic analysis
-		),
+		)),
 		language: language,
 		filePath: filePath,
 		position: pos,
@@ -278,13 +279,13 @@ type LSPExtractFunctionAction struct {
 
 func NewLSPExtractFunctionAction(language, filePath string, start, end Position, funcName string) *LSPExtractFunctionAction {
 	return &LSPExtractFunctionAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"LSPExtractFunction",
 			fmt.Sprintf("Extract function: %s", funcName),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"function_extracted": true},
 			9.0, // Very complex refactoring
-		),
+		)),
 		language:     language,
 		filePath:     filePath,
 		startPos:     start,
@@ -325,13 +326,13 @@ type LSPOrganizeImportsAction struct {
 
 func NewLSPOrganizeImportsAction(language, filePath string) *LSPOrganizeImportsAction {
 	return &LSPOrganizeImportsAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"LSPOrganizeImports",
 			fmt.Sprintf("Organize imports in %s", filePath),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"imports_organized": true},
 			3.0,
-		),
+		)),
 		language: language,
 		filePath: filePath,
 	}
@@ -384,13 +385,13 @@ type LSPCompletionInsertAction struct {
 
 func NewLSPCompletionInsertAction(language, filePath string, pos Position, trigger string, selection int) *LSPCompletionInsertAction {
 	return &LSPCompletionInsertAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"LSPCompletionInsert",
 			"Insert code via LSP completion",
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"completion_inserted": true},
 			5.0,
-		),
+		)),
 		language:    language,
 		filePath:    filePath,
 		position:    pos,
@@ -432,13 +433,13 @@ type GoLSPAction struct {
 
 func NewGoLSPAction(filePath string, edits []LSPEdit) *GoLSPAction {
 	return &GoLSPAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"GoLSPEdit",
 			fmt.Sprintf("Go LSP edit (gopls): %s", filePath),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"go_lsp_edited": true},
 			5.0, // Optimized for Go - lower cost than generic LSP
-		),
+		)),
 		filePath: filePath,
 		edits:    edits,
 	}
@@ -535,13 +536,13 @@ type RustLSPAction struct {
 
 func NewRustLSPAction(filePath string, edits []LSPEdit) *RustLSPAction {
 	return &RustLSPAction{
-		BaseAction: goap.NewBaseAction(
+		BaseAction: codeEdit(goap.NewBaseAction(
 			"RustLSPEdit",
 			fmt.Sprintf("Rust LSP edit (rust-analyzer): %s", filePath),
 			goap.WorldState{"file_exists": true},
 			goap.WorldState{"rust_lsp_edited": true},
 			5.0, // Optimized for Rust - lower cost than generic LSP
-		),
+		)),
 		filePath: filePath,
 		edits:    edits,
 	}
@@ -766,7 +767,7 @@ func ApplyWorkspaceEdit(edit *LSPWorkspaceEdit) error {
 
 		// Write back
 		result := strings.Join(lines, "\n")
-		err = os.WriteFile(filePath, []byte(result), 0644)
+		err = fsutil.WriteFileAtomic(filePath, []byte(result), 0644)
 		if err != nil {
 			return fmt.Errorf("failed to write %s: %w", filePath, err)
 		}
@@ -9,6 +9,22 @@ import (
 	"upside-down-research.com/oss/agentic/internal/goap"
 )
 
+// assumedFailureRate is the per-attempt failure probability used to estimate
+// the expected cost of retry and fallback wrappers.
+const assumedFailureRate = 0.25
+
+// expectedAttempts returns the expected number of executions of an action
+// that is retried up to maxRetries times, assuming independent failures.
+func expectedAttempts(maxRetries int) float64 {
+	attempts := 0.0
+	probability := 1.0
+	for i := 0; i <= maxRetries; i++ {
+		attempts += probability
+		probability *= assumedFailureRate
+	}
+	return attempts
+}
+
 // RetryAction wraps another action with retry logic
 type RetryAction struct {
 	*goap.BaseAction
@@ -18,18 +34,20 @@ type RetryAction struct {
 }
 
 func NewRetryAction(action goap.Action, maxRetries int, backoff time.Duration) *RetryAction {
-	return &RetryAction{
+	retry := &RetryAction{
 		BaseAction: goap.NewBaseAction(
 			fmt.Sprintf("Retry[%s]", action.Name()),
 			fmt.Sprintf("Execute %s with retry (max: %d)", action.Name(), maxRetries),
 			action.Preconditions(),
 			action.Effects(),
-			action.Cost()+2.0, // Add retry overhead
+			action.Cost()*expectedAttempts(maxRetries), // Expected cost under retries
 		),
 		wrappedAction: action,
 		maxRetries:    maxRetries,
 		backoff:       backoff,
 	}
+	retry.SetInvalidates(goap.ActionInvalidates(action)...)
+	return retry
 }
 
 func (a *RetryAction) Execute(ctx context.Context, current goap.WorldState) error {
@@ -60,6 +78,12 @@ func (a *RetryAction) Execute(ctx context.Context, current goap.WorldState) erro
 	return fmt.Errorf("action %s failed after %d retries: %w", a.wrappedAction.Name(), a.maxRetries, lastErr)
 }
 
+// CostIn returns the wrapped action's cost in the given state, scaled by the
+// expected number of attempts.
+func (a *RetryAction) CostIn(state goap.WorldState) float64 {
+	return goap.ActionCost(a.wrappedAction, state) * expectedAttempts(a.maxRetries)
+}
+
 func (a *RetryAction) Clone() goap.Action {
 	return NewRetryAction(a.wrappedAction.Clone(), a.maxRetries, a.backoff)
 }
@@ -84,17 +108,19 @@ func NewFallbackAction(primary, fallback goap.Action) *FallbackAction {
 		effects.Set(k, v)
 	}
 
-	return &FallbackAction{
+	fallbackAction := &FallbackAction{
 		BaseAction: goap.NewBaseAction(
 			fmt.Sprintf("Fallback[%s→%s]", primary.Name(), fallback.Name()),
 			fmt.Sprintf("Try %s, fallback to %s", primary.Name(), fallback.Name()),
 			preconditions,
 			effects,
-			primary.Cost()+1.0, // Small overhead
+			primary.Cost()+assumedFailureRate*fallback.Cost(), // Expected cost
 		),
 		primaryAction:  primary,
 		fallbackAction: fallback,
 	}
+	fallbackAction.SetInvalidates(append(goap.ActionInvalidates(primary), goap.ActionInvalidates(fallback)...)...)
+	return fallbackAction
 }
 
 func (a *FallbackAction) Execute(ctx context.Context, current goap.WorldState) error {
@@ -121,6 +147,12 @@ func (a *FallbackAction) Execute(ctx context.Context, current goap.WorldState) e
 	return nil
 }
 
+// CostIn returns the primary action's cost in the given state plus the
+// fallback's cost weighted by the chance the primary fails.
+func (a *FallbackAction) CostIn(state goap.WorldState) float64 {
+	return goap.ActionCost(a.primaryAction, state) + assumedFailureRate*goap.ActionCost(a.fallbackAction, state)
+}
+
 func (a *FallbackAction) Clone() goap.Action {
 	return NewFallbackAction(a.primaryAction.Clone(), a.fallbackAction.Clone())
 }
@@ -157,13 +189,22 @@ func (a *ImproveCoverageAction) Execute(ctx context.Context, current goap.WorldS
 		return fmt.Errorf("preconditions not met for ImproveCoverage")
 	}
 
-	log.Info("Starting iterative coverage improvement", "target", fmt.Sprintf("%.1f%%", a.targetCoverage), "maxIterations", a.maxIterations)
+	// The RaiseCoverage goal seeds the package and target
+	packagePath := stateString(current, "coverage_package", a.packagePath)
+	targetCoverage := a.targetCoverage
+	if target := stateString(current, "coverage_target", ""); target != "" {
+		if _, err := fmt.Sscanf(target, "%g", &targetCoverage); err != nil {
+			return fmt.Errorf("invalid coverage_target %q: %w", target, err)
+		}
+	}
+
+	log.Info("Starting iterative coverage improvement", "package", packagePath, "target", fmt.Sprintf("%.1f%%", targetCoverage), "maxIterations", a.maxIterations)
 
 	for iteration := 1; iteration <= a.maxIterations; iteration++ {
 		log.Info("Coverage improvement iteration", "iteration", iteration)
 
 		// Run tests with coverage
-		testAction := NewRunGoTestsAction(a.workDir, a.packagePath, true)
+		testAction := NewRunGoTestsAction(a.workDir, packagePath, true)
 		err := testAction.Execute(ctx, current)
 		if err != nil {
 			log.Warn("Tests failed during coverage improvement", "iteration", iteration, "error", err)
@@ -175,9 +216,9 @@ func (a *ImproveCoverageAction) Execute(ctx context.Context, current goap.WorldS
 			currentCoverage = 0.0
 		}
 
-		log.Info("Current coverage", "coverage", fmt.Sprintf("%.1f%%", currentCoverage), "target", fmt.Sprintf("%.1f%%", a.targetCoverage))
+		log.Info("Current coverage", "coverage", fmt.Sprintf("%.1f%%", currentCoverage), "target", fmt.Sprintf("%.1f%%", targetCoverage))
 
-		if currentCoverage >= a.targetCoverage {
+		if currentCoverage >= targetCoverage {
 			log.Info("Target coverage achieved!", "coverage", fmt.Sprintf("%.1f%%", currentCoverage))
 			current.Set("target_coverage_achieved", true)
 			current.Set("final_coverage", currentCoverage)
@@ -186,7 +227,7 @@ func (a *ImproveCoverageAction) Execute(ctx context.Context, current goap.WorldS
 		}
 
 		// Use LLM to identify uncovered code and generate tests
-		gap := a.targetCoverage - currentCoverage
+		gap := targetCoverage - currentCoverage
 		log.Info("Generating additional tests to close coverage gap", "gap", fmt.Sprintf("%.1f%%", gap))
 
 		// This is a simplified version - in a real implementation,
@@ -195,8 +236,8 @@ func (a *ImproveCoverageAction) Execute(ctx context.Context, current goap.WorldS
 		log.Info("LLM would generate additional tests here (simplified in this implementation)",
 			"iteration", iteration,
 			"currentCoverage", currentCoverage,
-			"target", a.targetCoverage,
-			"packagePath", a.packagePath)
+			"target", targetCoverage,
+			"packagePath", packagePath)
 
 		// Simulate adding tests (in real implementation, would write test files)
 		current.Set("coverage_improvement_attempt", iteration)
@@ -208,14 +249,14 @@ func (a *ImproveCoverageAction) Execute(ctx context.Context, current goap.WorldS
 	currentCoverage, _ := current.Get("test_coverage").(float64)
 	log.Warn("Max iterations reached without achieving target coverage",
 		"final", fmt.Sprintf("%.1f%%", currentCoverage),
-		"target", fmt.Sprintf("%.1f%%", a.targetCoverage))
+		"target", fmt.Sprintf("%.1f%%", targetCoverage))
 
 	current.Set("target_coverage_achieved", false)
 	current.Set("final_coverage", currentCoverage)
 	current.Set("coverage_iterations", a.maxIterations)
 
 	return fmt.Errorf("failed to achieve %.1f%% coverage after %d iterations (reached %.1f%%)",
-		a.targetCoverage, a.maxIterations, currentCoverage)
+		targetCoverage, a.maxIterations, currentCoverage)
 }
 
 func (a *ImproveCoverageAction) Clone() goap.Action {
@@ -230,7 +271,7 @@ type TimeoutAction struct {
 }
 
 func NewTimeoutAction(action goap.Action, timeout time.Duration) *TimeoutAction {
-	return &TimeoutAction{
+	timeoutAction := &TimeoutAction{
 		BaseAction: goap.NewBaseAction(
 			fmt.Sprintf("Timeout[%s]", action.Name()),
 			fmt.Sprintf("Execute %s with %v timeout", action.Name(), timeout),
@@ -241,6 +282,8 @@ func NewTimeoutAction(action goap.Action, timeout time.Duration) *TimeoutAction
 		wrappedAction: action,
 		timeout:       timeout,
 	}
+	timeoutAction.SetInvalidates(goap.ActionInvalidates(action)...)
+	return timeoutAction
 }
 
 func (a *TimeoutAction) Execute(ctx context.Context, current goap.WorldState) error {
@@ -273,6 +316,11 @@ func (a *TimeoutAction) Execute(ctx context.Context, current goap.WorldState) er
 	}
 }
 
+// CostIn returns the wrapped action's cost in the given state.
+func (a *TimeoutAction) CostIn(state goap.WorldState) float64 {
+	return goap.ActionCost(a.wrappedAction, state)
+}
+
 func (a *TimeoutAction) Clone() goap.Action {
 	return NewTimeoutAction(a.wrappedAction.Clone(), a.timeout)
 }
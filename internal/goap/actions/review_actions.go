@@ -231,6 +231,10 @@ type QualityGate struct {
 	Name      string
 	Condition func(goap.WorldState) bool
 	Message   string
+
+	// builtin identifies gates made by the constructors below, whose
+	// conditions can be rebuilt when the action is loaded from a plan graph
+	builtin *builtinGate
 }
 
 func NewQualityGateAction(gates []QualityGate, preconditions goap.WorldState) *QualityGateAction {
@@ -295,6 +299,7 @@ func TestsPassedGate() QualityGate {
 			return ws.Get("tests_passed") == true
 		},
 		Message: "All tests must pass",
+		builtin: &builtinGate{Kind: "tests_passed"},
 	}
 }
 
@@ -308,6 +313,7 @@ func CoverageGate(minCoverage float64) QualityGate {
 			return false
 		},
 		Message: fmt.Sprintf("Test coverage must be >= %.1f%%", minCoverage),
+		builtin: &builtinGate{Kind: "coverage", MinCoverage: minCoverage},
 	}
 }
 
@@ -318,6 +324,7 @@ func BuildSuccessGate() QualityGate {
 			return ws.Get("build_succeeded") == true
 		},
 		Message: "Build must succeed",
+		builtin: &builtinGate{Kind: "build_success"},
 	}
 }
 
@@ -328,5 +335,6 @@ func NoLintIssuesGate() QualityGate {
 			return ws.Get("lint_passed") == true
 		},
 		Message: "No linting issues allowed",
+		builtin: &builtinGate{Kind: "no_lint_issues"},
 	}
 }
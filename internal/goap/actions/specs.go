@@ -0,0 +1,831 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"upside-down-research.com/oss/agentic/internal/goap"
+	"upside-down-research.com/oss/agentic/internal/goap/templates"
+)
+
+// Action type IDs. They are stored in plan graphs, so they must never
+// change; a renamed Go type keeps its old ID.
+const (
+	TypeReadTicket               = "actions.ReadTicket"
+	TypeGeneratePlan             = "actions.GeneratePlan"
+	TypeImplementCode            = "actions.ImplementCode"
+	TypeWriteCode                = "actions.WriteCode"
+	TypeWritePlan                = "actions.WritePlan"
+	TypeHTTPRequest              = "actions.HTTPRequest"
+	TypeLLMPrompt                = "actions.LLMPrompt"
+	TypeWebhook                  = "actions.Webhook"
+	TypeBuild                    = "actions.Build"
+	TypeGoBuild                  = "actions.GoBuild"
+	TypeLint                     = "actions.Lint"
+	TypeGoFmt                    = "actions.GoFmt"
+	TypeCompileCheck             = "actions.CompileCheck"
+	TypeFileEdit                 = "actions.FileEdit"
+	TypeGitStatus                = "actions.GitStatus"
+	TypeGitAdd                   = "actions.GitAdd"
+	TypeGitCommit                = "actions.GitCommit"
+	TypeGitPush                  = "actions.GitPush"
+	TypeGitBranch                = "actions.GitBranch"
+	TypeWholesaleFileReplace     = "actions.WholesaleFileReplace"
+	TypePartialBlockEdit         = "actions.PartialBlockEdit"
+	TypeLineBasedEdit            = "actions.LineBasedEdit"
+	TypeCharacterBasedEdit       = "actions.CharacterBasedEdit"
+	TypeRangeEdit                = "actions.RangeEdit"
+	TypeLSPEdit                  = "actions.LSPEdit"
+	TypeLSPRename                = "actions.LSPRename"
+	TypeLSPExtractFunction       = "actions.LSPExtractFunction"
+	TypeLSPOrganizeImports       = "actions.LSPOrganizeImports"
+	TypeLSPCompletionInsert      = "actions.LSPCompletionInsert"
+	TypeGoLSP                    = "actions.GoLSP"
+	TypeRustLSP                  = "actions.RustLSP"
+	TypeRetry                    = "actions.Retry"
+	TypeFallback                 = "actions.Fallback"
+	TypeImproveCoverage          = "actions.ImproveCoverage"
+	TypeTimeout                  = "actions.Timeout"
+	TypeHumanReview              = "actions.HumanReview"
+	TypeAutoReview               = "actions.AutoReview"
+	TypePeerReview               = "actions.PeerReview"
+	TypeQualityGate              = "actions.QualityGate"
+	TypeTemplateBasedLLM         = "actions.TemplateBasedLLM"
+	TypeGenerateGoStruct         = "actions.GenerateGoStruct"
+	TypeGeneratePythonClass      = "actions.GeneratePythonClass"
+	TypeGenerateJavaScriptModule = "actions.GenerateJavaScriptModule"
+	TypeGenerateAPIEndpoint      = "actions.GenerateAPIEndpoint"
+	TypeRunTests                 = "actions.RunTests"
+	TypeRunGoTests               = "actions.RunGoTests"
+	TypeRunFailingTest           = "actions.RunFailingTest"
+	TypeBenchmark                = "actions.Benchmark"
+	TypeValidateState            = "actions.ValidateState"
+	TypeFileExists               = "actions.FileExists"
+	TypeCoverageThreshold        = "actions.CoverageThreshold"
+	TypeDirectoryStructure       = "actions.DirectoryStructure"
+	TypeNoErrors                 = "actions.NoErrors"
+	TypeDeclarative              = "actions.Declarative"
+)
+
+// RegisterFactories registers a factory for every serializable action type
+// in this package, and for goap.ConditionAction. Actions that need an
+// ActionContext get ctx, which is not serialized. Template-based actions
+// look their template up by name in tmpl, or in the built-in templates if
+// tmpl is nil.
+//
+// GoASTEditAction is not serializable, since its edits are code, and neither
+// are QualityGateActions with custom gates or wrappers of actions that are
+// not serializable.
+func RegisterFactories(registry *goap.ActionRegistry, ctx *ActionContext, tmpl *templates.TemplateRegistry) {
+	if tmpl == nil {
+		tmpl = templates.NewTemplateRegistry()
+	}
+
+	registry.Register(goap.TypeCondition, goap.BuildConditionAction)
+	register(registry, TypeReadTicket, func(p readTicketParams) (goap.Action, error) {
+		return NewReadTicketAction(ctx, p.TicketPath), nil
+	})
+	register(registry, TypeGeneratePlan, func(p generatePlanParams) (goap.Action, error) {
+		return NewGeneratePlanAction(ctx, p.PlannerPrompt), nil
+	})
+	register(registry, TypeImplementCode, func(p implementCodeParams) (goap.Action, error) {
+		return NewImplementCodeAction(ctx, p.ImplementPrompt, p.PlanIndex), nil
+	})
+	register(registry, TypeWriteCode, func(p writeCodeParams) (goap.Action, error) {
+		return NewWriteCodeAction(ctx, p.PlanIndex, p.RunID), nil
+	})
+	register(registry, TypeWritePlan, func(p writePlanParams) (goap.Action, error) {
+		return NewWritePlanAction(ctx, p.RunID), nil
+	})
+	register(registry, TypeHTTPRequest, func(p httpRequestParams) (goap.Action, error) {
+		return NewHTTPRequestAction(p.Method, p.URL, p.Headers, p.Body, p.ResultKey, p.Preconditions), nil
+	})
+	register(registry, TypeLLMPrompt, func(p llmPromptParams) (goap.Action, error) {
+		return NewLLMPromptAction(ctx, p.Prompt, p.ResultKey, p.Preconditions), nil
+	})
+	register(registry, TypeWebhook, func(p webhookParams) (goap.Action, error) {
+		return NewWebhookAction(p.WebhookURL, p.EventType, p.Payload, p.Preconditions), nil
+	})
+	register(registry, TypeBuild, func(p buildParams) (goap.Action, error) {
+		return NewBuildAction(p.WorkDir, p.BuildCommand, p.Args, p.OutputPath), nil
+	})
+	register(registry, TypeGoBuild, func(p goBuildParams) (goap.Action, error) {
+		return NewGoBuildAction(p.WorkDir, p.OutputPath, p.MainPath), nil
+	})
+	register(registry, TypeLint, func(p lintParams) (goap.Action, error) {
+		return NewLintAction(p.WorkDir, p.Linter, p.Paths), nil
+	})
+	register(registry, TypeGoFmt, func(p goFmtParams) (goap.Action, error) {
+		return NewGoFmtAction(p.WorkDir, p.Paths), nil
+	})
+	register(registry, TypeCompileCheck, func(p compileCheckParams) (goap.Action, error) {
+		return NewCompileCheckAction(p.WorkDir, p.PkgPath), nil
+	})
+	register(registry, TypeFileEdit, func(p fileEditParams) (goap.Action, error) {
+		return NewFileEditAction(p.FilePath, p.Edits), nil
+	})
+	register(registry, TypeGitStatus, func(p gitParams) (goap.Action, error) {
+		return NewGitStatusAction(p.WorkDir), nil
+	})
+	register(registry, TypeGitAdd, func(p gitParams) (goap.Action, error) {
+		return NewGitAddAction(p.WorkDir, p.Paths), nil
+	})
+	register(registry, TypeGitCommit, func(p gitParams) (goap.Action, error) {
+		return NewGitCommitAction(p.WorkDir, p.Message), nil
+	})
+	register(registry, TypeGitPush, func(p gitParams) (goap.Action, error) {
+		return NewGitPushAction(p.WorkDir, p.Branch), nil
+	})
+	register(registry, TypeGitBranch, func(p gitParams) (goap.Action, error) {
+		return NewGitBranchAction(p.WorkDir, p.Branch), nil
+	})
+	register(registry, TypeWholesaleFileReplace, func(p blockEditParams) (goap.Action, error) {
+		return NewWholesaleFileReplaceAction(p.FilePath, p.NewContent), nil
+	})
+	register(registry, TypePartialBlockEdit, func(p blockEditParams) (goap.Action, error) {
+		return NewPartialBlockEditAction(p.FilePath, p.StartMarker, p.EndMarker, p.NewContent), nil
+	})
+	register(registry, TypeLineBasedEdit, func(p lineEditParams) (goap.Action, error) {
+		return NewLineBasedEditAction(p.FilePath, p.Edits), nil
+	})
+	register(registry, TypeCharacterBasedEdit, func(p charEditParams) (goap.Action, error) {
+		return NewCharacterBasedEditAction(p.FilePath, p.Edits), nil
+	})
+	register(registry, TypeRangeEdit, func(p rangeEditParams) (goap.Action, error) {
+		return NewRangeEditAction(p.FilePath, p.Start, p.End, p.NewText), nil
+	})
+	register(registry, TypeLSPEdit, func(p lspParams) (goap.Action, error) {
+		return NewLSPEditAction(p.Language, p.FilePath, p.Edits, p.LSPCommand), nil
+	})
+	register(registry, TypeLSPRename, func(p lspParams) (goap.Action, error) {
+		return NewLSPRenameAction(p.Language, p.FilePath, p.Start, p.OldName, p.NewName), nil
+	})
+	register(registry, TypeLSPExtractFunction, func(p lspParams) (goap.Action, error) {
+		return NewLSPExtractFunctionAction(p.Language, p.FilePath, p.Start, p.End, p.FunctionName), nil
+	})
+	register(registry, TypeLSPOrganizeImports, func(p lspParams) (goap.Action, error) {
+		return NewLSPOrganizeImportsAction(p.Language, p.FilePath), nil
+	})
+	register(registry, TypeLSPCompletionInsert, func(p lspParams) (goap.Action, error) {
+		return NewLSPCompletionInsertAction(p.Language, p.FilePath, p.Start, p.TriggerChar, p.Selection), nil
+	})
+	register(registry, TypeGoLSP, func(p lspParams) (goap.Action, error) {
+		return NewGoLSPAction(p.FilePath, p.Edits), nil
+	})
+	register(registry, TypeRustLSP, func(p lspParams) (goap.Action, error) {
+		return NewRustLSPAction(p.FilePath, p.Edits), nil
+	})
+	register(registry, TypeRetry, func(p wrapperParams) (goap.Action, error) {
+		action, err := registry.Build(p.Action)
+		if err != nil {
+			return nil, err
+		}
+		retry := NewRetryAction(action, p.MaxRetries, p.Backoff)
+		retry.SetInvalidates(p.Invalidates...)
+		return retry, nil
+	})
+	register(registry, TypeFallback, func(p wrapperParams) (goap.Action, error) {
+		primary, err := registry.Build(p.Action)
+		if err != nil {
+			return nil, err
+		}
+		if p.Fallback == nil {
+			return nil, fmt.Errorf("fallback action missing")
+		}
+		fallback, err := registry.Build(*p.Fallback)
+		if err != nil {
+			return nil, err
+		}
+		fallbackAction := NewFallbackAction(primary, fallback)
+		fallbackAction.SetInvalidates(p.Invalidates...)
+		return fallbackAction, nil
+	})
+	register(registry, TypeTimeout, func(p wrapperParams) (goap.Action, error) {
+		action, err := registry.Build(p.Action)
+		if err != nil {
+			return nil, err
+		}
+		timeout := NewTimeoutAction(action, p.Timeout)
+		timeout.SetInvalidates(p.Invalidates...)
+		return timeout, nil
+	})
+	register(registry, TypeImproveCoverage, func(p improveCoverageParams) (goap.Action, error) {
+		return NewImproveCoverageAction(ctx, p.WorkDir, p.PackagePath, p.TargetCoverage, p.MaxIterations), nil
+	})
+	register(registry, TypeHumanReview, func(p reviewParams) (goap.Action, error) {
+		return NewHumanReviewAction(p.ReviewPrompt, p.Key, p.Preconditions), nil
+	})
+	register(registry, TypeAutoReview, func(p reviewParams) (goap.Action, error) {
+		return NewAutoReviewAction(p.Criteria, p.Key, p.Preconditions), nil
+	})
+	register(registry, TypePeerReview, func(p reviewParams) (goap.Action, error) {
+		return NewPeerReviewAction(p.Reviewers, p.Key, p.Preconditions), nil
+	})
+	register(registry, TypeQualityGate, func(p qualityGateParams) (goap.Action, error) {
+		gates := make([]QualityGate, len(p.Gates))
+		for i, builtin := range p.Gates {
+			gate, err := builtin.gate()
+			if err != nil {
+				return nil, err
+			}
+			gates[i] = gate
+		}
+		return NewQualityGateAction(gates, p.Preconditions), nil
+	})
+	register(registry, TypeTemplateBasedLLM, func(p templateParams) (goap.Action, error) {
+		template, err := tmpl.Get(p.Template)
+		if err != nil {
+			return nil, err
+		}
+		return NewTemplateBasedLLMAction(p.Name, ctx, template, p.TemplateData, p.ResultKey, p.Preconditions), nil
+	})
+	register(registry, TypeGenerateGoStruct, func(p generateParams) (goap.Action, error) {
+		return NewGenerateGoStructAction(ctx, p.Name, p.Fields), nil
+	})
+	register(registry, TypeGeneratePythonClass, func(p generateParams) (goap.Action, error) {
+		return NewGeneratePythonClassAction(ctx, p.Name, p.Members, p.BaseClass), nil
+	})
+	register(registry, TypeGenerateJavaScriptModule, func(p generateParams) (goap.Action, error) {
+		return NewGenerateJavaScriptModuleAction(ctx, p.Name, p.Members, p.IsTypeScript), nil
+	})
+	register(registry, TypeGenerateAPIEndpoint, func(p generateParams) (goap.Action, error) {
+		return NewGenerateAPIEndpointAction(ctx, p.Name, p.Method, p.Language), nil
+	})
+	register(registry, TypeRunTests, func(p testParams) (goap.Action, error) {
+		return NewRunTestsAction(p.WorkDir, p.TestCommand, p.Args), nil
+	})
+	register(registry, TypeRunGoTests, func(p testParams) (goap.Action, error) {
+		return NewRunGoTestsAction(p.WorkDir, p.PackagePath, p.WithCoverage), nil
+	})
+	register(registry, TypeRunFailingTest, func(p testParams) (goap.Action, error) {
+		return NewRunFailingTestAction(p.WorkDir, p.PackagePath), nil
+	})
+	register(registry, TypeBenchmark, func(p testParams) (goap.Action, error) {
+		return NewBenchmarkAction(p.WorkDir, p.BenchTarget), nil
+	})
+	register(registry, TypeValidateState, func(p validationParams) (goap.Action, error) {
+		return NewValidateStateAction(p.RequiredState, p.Message), nil
+	})
+	register(registry, TypeFileExists, func(p validationParams) (goap.Action, error) {
+		return NewFileExistsAction(p.Paths), nil
+	})
+	register(registry, TypeCoverageThreshold, func(p validationParams) (goap.Action, error) {
+		return NewCoverageThresholdAction(p.MinCoverage), nil
+	})
+	register(registry, TypeDirectoryStructure, func(p validationParams) (goap.Action, error) {
+		return NewDirectoryStructureAction(p.BasePath, p.RequiredDirs, p.RequiredPatterns), nil
+	})
+	register(registry, TypeNoErrors, func(p validationParams) (goap.Action, error) {
+		return NewNoErrorsAction(p.Keys), nil
+	})
+	register(registry, TypeDeclarative, func(p ActionDefinition) (goap.Action, error) {
+		return NewDeclarativeAction(ctx, tmpl, p)
+	})
+}
+
+// register registers a factory that unmarshals the spec parameters into P.
+func register[P any](registry *goap.ActionRegistry, typeID string, build func(params P) (goap.Action, error)) {
+	registry.Register(typeID, func(data json.RawMessage) (goap.Action, error) {
+		var params P
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &params); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal parameters: %w", err)
+			}
+		}
+		return build(params)
+	})
+}
+
+// Constructor parameters of each action type. Actions of similar shape
+// share a struct; unused fields are omitted from the JSON.
+
+type readTicketParams struct {
+	TicketPath string `json:"ticket_path"`
+}
+
+type generatePlanParams struct {
+	PlannerPrompt string `json:"planner_prompt"`
+}
+
+type implementCodeParams struct {
+	ImplementPrompt string `json:"implement_prompt"`
+	PlanIndex       int    `json:"plan_index"`
+}
+
+type writeCodeParams struct {
+	PlanIndex int    `json:"plan_index"`
+	RunID     string `json:"run_id"`
+}
+
+type writePlanParams struct {
+	RunID string `json:"run_id"`
+}
+
+type httpRequestParams struct {
+	Method        string            `json:"method"`
+	URL           string            `json:"url"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          []byte            `json:"body,omitempty"`
+	ResultKey     string            `json:"result_key"`
+	Preconditions goap.WorldState   `json:"preconditions,omitempty"`
+}
+
+type llmPromptParams struct {
+	Prompt        string          `json:"prompt"`
+	ResultKey     string          `json:"result_key"`
+	Preconditions goap.WorldState `json:"preconditions,omitempty"`
+}
+
+type webhookParams struct {
+	WebhookURL    string          `json:"webhook_url"`
+	EventType     string          `json:"event_type"`
+	Payload       interface{}     `json:"payload,omitempty"`
+	Preconditions goap.WorldState `json:"preconditions,omitempty"`
+}
+
+type buildParams struct {
+	WorkDir      string   `json:"work_dir"`
+	BuildCommand string   `json:"build_command"`
+	Args         []string `json:"args,omitempty"`
+	OutputPath   string   `json:"output_path,omitempty"`
+}
+
+type goBuildParams struct {
+	WorkDir    string `json:"work_dir"`
+	OutputPath string `json:"output_path,omitempty"`
+	MainPath   string `json:"main_path,omitempty"`
+}
+
+type lintParams struct {
+	WorkDir string   `json:"work_dir"`
+	Linter  string   `json:"linter"`
+	Paths   []string `json:"paths,omitempty"`
+}
+
+type goFmtParams struct {
+	WorkDir string   `json:"work_dir"`
+	Paths   []string `json:"paths,omitempty"`
+}
+
+type compileCheckParams struct {
+	WorkDir string `json:"work_dir"`
+	PkgPath string `json:"pkg_path"`
+}
+
+type fileEditParams struct {
+	FilePath string     `json:"file_path"`
+	Edits    []TextEdit `json:"edits"`
+}
+
+type gitParams struct {
+	WorkDir string   `json:"work_dir"`
+	Paths   []string `json:"paths,omitempty"`
+	Message string   `json:"message,omitempty"`
+	Branch  string   `json:"branch,omitempty"`
+}
+
+type blockEditParams struct {
+	FilePath    string `json:"file_path"`
+	StartMarker string `json:"start_marker,omitempty"`
+	EndMarker   string `json:"end_marker,omitempty"`
+	NewContent  string `json:"new_content"`
+}
+
+type lineEditParams struct {
+	FilePath string     `json:"file_path"`
+	Edits    []LineEdit `json:"edits"`
+}
+
+type charEditParams struct {
+	FilePath string     `json:"file_path"`
+	Edits    []CharEdit `json:"edits"`
+}
+
+type rangeEditParams struct {
+	FilePath string   `json:"file_path"`
+	Start    Position `json:"start"`
+	End      Position `json:"end"`
+	NewText  string   `json:"new_text"`
+}
+
+type lspParams struct {
+	Language     string    `json:"language,omitempty"`
+	FilePath     string    `json:"file_path"`
+	Edits        []LSPEdit `json:"edits,omitempty"`
+	LSPCommand   string    `json:"lsp_command,omitempty"`
+	Start        Position  `json:"start,omitempty"`
+	End          Position  `json:"end,omitempty"`
+	OldName      string    `json:"old_name,omitempty"`
+	NewName      string    `json:"new_name,omitempty"`
+	FunctionName string    `json:"function_name,omitempty"`
+	TriggerChar  string    `json:"trigger_char,omitempty"`
+	Selection    int       `json:"selection,omitempty"`
+}
+
+type wrapperParams struct {
+	Action      goap.ActionSpec  `json:"action"`
+	Fallback    *goap.ActionSpec `json:"fallback,omitempty"`
+	MaxRetries  int              `json:"max_retries,omitempty"`
+	Backoff     time.Duration    `json:"backoff,omitempty"`
+	Timeout     time.Duration    `json:"timeout,omitempty"`
+	Invalidates []string         `json:"invalidates,omitempty"`
+}
+
+type improveCoverageParams struct {
+	WorkDir        string  `json:"work_dir"`
+	PackagePath    string  `json:"package_path"`
+	TargetCoverage float64 `json:"target_coverage"`
+	MaxIterations  int     `json:"max_iterations"`
+}
+
+type reviewParams struct {
+	ReviewPrompt  string          `json:"review_prompt,omitempty"`
+	Criteria      []string        `json:"criteria,omitempty"`
+	Reviewers     []string        `json:"reviewers,omitempty"`
+	Key           string          `json:"key"`
+	Preconditions goap.WorldState `json:"preconditions,omitempty"`
+}
+
+type qualityGateParams struct {
+	Gates         []builtinGate   `json:"gates"`
+	Preconditions goap.WorldState `json:"preconditions,omitempty"`
+}
+
+// builtinGate identifies a quality gate made by one of the gate
+// constructors.
+type builtinGate struct {
+	Kind        string  `json:"kind"`
+	MinCoverage float64 `json:"min_coverage,omitempty"`
+}
+
+// gate rebuilds the quality gate.
+func (bg builtinGate) gate() (QualityGate, error) {
+	switch bg.Kind {
+	case "tests_passed":
+		return TestsPassedGate(), nil
+	case "coverage":
+		return CoverageGate(bg.MinCoverage), nil
+	case "build_success":
+		return BuildSuccessGate(), nil
+	case "no_lint_issues":
+		return NoLintIssuesGate(), nil
+	}
+	return QualityGate{}, fmt.Errorf("unknown quality gate kind %q", bg.Kind)
+}
+
+type templateParams struct {
+	Name          string          `json:"name"`
+	Template      string          `json:"template"`
+	TemplateData  interface{}     `json:"template_data,omitempty"`
+	ResultKey     string          `json:"result_key"`
+	Preconditions goap.WorldState `json:"preconditions,omitempty"`
+}
+
+type generateParams struct {
+	Name         string      `json:"name"`
+	Fields       []FieldSpec `json:"fields,omitempty"`
+	Members      []string    `json:"members,omitempty"`
+	BaseClass    string      `json:"base_class,omitempty"`
+	IsTypeScript bool        `json:"is_typescript,omitempty"`
+	Method       string      `json:"method,omitempty"`
+	Language     string      `json:"language,omitempty"`
+}
+
+type testParams struct {
+	WorkDir      string   `json:"work_dir"`
+	TestCommand  string   `json:"test_command,omitempty"`
+	Args         []string `json:"args,omitempty"`
+	PackagePath  string   `json:"package_path,omitempty"`
+	WithCoverage bool     `json:"with_coverage,omitempty"`
+	BenchTarget  string   `json:"bench_target,omitempty"`
+}
+
+type validationParams struct {
+	RequiredState    goap.WorldState `json:"required_state,omitempty"`
+	Message          string          `json:"message,omitempty"`
+	Paths            []string        `json:"paths,omitempty"`
+	MinCoverage      float64         `json:"min_coverage,omitempty"`
+	BasePath         string          `json:"base_path,omitempty"`
+	RequiredDirs     []string        `json:"required_dirs,omitempty"`
+	RequiredPatterns []string        `json:"required_patterns,omitempty"`
+	Keys             []string        `json:"keys,omitempty"`
+}
+
+// Spec methods, one per serializable action type.
+
+func (a *ReadTicketAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeReadTicket, readTicketParams{TicketPath: a.ticketPath})
+}
+
+func (a *GeneratePlanAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGeneratePlan, generatePlanParams{PlannerPrompt: a.plannerPrompt})
+}
+
+func (a *ImplementCodeAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeImplementCode, implementCodeParams{ImplementPrompt: a.implementPrompt, PlanIndex: a.planIndex})
+}
+
+func (a *WriteCodeAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeWriteCode, writeCodeParams{PlanIndex: a.planIndex, RunID: a.runID})
+}
+
+func (a *WritePlanAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeWritePlan, writePlanParams{RunID: a.runID})
+}
+
+func (a *HTTPRequestAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeHTTPRequest, httpRequestParams{
+		Method:        a.method,
+		URL:           a.url,
+		Headers:       a.headers,
+		Body:          a.body,
+		ResultKey:     a.resultKey,
+		Preconditions: a.Preconditions(),
+	})
+}
+
+func (a *LLMPromptAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeLLMPrompt, llmPromptParams{Prompt: a.prompt, ResultKey: a.resultKey, Preconditions: a.Preconditions()})
+}
+
+func (a *WebhookAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeWebhook, webhookParams{
+		WebhookURL:    a.webhookURL,
+		EventType:     a.eventType,
+		Payload:       a.payload,
+		Preconditions: a.Preconditions(),
+	})
+}
+
+func (a *BuildAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeBuild, buildParams{WorkDir: a.workDir, BuildCommand: a.buildCommand, Args: a.buildArgs, OutputPath: a.outputPath})
+}
+
+func (a *GoBuildAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGoBuild, goBuildParams{WorkDir: a.workDir, OutputPath: a.outputPath, MainPath: a.mainPath})
+}
+
+func (a *LintAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeLint, lintParams{WorkDir: a.workDir, Linter: a.linter, Paths: a.paths})
+}
+
+func (a *GoFmtAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGoFmt, goFmtParams{WorkDir: a.workDir, Paths: a.paths})
+}
+
+func (a *CompileCheckAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeCompileCheck, compileCheckParams{WorkDir: a.workDir, PkgPath: a.pkgPath})
+}
+
+func (a *FileEditAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeFileEdit, fileEditParams{FilePath: a.filePath, Edits: a.edits})
+}
+
+func (a *GitStatusAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGitStatus, gitParams{WorkDir: a.workDir})
+}
+
+func (a *GitAddAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGitAdd, gitParams{WorkDir: a.workDir, Paths: a.paths})
+}
+
+func (a *GitCommitAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGitCommit, gitParams{WorkDir: a.workDir, Message: a.message})
+}
+
+func (a *GitPushAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGitPush, gitParams{WorkDir: a.workDir, Branch: a.branch})
+}
+
+func (a *GitBranchAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGitBranch, gitParams{WorkDir: a.workDir, Branch: a.branchName})
+}
+
+func (a *WholesaleFileReplaceAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeWholesaleFileReplace, blockEditParams{FilePath: a.filePath, NewContent: a.newContent})
+}
+
+func (a *PartialBlockEditAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypePartialBlockEdit, blockEditParams{
+		FilePath:    a.filePath,
+		StartMarker: a.startMarker,
+		EndMarker:   a.endMarker,
+		NewContent:  a.newContent,
+	})
+}
+
+func (a *LineBasedEditAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeLineBasedEdit, lineEditParams{FilePath: a.filePath, Edits: a.edits})
+}
+
+func (a *CharacterBasedEditAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeCharacterBasedEdit, charEditParams{FilePath: a.filePath, Edits: a.edits})
+}
+
+func (a *RangeEditAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeRangeEdit, rangeEditParams{FilePath: a.filePath, Start: a.start, End: a.end, NewText: a.newText})
+}
+
+func (a *LSPEditAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeLSPEdit, lspParams{Language: a.language, FilePath: a.filePath, Edits: a.edits, LSPCommand: a.lspCommand})
+}
+
+func (a *LSPRenameAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeLSPRename, lspParams{
+		Language: a.language,
+		FilePath: a.filePath,
+		Start:    a.position,
+		OldName:  a.oldName,
+		NewName:  a.newName,
+	})
+}
+
+func (a *LSPExtractFunctionAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeLSPExtractFunction, lspParams{
+		Language:     a.language,
+		FilePath:     a.filePath,
+		Start:        a.startPos,
+		End:          a.endPos,
+		FunctionName: a.functionName,
+	})
+}
+
+func (a *LSPOrganizeImportsAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeLSPOrganizeImports, lspParams{Language: a.language, FilePath: a.filePath})
+}
+
+func (a *LSPCompletionInsertAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeLSPCompletionInsert, lspParams{
+		Language:    a.language,
+		FilePath:    a.filePath,
+		Start:       a.position,
+		TriggerChar: a.triggerChar,
+		Selection:   a.selection,
+	})
+}
+
+func (a *GoLSPAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGoLSP, lspParams{FilePath: a.filePath, Edits: a.edits})
+}
+
+func (a *RustLSPAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeRustLSP, lspParams{FilePath: a.filePath, Edits: a.edits})
+}
+
+func (a *RetryAction) Spec() (goap.ActionSpec, error) {
+	inner, err := wrappedSpec(a.wrappedAction)
+	if err != nil {
+		return goap.ActionSpec{}, err
+	}
+	return goap.NewActionSpec(TypeRetry, wrapperParams{
+		Action:      inner,
+		MaxRetries:  a.maxRetries,
+		Backoff:     a.backoff,
+		Invalidates: a.Invalidates(),
+	})
+}
+
+func (a *FallbackAction) Spec() (goap.ActionSpec, error) {
+	primary, err := wrappedSpec(a.primaryAction)
+	if err != nil {
+		return goap.ActionSpec{}, err
+	}
+	fallback, err := wrappedSpec(a.fallbackAction)
+	if err != nil {
+		return goap.ActionSpec{}, err
+	}
+	return goap.NewActionSpec(TypeFallback, wrapperParams{
+		Action:      primary,
+		Fallback:    &fallback,
+		Invalidates: a.Invalidates(),
+	})
+}
+
+func (a *TimeoutAction) Spec() (goap.ActionSpec, error) {
+	inner, err := wrappedSpec(a.wrappedAction)
+	if err != nil {
+		return goap.ActionSpec{}, err
+	}
+	return goap.NewActionSpec(TypeTimeout, wrapperParams{
+		Action:      inner,
+		Timeout:     a.timeout,
+		Invalidates: a.Invalidates(),
+	})
+}
+
+// wrappedSpec returns the spec of an action wrapped by a resilience action,
+// which can only be serialized if the wrapped action can.
+func wrappedSpec(action goap.Action) (goap.ActionSpec, error) {
+	spec, err := goap.ActionSpecOf(action)
+	if err != nil {
+		return goap.ActionSpec{}, fmt.Errorf("wrapped action %s: %w", action.Name(), err)
+	}
+	return spec, nil
+}
+
+func (a *ImproveCoverageAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeImproveCoverage, improveCoverageParams{
+		WorkDir:        a.workDir,
+		PackagePath:    a.packagePath,
+		TargetCoverage: a.targetCoverage,
+		MaxIterations:  a.maxIterations,
+	})
+}
+
+func (a *HumanReviewAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeHumanReview, reviewParams{ReviewPrompt: a.reviewPrompt, Key: a.reviewKey, Preconditions: a.Preconditions()})
+}
+
+func (a *AutoReviewAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeAutoReview, reviewParams{Criteria: a.reviewCriteria, Key: a.targetKey, Preconditions: a.Preconditions()})
+}
+
+func (a *PeerReviewAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypePeerReview, reviewParams{Reviewers: a.reviewers, Key: a.codeKey, Preconditions: a.Preconditions()})
+}
+
+// Spec is empty unless every gate was made by a gate constructor, since
+// custom conditions cannot be serialized.
+func (a *QualityGateAction) Spec() (goap.ActionSpec, error) {
+	gates := make([]builtinGate, len(a.gates))
+	for i, gate := range a.gates {
+		if gate.builtin == nil {
+			return goap.ActionSpec{}, fmt.Errorf("gate %s has a custom condition: %w", gate.Name, goap.ErrNotSerializable)
+		}
+		gates[i] = *gate.builtin
+	}
+	return goap.NewActionSpec(TypeQualityGate, qualityGateParams{Gates: gates, Preconditions: a.Preconditions()})
+}
+
+// Spec stores the template by name, so it must be registered with the
+// template registry passed to RegisterFactories.
+func (a *TemplateBasedLLMAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeTemplateBasedLLM, templateParams{
+		Name:          a.Name(),
+		Template:      a.template.Name(),
+		TemplateData:  a.templateData,
+		ResultKey:     a.resultKey,
+		Preconditions: a.Preconditions(),
+	})
+}
+
+func (a *GenerateGoStructAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGenerateGoStruct, generateParams{Name: a.structName, Fields: a.fields})
+}
+
+func (a *GeneratePythonClassAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGeneratePythonClass, generateParams{Name: a.className, Members: a.methods, BaseClass: a.baseClass})
+}
+
+func (a *GenerateJavaScriptModuleAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGenerateJavaScriptModule, generateParams{Name: a.moduleName, Members: a.exports, IsTypeScript: a.isTypeScript})
+}
+
+func (a *GenerateAPIEndpointAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeGenerateAPIEndpoint, generateParams{Name: a.endpoint, Method: a.method, Language: a.language})
+}
+
+func (a *RunTestsAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeRunTests, testParams{WorkDir: a.workDir, TestCommand: a.testCommand, Args: a.testArgs})
+}
+
+func (a *RunGoTestsAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeRunGoTests, testParams{WorkDir: a.workDir, PackagePath: a.packagePath, WithCoverage: a.withCoverage})
+}
+
+func (a *RunFailingTestAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeRunFailingTest, testParams{WorkDir: a.workDir, PackagePath: a.packagePath})
+}
+
+func (a *BenchmarkAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeBenchmark, testParams{WorkDir: a.workDir, BenchTarget: a.benchTarget})
+}
+
+func (a *ValidateStateAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeValidateState, validationParams{RequiredState: a.requiredState, Message: a.validationMsg})
+}
+
+func (a *FileExistsAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeFileExists, validationParams{Paths: a.filePaths})
+}
+
+func (a *CoverageThresholdAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeCoverageThreshold, validationParams{MinCoverage: a.minCoverage})
+}
+
+func (a *DirectoryStructureAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeDirectoryStructure, validationParams{
+		BasePath:         a.basePath,
+		RequiredDirs:     a.requiredDirs,
+		RequiredPatterns: a.requiredPatterns,
+	})
+}
+
+func (a *NoErrorsAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeNoErrors, validationParams{Keys: a.errorKeys})
+}
+
+func (a *DeclarativeAction) Spec() (goap.ActionSpec, error) {
+	return goap.NewActionSpec(TypeDeclarative, a.definition)
+}
@@ -0,0 +1,54 @@
+package actions
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"upside-down-research.com/oss/agentic/internal/goap"
+)
+
+func TestWrapperSpecs(t *testing.T) {
+	registry := goap.NewActionRegistry()
+	RegisterFactories(registry, nil, nil)
+
+	t.Run("KeepsInvalidates", func(t *testing.T) {
+		retry := NewRetryAction(NewGoFmtAction("/tmp", []string{"./..."}), 2, time.Second)
+		retry.SetInvalidates("lint_passed")
+
+		spec, err := goap.ActionSpecOf(retry)
+		if err != nil {
+			t.Fatalf("Expected the retry to be serializable, got %v", err)
+		}
+		rebuilt, err := registry.Build(spec)
+		if err != nil {
+			t.Fatalf("Failed to rebuild action: %v", err)
+		}
+		if invalidates := goap.ActionInvalidates(rebuilt); !slices.Equal(invalidates, []string{"lint_passed"}) {
+			t.Errorf("Expected invalidates [lint_passed], got %v", invalidates)
+		}
+	})
+
+	t.Run("RejectsUnserializableWrapped", func(t *testing.T) {
+		inner := goap.NewSimpleAction("Inline", "Inline", goap.WorldState{}, goap.WorldState{"done": true}, 1.0, nil)
+		for _, wrapper := range []goap.Action{
+			NewRetryAction(inner, 2, time.Second),
+			NewTimeoutAction(inner, time.Second),
+			NewFallbackAction(NewGoFmtAction("/tmp", nil), inner),
+		} {
+			if _, err := goap.ActionSpecOf(wrapper); !errors.Is(err, goap.ErrNotSerializable) {
+				t.Errorf("Expected ErrNotSerializable for %s, got %v", wrapper.Name(), err)
+			}
+		}
+	})
+
+	t.Run("RejectsCostFunc", func(t *testing.T) {
+		timeout := NewTimeoutAction(NewGoFmtAction("/tmp", nil), time.Second)
+		timeout.SetCostFunc(func(state goap.WorldState) float64 { return 1.0 })
+
+		if _, err := goap.ActionSpecOf(timeout); !errors.Is(err, goap.ErrNotSerializable) {
+			t.Errorf("Expected ErrNotSerializable for an action with a cost function, got %v", err)
+		}
+	})
+}
@@ -305,20 +305,24 @@ func (a *GenerateAPIEndpointAction) Execute(ctx context.Context, current goap.Wo
 		return fmt.Errorf("preconditions not met for GenerateAPIEndpoint")
 	}
 
+	// The AddHTTPEndpoint goal seeds the endpoint to generate
+	endpoint := stateString(current, "endpoint_path", a.endpoint)
+	method := strings.ToUpper(stateString(current, "endpoint_method", a.method))
+
 	log.Info("Generating API endpoint template",
-		"endpoint", a.endpoint,
-		"method", a.method,
+		"endpoint", endpoint,
+		"method", method,
 		"language", a.language)
 
 	var code string
 
 	switch a.language {
 	case "go":
-		code = a.generateGoEndpoint()
+		code = generateGoEndpoint(endpoint, method)
 	case "python":
-		code = a.generatePythonEndpoint()
+		code = generatePythonEndpoint(endpoint, method)
 	case "javascript", "typescript":
-		code = a.generateJavaScriptEndpoint()
+		code = generateJavaScriptEndpoint(endpoint, method)
 	default:
 		return fmt.Errorf("unsupported language: %s", a.language)
 	}
@@ -330,7 +334,7 @@ func (a *GenerateAPIEndpointAction) Execute(ctx context.Context, current goap.Wo
 	return nil
 }
 
-func (a *GenerateAPIEndpointAction) generateGoEndpoint() string {
+func generateGoEndpoint(endpoint, method string) string {
 	return fmt.Sprintf(`func Handle%s(w http.ResponseWriter, r *http.Request) {
 	// TODO: Implement %s %s handler
 
@@ -349,10 +353,10 @@ func (a *GenerateAPIEndpointAction) generateGoEndpoint() string {
 		"status": "success",
 	})
 }
-`, strings.Title(strings.ToLower(a.method)), a.method, a.endpoint, a.method)
+`, strings.Title(strings.ToLower(method)), method, endpoint, method)
 }
 
-func (a *GenerateAPIEndpointAction) generatePythonEndpoint() string {
+func generatePythonEndpoint(endpoint, method string) string {
 	return fmt.Sprintf(`@app.route('%s', methods=['%s'])
 def handle_%s():
     """
@@ -376,10 +380,10 @@ def handle_%s():
             'status': 'error',
             'message': str(e)
         }), 500
-`, a.endpoint, a.method, strings.ToLower(a.method), a.method, a.endpoint)
+`, endpoint, method, strings.ToLower(method), method, endpoint)
 }
 
-func (a *GenerateAPIEndpointAction) generateJavaScriptEndpoint() string {
+func generateJavaScriptEndpoint(endpoint, method string) string {
 	return fmt.Sprintf(`app.%s('%s', async (req, res) => {
   /**
    * Handle %s %s request
@@ -404,7 +408,7 @@ func (a *GenerateAPIEndpointAction) generateJavaScriptEndpoint() string {
     });
   }
 });
-`, strings.ToLower(a.method), a.endpoint, a.method, a.endpoint)
+`, strings.ToLower(method), endpoint, method, endpoint)
 }
 
 func (a *GenerateAPIEndpointAction) Clone() goap.Action {
@@ -0,0 +1,34 @@
+package actions
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"upside-down-research.com/oss/agentic/internal/goap"
+)
+
+func TestGenerateAPIEndpointAction(t *testing.T) {
+	t.Run("EndpointFromState", func(t *testing.T) {
+		action := NewGenerateAPIEndpointAction(nil, "/", "GET", "go")
+		state := goap.WorldState{"project_initialized": true, "endpoint_path": "/users/{id}", "endpoint_method": "delete"}
+		if err := action.Execute(context.Background(), state); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		code := state.Get("api_endpoint_code").(string)
+		if !strings.Contains(code, "func HandleDelete") || !strings.Contains(code, "DELETE /users/{id}") {
+			t.Errorf("Expected a DELETE /users/{id} handler, got %s", code)
+		}
+	})
+
+	t.Run("DefaultEndpoint", func(t *testing.T) {
+		action := NewGenerateAPIEndpointAction(nil, "/health", "GET", "go")
+		state := goap.WorldState{"project_initialized": true}
+		if err := action.Execute(context.Background(), state); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if code := state.Get("api_endpoint_code").(string); !strings.Contains(code, "GET /health") {
+			t.Errorf("Expected the constructed endpoint, got %s", code)
+		}
+	})
+}
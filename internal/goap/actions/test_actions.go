@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"regexp"
 	"strings"
 	"time"
 
@@ -101,13 +102,16 @@ func (a *RunGoTestsAction) Execute(ctx context.Context, current goap.WorldState)
 		return fmt.Errorf("preconditions not met for RunGoTests")
 	}
 
+	// Goals such as FixFailingTest and RaiseCoverage seed the package to
+	// test. All of its tests run; RunFailingTest runs the failing one alone.
+	packagePath := stateString(current, "failing_test_package", stateString(current, "coverage_package", a.packagePath))
 	args := []string{"test", "-v"}
 	if a.withCoverage {
 		args = append(args, "-cover")
 	}
-	args = append(args, a.packagePath)
+	args = append(args, packagePath)
 
-	log.Info("Running Go tests", "package", a.packagePath, "coverage", a.withCoverage)
+	log.Info("Running Go tests", "package", packagePath, "coverage", a.withCoverage)
 
 	start := time.Now()
 	cmd := exec.CommandContext(ctx, "go", args...)
@@ -143,6 +147,63 @@ func (a *RunGoTestsAction) Clone() goap.Action {
 	return NewRunGoTestsAction(a.workDir, a.packagePath, a.withCoverage)
 }
 
+// RunFailingTestAction runs only the test a goal such as FixFailingTest
+// names in failing_test, so that its result does not stand in for the
+// package's full test run.
+type RunFailingTestAction struct {
+	*goap.BaseAction
+	workDir     string
+	packagePath string
+}
+
+func NewRunFailingTestAction(workDir, packagePath string) *RunFailingTestAction {
+	return &RunFailingTestAction{
+		BaseAction: goap.NewBaseAction(
+			"RunFailingTest",
+			fmt.Sprintf("Run the failing Go test in %s", packagePath),
+			goap.WorldState{"code_written": true},
+			goap.WorldState{"failing_test_passed": true},
+			4.0,
+		),
+		workDir:     workDir,
+		packagePath: packagePath,
+	}
+}
+
+func (a *RunFailingTestAction) Execute(ctx context.Context, current goap.WorldState) error {
+	if !a.CanExecute(current) {
+		return fmt.Errorf("preconditions not met for RunFailingTest")
+	}
+
+	test := stateString(current, "failing_test", "")
+	if test == "" {
+		return fmt.Errorf("no failing_test in state")
+	}
+	packagePath := stateString(current, "failing_test_package", a.packagePath)
+
+	log.Info("Running failing Go test", "test", test, "package", packagePath)
+
+	cmd := exec.CommandContext(ctx, "go", "test", "-v", "-run", "^"+regexp.QuoteMeta(test)+"$", packagePath)
+	cmd.Dir = a.workDir
+
+	output, err := cmd.CombinedOutput()
+	current.Set("test_output", string(output))
+
+	if err != nil {
+		current.Set("failing_test_passed", false)
+		log.Error("Failing test still fails", "test", test, "error", err)
+		return fmt.Errorf("test %s failed: %w\nOutput:\n%s", test, err, output)
+	}
+
+	current.Set("failing_test_passed", true)
+	log.Info("Failing test passes", "test", test)
+	return nil
+}
+
+func (a *RunFailingTestAction) Clone() goap.Action {
+	return NewRunFailingTestAction(a.workDir, a.packagePath)
+}
+
 // BenchmarkAction runs performance benchmarks
 type BenchmarkAction struct {
 	*goap.BaseAction
@@ -212,3 +273,16 @@ func parseCoverage(output string) float64 {
 	}
 	return 0.0
 }
+
+// stateString returns the state value of key as a string, or fallback if
+// the key is not set or empty.
+func stateString(current goap.WorldState, key, fallback string) string {
+	if !current.Has(key) {
+		return fallback
+	}
+	value := fmt.Sprint(current.Get(key))
+	if value == "" {
+		return fallback
+	}
+	return value
+}
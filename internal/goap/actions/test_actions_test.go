@@ -0,0 +1,100 @@
+package actions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"upside-down-research.com/oss/agentic/internal/goap"
+)
+
+func TestRunGoTestsAction(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/sample\n\ngo 1.21\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "good"), 0755)
+	os.WriteFile(filepath.Join(dir, "good", "good_test.go"), []byte(`package good
+
+import "testing"
+
+func TestPasses(t *testing.T) {}
+
+func TestFails(t *testing.T) { t.Fatal("broken") }
+`), 0644)
+	os.MkdirAll(filepath.Join(dir, "bad"), 0755)
+	os.WriteFile(filepath.Join(dir, "bad", "bad_test.go"), []byte(`package bad
+
+import "testing"
+
+func TestFails(t *testing.T) { t.Fatal("broken") }
+`), 0644)
+
+	t.Run("RunsAllByDefault", func(t *testing.T) {
+		action := NewRunGoTestsAction(dir, "./...", false)
+		state := goap.WorldState{"code_written": true}
+		if err := action.Execute(context.Background(), state); err == nil {
+			t.Error("Expected the failing tests to fail the run")
+		}
+	})
+
+	t.Run("RunsAllTestsOfFailingTestPackage", func(t *testing.T) {
+		action := NewRunGoTestsAction(dir, "./...", false)
+		state := goap.WorldState{"code_written": true, "failing_test": "TestPasses", "failing_test_package": "./good"}
+		if err := action.Execute(context.Background(), state); err == nil {
+			t.Fatal("Expected the full run of ./good to include TestFails")
+		}
+		if state.Get("go_tests_passed") != false {
+			t.Errorf("Expected go_tests_passed false, got %v", state.Get("go_tests_passed"))
+		}
+	})
+
+	t.Run("RunsCoveragePackageFromState", func(t *testing.T) {
+		action := NewRunGoTestsAction(dir, "./...", false)
+		state := goap.WorldState{"code_written": true, "coverage_package": "./bad"}
+		err := action.Execute(context.Background(), state)
+		if err == nil || !strings.Contains(err.Error(), "example.com/sample/bad") {
+			t.Errorf("Expected the tests of ./bad to run, got %v", err)
+		}
+	})
+}
+
+func TestRunFailingTestAction(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/sample\n\ngo 1.21\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "good"), 0755)
+	os.WriteFile(filepath.Join(dir, "good", "good_test.go"), []byte(`package good
+
+import "testing"
+
+func TestPasses(t *testing.T) {}
+
+func TestFails(t *testing.T) { t.Fatal("broken") }
+`), 0644)
+
+	t.Run("RunsOnlyFailingTest", func(t *testing.T) {
+		action := NewRunFailingTestAction(dir, "./...")
+		state := goap.WorldState{"code_written": true, "failing_test": "TestPasses", "failing_test_package": "./good"}
+		if err := action.Execute(context.Background(), state); err != nil {
+			t.Fatalf("Expected only TestPasses in ./good to run, got %v", err)
+		}
+		output := state.Get("test_output").(string)
+		if strings.Contains(output, "TestFails") {
+			t.Errorf("Expected other tests to be filtered out, got %s", output)
+		}
+		if state.Get("failing_test_passed") != true {
+			t.Errorf("Expected failing_test_passed true, got %v", state.Get("failing_test_passed"))
+		}
+		if state.Has("go_tests_passed") {
+			t.Errorf("Expected go_tests_passed to be left to the full run, got %v", state.Get("go_tests_passed"))
+		}
+	})
+
+	t.Run("RequiresFailingTest", func(t *testing.T) {
+		action := NewRunFailingTestAction(dir, "./...")
+		state := goap.WorldState{"code_written": true}
+		if err := action.Execute(context.Background(), state); err == nil {
+			t.Error("Expected an error without failing_test in state")
+		}
+	})
+}
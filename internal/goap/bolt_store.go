@@ -0,0 +1,297 @@
+package goap
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltGraphFile is the conventional file name of a BoltGraphStore database
+// inside an output directory.
+const BoltGraphFile = "plan_graphs.db"
+
+var (
+	boltNodesBucket = []byte("nodes")
+	boltGraphKey    = []byte("graph")
+)
+
+// boltGraphHeader is the part of a PlanGraph stored apart from its nodes.
+type boltGraphHeader struct {
+	RootNodeID string        `json:"root_node_id"`
+	Metadata   GraphMetadata `json:"metadata"`
+}
+
+// BoltGraphStore stores all runs' graphs in a single embedded bbolt database
+// file. Each run has its own bucket holding the graph header and one record
+// per node, so a node update reads and writes a single record inside a
+// transaction. The database file is locked while open, so only one process
+// can use it at a time.
+type BoltGraphStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltGraphStore opens or creates the database file at path.
+func OpenBoltGraphStore(path string) (*BoltGraphStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open graph database %s: %w", path, err)
+	}
+	return &BoltGraphStore{db: db}, nil
+}
+
+// SaveGraph replaces the run's bucket with the graph in one transaction.
+func (s *BoltGraphStore) SaveGraph(graph *PlanGraph, runID string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putGraph(tx, graph, runID)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("Plan graph saved", "runID", runID, "nodes", len(graph.Nodes))
+	return nil
+}
+
+// LoadGraph reads the run's graph header and every node.
+func (s *BoltGraphStore) LoadGraph(runID string) (*PlanGraph, error) {
+	var graph *PlanGraph
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		graph, err = getGraph(tx, runID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Plan graph loaded", "nodes", len(graph.Nodes))
+	return graph, nil
+}
+
+// LoadNodeContext reads the node, its parent, children and siblings, and its
+// ancestors for the path from the root.
+func (s *BoltGraphStore) LoadNodeContext(runID, nodeID string) (*NodeContext, error) {
+	var context *NodeContext
+	err := s.db.View(func(tx *bolt.Tx) error {
+		nodes, err := nodesBucket(tx, runID)
+		if err != nil {
+			return err
+		}
+
+		node, err := getNode(nodes, nodeID)
+		if err != nil {
+			return err
+		}
+
+		context = &NodeContext{
+			Node:         node,
+			Children:     []*GraphNode{},
+			Siblings:     []*GraphNode{},
+			PathFromRoot: []string{nodeID},
+		}
+
+		if node.ParentID != "" {
+			parent, err := getNode(nodes, node.ParentID)
+			if err != nil {
+				return err
+			}
+			context.Parent = parent
+
+			for _, siblingID := range parent.ChildIDs {
+				if siblingID == nodeID {
+					continue
+				}
+				sibling, err := getNode(nodes, siblingID)
+				if err != nil {
+					return err
+				}
+				context.Siblings = append(context.Siblings, sibling)
+			}
+		}
+
+		for _, childID := range node.ChildIDs {
+			child, err := getNode(nodes, childID)
+			if err != nil {
+				return err
+			}
+			context.Children = append(context.Children, child)
+		}
+
+		for ancestor := node; ancestor.ParentID != ""; {
+			context.PathFromRoot = append([]string{ancestor.ParentID}, context.PathFromRoot...)
+			ancestor, err = getNode(nodes, ancestor.ParentID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load node context: %w", err)
+	}
+
+	return context, nil
+}
+
+// UpdateNode reads, updates and writes a single node record in one
+// transaction.
+func (s *BoltGraphStore) UpdateNode(runID, nodeID string, update func(node *GraphNode) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		nodes, err := nodesBucket(tx, runID)
+		if err != nil {
+			return err
+		}
+
+		node, err := getNode(nodes, nodeID)
+		if err != nil {
+			return err
+		}
+
+		err = update(node)
+		if err != nil {
+			return err
+		}
+
+		return putNode(nodes, node)
+	})
+}
+
+// UpdateGraph loads, updates and rewrites the run's graph in one transaction.
+func (s *BoltGraphStore) UpdateGraph(runID string, update func(graph *PlanGraph) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		graph, err := getGraph(tx, runID)
+		if err != nil {
+			return err
+		}
+
+		err = update(graph)
+		if err != nil {
+			return err
+		}
+
+		return putGraph(tx, graph, runID)
+	})
+}
+
+// Runs lists the runs' bucket names.
+func (s *BoltGraphStore) Runs() ([]string, error) {
+	runIDs := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			runIDs = append(runIDs, string(name))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	return runIDs, nil
+}
+
+// Close closes the database file.
+func (s *BoltGraphStore) Close() error {
+	return s.db.Close()
+}
+
+// putGraph replaces the run's bucket with the graph.
+func putGraph(tx *bolt.Tx, graph *PlanGraph, runID string) error {
+	if tx.Bucket([]byte(runID)) != nil {
+		if err := tx.DeleteBucket([]byte(runID)); err != nil {
+			return fmt.Errorf("failed to delete graph of run %s: %w", runID, err)
+		}
+	}
+
+	run, err := tx.CreateBucket([]byte(runID))
+	if err != nil {
+		return fmt.Errorf("failed to create graph of run %s: %w", runID, err)
+	}
+
+	headerJSON, err := json.Marshal(boltGraphHeader{RootNodeID: graph.RootNodeID, Metadata: graph.Metadata})
+	if err != nil {
+		return fmt.Errorf("failed to marshal graph: %w", err)
+	}
+	if err := run.Put(boltGraphKey, headerJSON); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+
+	nodes, err := run.CreateBucket(boltNodesBucket)
+	if err != nil {
+		return fmt.Errorf("failed to create nodes of run %s: %w", runID, err)
+	}
+	for _, node := range graph.Nodes {
+		if err := putNode(nodes, node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getGraph reads the run's graph header and every node.
+func getGraph(tx *bolt.Tx, runID string) (*PlanGraph, error) {
+	run := tx.Bucket([]byte(runID))
+	if run == nil {
+		return nil, fmt.Errorf("graph not found for run %s", runID)
+	}
+
+	var header boltGraphHeader
+	if err := json.Unmarshal(run.Get(boltGraphKey), &header); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal graph: %w", err)
+	}
+
+	graph := &PlanGraph{
+		RootNodeID: header.RootNodeID,
+		Nodes:      make(map[string]*GraphNode),
+		Metadata:   header.Metadata,
+	}
+
+	err := run.Bucket(boltNodesBucket).ForEach(func(_, data []byte) error {
+		var node GraphNode
+		if err := json.Unmarshal(data, &node); err != nil {
+			return fmt.Errorf("failed to unmarshal node: %w", err)
+		}
+		graph.Nodes[node.ID] = &node
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return graph, nil
+}
+
+func nodesBucket(tx *bolt.Tx, runID string) (*bolt.Bucket, error) {
+	run := tx.Bucket([]byte(runID))
+	if run == nil {
+		return nil, fmt.Errorf("graph not found for run %s", runID)
+	}
+	return run.Bucket(boltNodesBucket), nil
+}
+
+func getNode(nodes *bolt.Bucket, nodeID string) (*GraphNode, error) {
+	data := nodes.Get([]byte(nodeID))
+	if data == nil {
+		return nil, fmt.Errorf("node not found: %s", nodeID)
+	}
+
+	var node GraphNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal node %s: %w", nodeID, err)
+	}
+	return &node, nil
+}
+
+func putNode(nodes *bolt.Bucket, node *GraphNode) error {
+	data, err := json.Marshal(node)
+	if err != nil {
+		return fmt.Errorf("failed to marshal node %s: %w", node.ID, err)
+	}
+	if err := nodes.Put([]byte(node.ID), data); err != nil {
+		return fmt.Errorf("failed to write node %s: %w", node.ID, err)
+	}
+	return nil
+}
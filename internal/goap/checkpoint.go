@@ -0,0 +1,339 @@
+package goap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"upside-down-research.com/oss/agentic/internal/fsutil"
+)
+
+// largeValueBytes is the JSON size above which a WorldState value is stored
+// in a separate blob file instead of inline in the checkpoint.
+const largeValueBytes = 4 * 1024
+
+// CheckpointPhase says whether a checkpoint was taken before or after a node
+// executed.
+type CheckpointPhase string
+
+const (
+	CheckpointBefore CheckpointPhase = "before"
+	CheckpointAfter  CheckpointPhase = "after"
+)
+
+// Checkpoint is a versioned snapshot of the WorldState the executor believed
+// in before or after executing a node. Versions increase monotonically within
+// a run, so a node that executes more than once has several checkpoints.
+type Checkpoint struct {
+	Version   int             `json:"version"`
+	NodeID    string          `json:"node_id"`
+	Phase     CheckpointPhase `json:"phase"`
+	Timestamp string          `json:"timestamp"`
+
+	// State holds the inline values of the snapshot
+	State WorldState `json:"state"`
+
+	// Blobs maps keys whose values were too large to inline to the content
+	// hash of the blob file holding them. Blobs are shared between
+	// checkpoints with identical values.
+	Blobs map[string]string `json:"blobs,omitempty"`
+}
+
+// StateDiff describes how the WorldState changed between two checkpoints.
+type StateDiff struct {
+	Added   map[string]interface{} `json:"added"`
+	Removed map[string]interface{} `json:"removed"`
+	Changed map[string]ValueChange `json:"changed"`
+}
+
+// ValueChange is a value that differs between two checkpoints.
+type ValueChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// IsEmpty reports whether the two checkpoints hold the same state.
+func (sd *StateDiff) IsEmpty() bool {
+	return len(sd.Added) == 0 && len(sd.Removed) == 0 && len(sd.Changed) == 0
+}
+
+// String returns a human-readable rendering of the diff, one key per line.
+func (sd *StateDiff) String() string {
+	var b strings.Builder
+	for _, key := range sortedKeys(sd.Added) {
+		fmt.Fprintf(&b, "+ %s: %v\n", key, sd.Added[key])
+	}
+	for _, key := range sortedKeys(sd.Removed) {
+		fmt.Fprintf(&b, "- %s: %v\n", key, sd.Removed[key])
+	}
+	changed := make([]string, 0, len(sd.Changed))
+	for key := range sd.Changed {
+		changed = append(changed, key)
+	}
+	sort.Strings(changed)
+	for _, key := range changed {
+		fmt.Fprintf(&b, "~ %s: %v → %v\n", key, sd.Changed[key].Before, sd.Changed[key].After)
+	}
+	return b.String()
+}
+
+// DiffStates compares two WorldStates.
+func DiffStates(before, after WorldState) *StateDiff {
+	diff := &StateDiff{
+		Added:   make(map[string]interface{}),
+		Removed: make(map[string]interface{}),
+		Changed: make(map[string]ValueChange),
+	}
+	for key, value := range after {
+		beforeValue, exists := before[key]
+		if !exists {
+			diff.Added[key] = value
+		} else if !reflect.DeepEqual(beforeValue, value) {
+			diff.Changed[key] = ValueChange{Before: beforeValue, After: value}
+		}
+	}
+	for key, value := range before {
+		if _, exists := after[key]; !exists {
+			diff.Removed[key] = value
+		}
+	}
+	return diff
+}
+
+// SaveCheckpoint saves a new version of the WorldState for a node and phase.
+// Values larger than largeValueBytes are written to content-addressed blob
+// files. The snapshot is not made the run's latest state; see
+// SaveStateCheckpoint.
+func (gp *GraphPersistence) SaveCheckpoint(runID, nodeID string, phase CheckpointPhase, state WorldState) (*Checkpoint, error) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+
+	checkpointsDir := gp.checkpointsDir(runID)
+	blobsDir := filepath.Join(checkpointsDir, "blobs")
+	err := os.MkdirAll(blobsDir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoints directory: %w", err)
+	}
+
+	last, known := gp.checkpointVersions[runID]
+	if !known {
+		files, err := gp.checkpointFiles(runID)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 {
+			last = checkpointVersion(files[len(files)-1])
+		}
+	}
+
+	checkpoint := &Checkpoint{
+		Version:   last + 1,
+		NodeID:    nodeID,
+		Phase:     phase,
+		Timestamp: time.Now().Format(time.RFC3339),
+		State:     NewWorldState(),
+		Blobs:     make(map[string]string),
+	}
+
+	for key, value := range state {
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal state key %s: %w", key, err)
+		}
+		if len(valueJSON) <= largeValueBytes {
+			checkpoint.State[key] = value
+			continue
+		}
+
+		sum := sha256.Sum256(valueJSON)
+		hash := hex.EncodeToString(sum[:])
+		blobPath := filepath.Join(blobsDir, hash+".json")
+		if _, err := os.Stat(blobPath); os.IsNotExist(err) {
+			if err := fsutil.WriteFileAtomic(blobPath, valueJSON, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write blob for state key %s: %w", key, err)
+			}
+		}
+		checkpoint.Blobs[key] = hash
+	}
+
+	checkpointJSON, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	name := fmt.Sprintf("%06d_%s_%s.json", checkpoint.Version, nodeID, phase)
+	err = fsutil.WriteFileAtomic(filepath.Join(checkpointsDir, name), checkpointJSON, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	gp.checkpointVersions[runID] = checkpoint.Version
+
+	return checkpoint, nil
+}
+
+// ListCheckpoints returns a run's checkpoints in version order. Large values
+// are not loaded; use LoadCheckpoint for the full state.
+func (gp *GraphPersistence) ListCheckpoints(runID string) ([]*Checkpoint, error) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+
+	files, err := gp.checkpointFiles(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := make([]*Checkpoint, 0, len(files))
+	for _, file := range files {
+		checkpoint, err := readCheckpoint(file)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	return checkpoints, nil
+}
+
+// LoadCheckpoint loads the full WorldState of a checkpoint version,
+// including values stored in blob files.
+func (gp *GraphPersistence) LoadCheckpoint(runID string, version int) (WorldState, error) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+
+	files, err := gp.checkpointFiles(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if checkpointVersion(file) != version {
+			continue
+		}
+		checkpoint, err := readCheckpoint(file)
+		if err != nil {
+			return nil, err
+		}
+		return gp.resolveCheckpoint(runID, checkpoint)
+	}
+
+	return nil, fmt.Errorf("checkpoint version %d not found", version)
+}
+
+// LoadStateAtNode loads the WorldState as it was before or after the most
+// recent execution of a node.
+func (gp *GraphPersistence) LoadStateAtNode(runID, nodeID string, phase CheckpointPhase) (WorldState, error) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+
+	files, err := gp.checkpointFiles(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		checkpoint, err := readCheckpoint(files[i])
+		if err != nil {
+			return nil, err
+		}
+		if checkpoint.NodeID == nodeID && checkpoint.Phase == phase {
+			return gp.resolveCheckpoint(runID, checkpoint)
+		}
+	}
+
+	return nil, fmt.Errorf("no %s checkpoint for node %s", phase, nodeID)
+}
+
+// DiffCheckpoints compares the WorldStates of two checkpoint versions.
+func (gp *GraphPersistence) DiffCheckpoints(runID string, fromVersion, toVersion int) (*StateDiff, error) {
+	from, err := gp.LoadCheckpoint(runID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := gp.LoadCheckpoint(runID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+	return DiffStates(from, to), nil
+}
+
+func (gp *GraphPersistence) checkpointsDir(runID string) string {
+	return filepath.Join(gp.basePath, runID, "graph", "checkpoints")
+}
+
+// checkpointFiles returns the paths of a run's checkpoint files in version
+// order. The file names start with the zero-padded version.
+func (gp *GraphPersistence) checkpointFiles(runID string) ([]string, error) {
+	entries, err := os.ReadDir(gp.checkpointsDir(runID))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints directory: %w", err)
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+			files = append(files, filepath.Join(gp.checkpointsDir(runID), entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// checkpointVersion returns the version a checkpoint file name starts with,
+// or 0 if it does not start with one.
+func checkpointVersion(path string) int {
+	prefix, _, _ := strings.Cut(filepath.Base(path), "_")
+	version, err := strconv.Atoi(prefix)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// resolveCheckpoint rebuilds a checkpoint's full WorldState from its inline
+// values and blob files, restoring the markers among them.
+func (gp *GraphPersistence) resolveCheckpoint(runID string, checkpoint *Checkpoint) (WorldState, error) {
+	state := NewWorldState()
+	for key, value := range checkpoint.State {
+		state[key] = stateValue(value)
+	}
+
+	for key, hash := range checkpoint.Blobs {
+		data, err := os.ReadFile(filepath.Join(gp.checkpointsDir(runID), "blobs", hash+".json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read blob for state key %s: %w", key, err)
+		}
+		var value interface{}
+		if err := decodeStateJSON(data, &value); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal blob for state key %s: %w", key, err)
+		}
+		state[key] = stateValue(value)
+	}
+
+	return state, nil
+}
+
+func readCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+	err = json.Unmarshal(data, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+
+	return &checkpoint, nil
+}
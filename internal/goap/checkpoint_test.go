@@ -0,0 +1,188 @@
+package goap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckpoints(t *testing.T) {
+	tmpDir := t.TempDir()
+	persistence := NewGraphPersistence(tmpDir)
+
+	t.Run("VersionsAndLargeValues", func(t *testing.T) {
+		runID := "test-checkpoints"
+		largeValue := strings.Repeat("x", largeValueBytes+1)
+
+		first, err := persistence.SaveCheckpoint(runID, "node_1", CheckpointBefore, WorldState{"a": true})
+		if err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+		second, err := persistence.SaveCheckpoint(runID, "node_1", CheckpointAfter, WorldState{"a": false, "source": largeValue})
+		if err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+
+		if first.Version != 1 || second.Version != 2 {
+			t.Errorf("Expected versions 1 and 2, got %d and %d", first.Version, second.Version)
+		}
+		if _, inline := second.State["source"]; inline || second.Blobs["source"] == "" {
+			t.Error("Large value should be stored as a blob")
+		}
+
+		blobs, _ := os.ReadDir(filepath.Join(tmpDir, runID, "graph", "checkpoints", "blobs"))
+		if len(blobs) != 1 {
+			t.Errorf("Expected 1 blob file, got %d", len(blobs))
+		}
+
+		state, err := persistence.LoadStateAtNode(runID, "node_1", CheckpointAfter)
+		if err != nil {
+			t.Fatalf("Failed to load state at node: %v", err)
+		}
+		if state.Get("source") != largeValue || state.Get("a") != false {
+			t.Errorf("Large value should be restored from its blob, got %d keys", len(state))
+		}
+
+		diff, err := persistence.DiffCheckpoints(runID, 1, 2)
+		if err != nil {
+			t.Fatalf("Failed to diff checkpoints: %v", err)
+		}
+		if _, added := diff.Added["source"]; !added {
+			t.Error("Expected source to be added")
+		}
+		if change, changed := diff.Changed["a"]; !changed || change.Before != true || change.After != false {
+			t.Errorf("Expected a to change from true to false, got %+v", diff.Changed)
+		}
+		if !strings.Contains(diff.String(), "~ a: true → false") {
+			t.Errorf("Unexpected diff rendering: %s", diff)
+		}
+
+		if _, err := persistence.LoadCheckpoint(runID, 3); err == nil {
+			t.Error("Loading a missing version should fail")
+		}
+	})
+
+	t.Run("KeepsIntValues", func(t *testing.T) {
+		runID := "test-checkpoints-int"
+		ports := make([]interface{}, largeValueBytes)
+		for i := range ports {
+			ports[i] = 8000 + i
+		}
+
+		_, err := persistence.SaveCheckpoint(runID, "node_1", CheckpointAfter, WorldState{"port": 80, "ratio": 0.5, "ports": ports})
+		if err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+
+		state, err := persistence.LoadStateAtNode(runID, "node_1", CheckpointAfter)
+		if err != nil {
+			t.Fatalf("Failed to load state at node: %v", err)
+		}
+		if !state.Matches(WorldState{"port": 80, "ratio": 0.5}) {
+			t.Errorf("Expected int port and float ratio, got %T and %T", state.Get("port"), state.Get("ratio"))
+		}
+		if !stateValuesEqual(state.Get("ports"), ports) {
+			t.Error("Expected ints in the blob value to be restored as int")
+		}
+	})
+
+	t.Run("VersionsSurviveMissingFiles", func(t *testing.T) {
+		runID := "test-checkpoints-missing"
+		for i := 0; i < 3; i++ {
+			if _, err := persistence.SaveCheckpoint(runID, "node_1", CheckpointBefore, WorldState{"i": i}); err != nil {
+				t.Fatalf("Failed to save checkpoint: %v", err)
+			}
+		}
+
+		files, _ := filepath.Glob(filepath.Join(tmpDir, runID, "graph", "checkpoints", "000002_*.json"))
+		if len(files) != 1 {
+			t.Fatalf("Expected 1 file for version 2, got %v", files)
+		}
+		os.Remove(files[0])
+
+		// A fresh handler has to find the highest version on disk
+		reopened := NewGraphPersistence(tmpDir)
+		next, err := reopened.SaveCheckpoint(runID, "node_1", CheckpointAfter, WorldState{"i": 3})
+		if err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+		if next.Version != 4 {
+			t.Errorf("Expected version 4, got %d", next.Version)
+		}
+
+		state, err := reopened.LoadCheckpoint(runID, 3)
+		if err != nil {
+			t.Fatalf("Failed to load version 3: %v", err)
+		}
+		if state.Get("i") != 2 {
+			t.Errorf("Expected version 3 to hold i=2, got %v", state.Get("i"))
+		}
+		if _, err := reopened.LoadCheckpoint(runID, 2); err == nil {
+			t.Error("Loading the removed version should fail")
+		}
+	})
+
+	t.Run("RestoresMarkers", func(t *testing.T) {
+		runID := "test-checkpoints-markers"
+		_, err := persistence.SaveCheckpoint(runID, "node_1", CheckpointAfter, WorldState{"remote": Unknown})
+		if err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+
+		state, err := persistence.LoadStateAtNode(runID, "node_1", CheckpointAfter)
+		if err != nil {
+			t.Fatalf("Failed to load state at node: %v", err)
+		}
+		if state.Get("remote") != Unknown {
+			t.Errorf("Expected the Unknown marker, got %T %v", state.Get("remote"), state.Get("remote"))
+		}
+	})
+
+	t.Run("ExecutorCheckpointsEachNode", func(t *testing.T) {
+		runID := "test-executor-checkpoints"
+
+		action := NewSimpleAction("DoA", "Do A", WorldState{}, WorldState{"a": true}, 1.0,
+			func(ctx context.Context, ws WorldState) error { return nil })
+		plan := &HierarchicalPlan{
+			Goal:    NewGoal("GoalA", "A", WorldState{"a": true}, 1.0),
+			Actions: []Action{action},
+		}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterAction(action)
+		if err := executor.Execute(context.Background(), WorldState{"start": true}); err != nil {
+			t.Fatalf("Execution failed: %v", err)
+		}
+
+		checkpoints, err := persistence.ListCheckpoints(runID)
+		if err != nil {
+			t.Fatalf("Failed to list checkpoints: %v", err)
+		}
+		if len(checkpoints) != 2 || checkpoints[0].Phase != CheckpointBefore || checkpoints[1].Phase != CheckpointAfter {
+			t.Fatalf("Expected before and after checkpoints, got %d", len(checkpoints))
+		}
+
+		before, err := persistence.LoadStateAtNode(runID, graph.RootNodeID, CheckpointBefore)
+		if err != nil {
+			t.Fatalf("Failed to load state at node: %v", err)
+		}
+		if before.Has("a") || before.Get("start") != true {
+			t.Errorf("Unexpected state before node: %s", before)
+		}
+
+		diff, err := persistence.DiffCheckpoints(runID, 1, 2)
+		if err != nil {
+			t.Fatalf("Failed to diff checkpoints: %v", err)
+		}
+		if len(diff.Added) != 1 || diff.Added["a"] != true {
+			t.Errorf("Expected the node to add a=true, got %s", diff)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package goap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/charmbracelet/log"
+)
+
+// Condition is a comparison on a world state value, such as
+// test_coverage >= 80. The planner only matches state values exactly, so a
+// condition is planned as a boolean fact, produced by a ConditionAction that
+// checks the comparison against the actual state.
+type Condition struct {
+	Key   string      `yaml:"key" json:"key"`
+	Op    string      `yaml:"op" json:"op"`
+	Value interface{} `yaml:"value" json:"value"`
+	// Fact is the boolean key the condition is planned as. It defaults to
+	// the key, operator and value, e.g. "test_coverage_ge_80".
+	Fact string `yaml:"fact,omitempty" json:"fact,omitempty"`
+	// After holds the preconditions of the check, typically the state in
+	// which Key has been measured
+	After WorldState `yaml:"after,omitempty" json:"after,omitempty"`
+}
+
+// conditionOps maps each operator to the name used in default facts.
+var conditionOps = map[string]string{
+	"==": "eq",
+	"!=": "ne",
+	"<":  "lt",
+	"<=": "le",
+	">":  "gt",
+	">=": "ge",
+}
+
+// Validate checks that the condition has a key and a known operator.
+func (c Condition) Validate() error {
+	if c.Key == "" {
+		return fmt.Errorf("condition has no key")
+	}
+	if _, exists := conditionOps[c.Op]; !exists {
+		return fmt.Errorf("condition on %s has unknown operator %q", c.Key, c.Op)
+	}
+	return nil
+}
+
+// FactKey returns the boolean key the condition is planned as.
+func (c Condition) FactKey() string {
+	if c.Fact != "" {
+		return c.Fact
+	}
+	return fmt.Sprintf("%s_%s_%v", c.Key, conditionOps[c.Op], c.Value)
+}
+
+// String returns the condition as an expression.
+func (c Condition) String() string {
+	return fmt.Sprintf("%s %s %v", c.Key, c.Op, c.Value)
+}
+
+// Holds evaluates the condition against a state. Ordering operators need
+// numeric values; equality compares numbers numerically and other values
+// deeply. It returns an error if the key is missing or the values cannot be
+// compared.
+func (c Condition) Holds(state WorldState) (bool, error) {
+	actual, exists := state[c.Key]
+	if !exists {
+		return false, fmt.Errorf("%s not found in world state", c.Key)
+	}
+
+	actualNumber, actualNumeric := toFloat(actual)
+	expectedNumber, expectedNumeric := toFloat(c.Value)
+	numeric := actualNumeric && expectedNumeric
+
+	switch c.Op {
+	case "==", "!=":
+		equal := reflect.DeepEqual(actual, c.Value)
+		if numeric {
+			equal = actualNumber == expectedNumber
+		}
+		return equal == (c.Op == "=="), nil
+	}
+
+	if !numeric {
+		return false, fmt.Errorf("cannot compare %s=%v %s %v: values are not numeric", c.Key, actual, c.Op, c.Value)
+	}
+	switch c.Op {
+	case "<":
+		return actualNumber < expectedNumber, nil
+	case "<=":
+		return actualNumber <= expectedNumber, nil
+	case ">":
+		return actualNumber > expectedNumber, nil
+	case ">=":
+		return actualNumber >= expectedNumber, nil
+	}
+	return false, fmt.Errorf("condition on %s has unknown operator %q", c.Key, c.Op)
+}
+
+// toFloat converts a numeric state value to float64.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// TypeCondition is the type ID of ConditionActions.
+const TypeCondition = "goap.Condition"
+
+// ConditionAction checks a condition against the actual state and sets its
+// fact. It fails if the condition does not hold, so that replanning or
+// re-refinement can try to make it hold.
+type ConditionAction struct {
+	*BaseAction
+	condition Condition
+}
+
+// NewConditionAction creates the action that checks a condition.
+func NewConditionAction(condition Condition) *ConditionAction {
+	preconditions := condition.After
+	if preconditions == nil {
+		preconditions = NewWorldState()
+	}
+	return &ConditionAction{
+		BaseAction: NewBaseAction(
+			"Check_"+condition.FactKey(),
+			"Check that "+condition.String(),
+			preconditions.Clone(),
+			WorldState{condition.FactKey(): true},
+			1.0, // Low complexity - a state lookup
+		),
+		condition: condition,
+	}
+}
+
+func (a *ConditionAction) Execute(ctx context.Context, current WorldState) error {
+	if !a.CanExecute(current) {
+		return fmt.Errorf("preconditions not met for %s", a.Name())
+	}
+
+	holds, err := a.condition.Holds(current)
+	current.Set(a.condition.FactKey(), holds)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", a.condition, err)
+	}
+	if !holds {
+		log.Warn("Condition does not hold", "condition", a.condition.String(), "actual", current.Get(a.condition.Key))
+		return fmt.Errorf("condition %s does not hold: %s is %v", a.condition, a.condition.Key, current.Get(a.condition.Key))
+	}
+
+	log.Info("Condition holds", "condition", a.condition.String())
+	return nil
+}
+
+func (a *ConditionAction) Clone() Action {
+	return NewConditionAction(a.condition)
+}
+
+// Spec implements SerializableAction.
+func (a *ConditionAction) Spec() (ActionSpec, error) {
+	return NewActionSpec(TypeCondition, a.condition)
+}
+
+// BuildConditionAction is the ActionFactory of ConditionActions.
+func BuildConditionAction(params json.RawMessage) (Action, error) {
+	var condition Condition
+	if err := json.Unmarshal(params, &condition); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal condition: %w", err)
+	}
+	if err := condition.Validate(); err != nil {
+		return nil, err
+	}
+	return NewConditionAction(condition), nil
+}
@@ -0,0 +1,195 @@
+package goap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// defaultAtomicBudget is how many node expansions a plan search may take
+// for a goal to count as atomic.
+const defaultAtomicBudget = 200
+
+// DependencyRefiner decomposes goals deterministically, without an LLM, by
+// analysing which actions produce which state values and what they require.
+// The goal's values are grouped into dependency layers: a value is in layer
+// n if it takes a chain of n actions to produce it, ignoring what actions
+// undo. Each layer becomes a subgoal that depends on the layer before it.
+// It is a zero-cost default refiner and a baseline to compare LLM
+// decompositions against.
+type DependencyRefiner struct {
+	actions []Action
+	planner *Planner
+}
+
+// NewDependencyRefiner creates a refiner over the given actions.
+func NewDependencyRefiner(actions []Action) *DependencyRefiner {
+	planner := NewPlanner(actions)
+	planner.SetMaxIterations(defaultAtomicBudget)
+	return &DependencyRefiner{
+		actions: actions,
+		planner: planner,
+	}
+}
+
+// SetBudget sets how many node expansions a plan search may take for a goal
+// to count as atomic.
+func (r *DependencyRefiner) SetBudget(iterations int) {
+	r.planner.SetMaxIterations(iterations)
+}
+
+// IsAtomic reports a goal as atomic if the planner finds a plan for it
+// within the budget. Goals with a single missing value, or with values no
+// action chain produces, are atomic too: decomposing them cannot help, and
+// planning them explains why they are unreachable.
+func (r *DependencyRefiner) IsAtomic(goal *Goal, current WorldState) bool {
+	missing := missingValues(goal, current)
+	if len(missing) <= 1 {
+		return true
+	}
+	layers := r.valueLayers(current)
+	for key, value := range missing {
+		if _, reachable := layers[valueKey(key, value)]; !reachable {
+			return true
+		}
+	}
+	return r.planner.FindPlan(current, goal) != nil
+}
+
+// Refine splits the goal into one subgoal per dependency layer, in layer
+// order. If all its missing values are in the same layer, it is split into
+// one independent subgoal per value instead.
+func (r *DependencyRefiner) Refine(ctx context.Context, goal *Goal, current WorldState) ([]*Goal, error) {
+	layers, err := r.Layers(goal, current)
+	if err != nil {
+		return nil, err
+	}
+	if len(layers) == 0 {
+		return nil, nil
+	}
+
+	var subgoals []*Goal
+	if len(layers) == 1 {
+		keys := make([]string, 0, len(layers[0]))
+		for key := range layers[0] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			subgoal := NewGoal(fmt.Sprintf("%s_%s", goal.Name(), key), fmt.Sprintf("Achieve %s=%v", key, layers[0][key]), WorldState{key: layers[0][key]}, goal.Priority())
+			subgoal.SetDependsOn()
+			subgoals = append(subgoals, subgoal)
+		}
+	} else {
+		for i, layer := range layers {
+			name := fmt.Sprintf("%s_Layer%d", goal.Name(), i+1)
+			subgoal := NewGoal(name, fmt.Sprintf("Achieve %s (dependency layer %d)", layer.String(), i+1), layer, goal.Priority())
+			if i == 0 {
+				subgoal.SetDependsOn()
+			} else {
+				subgoal.SetDependsOn(subgoals[i-1].Name())
+			}
+			subgoals = append(subgoals, subgoal)
+		}
+	}
+
+	log.Info("Refined goal along dependency layers", "goal", goal.Name(), "numSubgoals", len(subgoals))
+	return subgoals, nil
+}
+
+// Layers groups the values of the goal's desired state that do not hold yet
+// by dependency layer, lowest first. Empty layers are omitted. It fails if
+// no chain of actions produces one of the values.
+func (r *DependencyRefiner) Layers(goal *Goal, current WorldState) ([]WorldState, error) {
+	layers := r.valueLayers(current)
+
+	byLayer := make(map[int]WorldState)
+	var unreachable []string
+	for key, value := range missingValues(goal, current) {
+		layer, reachable := layers[valueKey(key, value)]
+		if !reachable {
+			unreachable = append(unreachable, fmt.Sprintf("%s=%v", key, value))
+			continue
+		}
+		if byLayer[layer] == nil {
+			byLayer[layer] = NewWorldState()
+		}
+		byLayer[layer].Set(key, value)
+	}
+	if len(unreachable) > 0 {
+		sort.Strings(unreachable)
+		return nil, fmt.Errorf("cannot decompose goal %s: no action chain produces %s", goal.Name(), strings.Join(unreachable, ", "))
+	}
+
+	order := make([]int, 0, len(byLayer))
+	for layer := range byLayer {
+		order = append(order, layer)
+	}
+	sort.Ints(order)
+
+	result := make([]WorldState, len(order))
+	for i, layer := range order {
+		result[i] = byLayer[layer]
+	}
+	return result, nil
+}
+
+// valueLayers computes the layer of every state value the actions can
+// produce from the current state. Values that hold are in layer 0, and an
+// action's effects are one layer above its highest precondition.
+func (r *DependencyRefiner) valueLayers(current WorldState) map[string]int {
+	layers := make(map[string]int, len(current))
+	for key, value := range current {
+		layers[valueKey(key, value)] = 0
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, action := range r.actions {
+			layer, ready := 0, true
+			for key, value := range action.Preconditions() {
+				precondition, reachable := layers[valueKey(key, value)]
+				if !reachable {
+					ready = false
+					break
+				}
+				if precondition > layer {
+					layer = precondition
+				}
+			}
+			if !ready {
+				continue
+			}
+			for key, value := range action.Effects() {
+				effect := valueKey(key, value)
+				if existing, reachable := layers[effect]; !reachable || layer+1 < existing {
+					layers[effect] = layer + 1
+					changed = true
+				}
+			}
+		}
+	}
+	return layers
+}
+
+// missingValues returns the values of the goal's desired state that do not
+// hold in the current state.
+func missingValues(goal *Goal, current WorldState) WorldState {
+	missing := NewWorldState()
+	for key, value := range goal.DesiredState() {
+		if !current.Matches(WorldState{key: value}) {
+			missing.Set(key, value)
+		}
+	}
+	return missing
+}
+
+// valueKey identifies a key's value in the layer map. The type is part of
+// it, since int 80 and float64 80, or true and "true", are different values
+// to Matches.
+func valueKey(key string, value interface{}) string {
+	return fmt.Sprintf("%s=%T:%v", key, value, value)
+}
@@ -0,0 +1,139 @@
+package goap
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDependencyRefiner(t *testing.T) {
+	noop := func(ctx context.Context, ws WorldState) error { return nil }
+	actions := []Action{
+		NewSimpleAction("Design", "Design", WorldState{}, WorldState{"designed": true}, 1.0, noop),
+		NewSimpleAction("Implement", "Implement", WorldState{"designed": true}, WorldState{"implemented": true}, 1.0, noop),
+		NewSimpleAction("WriteDocs", "Write docs", WorldState{"designed": true}, WorldState{"documented": true}, 1.0, noop),
+		NewSimpleAction("Test", "Test", WorldState{"implemented": true}, WorldState{"tested": true}, 1.0, noop),
+		NewSimpleAction("Release", "Release", WorldState{"tested": true, "documented": true}, WorldState{"released": true}, 1.0, noop),
+	}
+	goal := NewGoal("Ship", "Ship it", WorldState{"designed": true, "implemented": true, "documented": true, "tested": true, "released": true}, 1.0)
+
+	t.Run("Layers", func(t *testing.T) {
+		layers, err := NewDependencyRefiner(actions).Layers(goal, NewWorldState())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []string{"{designed: true}", "{documented: true, implemented: true}", "{tested: true}", "{released: true}"}
+		if len(layers) != len(expected) {
+			t.Fatalf("Expected %d layers, got %v", len(expected), layers)
+		}
+		for i := range expected {
+			if layers[i].String() != expected[i] {
+				t.Errorf("Expected layer %d to be %s, got %s", i+1, expected[i], layers[i].String())
+			}
+		}
+
+		// Values that hold are left out, and the rest move down
+		layers, _ = NewDependencyRefiner(actions).Layers(goal, WorldState{"designed": true, "implemented": true})
+		if len(layers) != 2 || layers[0].String() != "{documented: true, tested: true}" {
+			t.Errorf("Expected layers from the current state, got %v", layers)
+		}
+	})
+
+	t.Run("RefinesInLayerOrder", func(t *testing.T) {
+		refiner := NewDependencyRefiner(actions)
+		subgoals, err := refiner.Refine(context.Background(), goal, NewWorldState())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(subgoals) != 4 {
+			t.Fatalf("Expected 4 subgoals, got %d", len(subgoals))
+		}
+		if subgoals[0].Name() != "Ship_Layer1" || len(subgoals[0].DependsOn()) != 0 {
+			t.Errorf("Expected an independent first layer, got %s depending on %v", subgoals[0].Name(), subgoals[0].DependsOn())
+		}
+		if deps := subgoals[3].DependsOn(); len(deps) != 1 || deps[0] != "Ship_Layer3" {
+			t.Errorf("Expected the last layer to depend on the third, got %v", deps)
+		}
+	})
+
+	t.Run("SplitsSingleLayer", func(t *testing.T) {
+		sameLayer := NewGoal("Prepare", "Prepare", WorldState{"implemented": true, "documented": true}, 1.0)
+		subgoals, err := NewDependencyRefiner(actions).Refine(context.Background(), sameLayer, WorldState{"designed": true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(subgoals) != 2 || subgoals[0].Name() != "Prepare_documented" || subgoals[1].Name() != "Prepare_implemented" {
+			t.Errorf("Expected one subgoal per value, got %v", subgoals)
+		}
+		if !subgoals[1].DeclaresDependencies() || len(subgoals[1].DependsOn()) != 0 {
+			t.Errorf("Expected subgoals of the same layer to be independent, got %v", subgoals[1].DependsOn())
+		}
+	})
+
+	t.Run("AtomicWithinBudget", func(t *testing.T) {
+		refiner := NewDependencyRefiner(actions)
+		if !refiner.IsAtomic(goal, NewWorldState()) {
+			t.Error("Expected a goal the planner solves within the budget to be atomic")
+		}
+
+		refiner.SetBudget(2)
+		if refiner.IsAtomic(goal, NewWorldState()) {
+			t.Error("Expected a goal the planner cannot solve within the budget not to be atomic")
+		}
+		if !refiner.IsAtomic(NewGoal("Release", "Release", WorldState{"released": true}, 1.0), NewWorldState()) {
+			t.Error("Expected a goal with a single missing value to be atomic")
+		}
+	})
+
+	t.Run("Unproducible", func(t *testing.T) {
+		refiner := NewDependencyRefiner(actions)
+		unreachable := NewGoal("Audit", "Audit", WorldState{"released": true, "audited": true}, 1.0)
+
+		if !refiner.IsAtomic(unreachable, NewWorldState()) {
+			t.Error("Expected a goal with unproducible values to be left to the planner's diagnostics")
+		}
+		_, err := refiner.Refine(context.Background(), unreachable, NewWorldState())
+		if err == nil || !strings.Contains(err.Error(), "no action chain produces audited=true") {
+			t.Errorf("Expected unproducible value error, got %v", err)
+		}
+	})
+
+	t.Run("DistinguishesValueTypes", func(t *testing.T) {
+		refiner := NewDependencyRefiner([]Action{
+			NewSimpleAction("Listen", "Listen", WorldState{}, WorldState{"port": 80, "ready": true}, 1.0, noop),
+		})
+
+		for _, desired := range []WorldState{{"port": 80.0}, {"ready": "true"}} {
+			_, err := refiner.Refine(context.Background(), NewGoal("Serve", "Serve", desired, 1.0), NewWorldState())
+			if err == nil || !strings.Contains(err.Error(), "no action chain produces") {
+				t.Errorf("Expected %s to be unproducible, got %v", desired, err)
+			}
+		}
+
+		layers, err := refiner.Layers(NewGoal("Serve", "Serve", WorldState{"port": 80, "ready": true}, 1.0), NewWorldState())
+		if err != nil || len(layers) != 1 {
+			t.Errorf("Expected the int port to be produced in 1 layer, got %v, %v", layers, err)
+		}
+	})
+
+	t.Run("PlansHierarchically", func(t *testing.T) {
+		refiner := NewDependencyRefiner(actions)
+		refiner.SetBudget(2)
+		hp := NewHierarchicalPlanner(NewPlanner(actions), refiner, 5)
+
+		plan, err := hp.PlanHierarchical(context.Background(), NewWorldState(), goal)
+		if err != nil {
+			t.Fatalf("Failed to plan: %v", err)
+		}
+		state := NewWorldState()
+		for _, action := range plan.AllActions() {
+			if !state.Matches(action.Preconditions()) {
+				t.Fatalf("Expected preconditions of %s to hold in %v", action.Name(), state)
+			}
+			ApplyEffects(state, action)
+		}
+		if !goal.IsSatisfied(state) {
+			t.Errorf("Expected the plan to satisfy the goal, got %v", state)
+		}
+	})
+}
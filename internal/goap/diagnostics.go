@@ -0,0 +1,228 @@
+package goap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlanFailure is returned when the planner cannot find any plan for a goal.
+// It explains which parts of the desired state are out of reach and why, so
+// that a failed plan can be fixed by adding actions or adjusting the goal
+// rather than by guesswork.
+type PlanFailure struct {
+	// Goal is the name of the goal that could not be planned
+	Goal string
+
+	// UnproducibleKeys lists desired-state keys that no registered action
+	// can ever set to the desired value
+	UnproducibleKeys []string
+
+	// BlockedKeys lists desired-state keys that some action produces, but
+	// whose producers can never run because of unmet preconditions
+	BlockedKeys []BlockedKey
+
+	// ClosestState is the state explored by the search that came closest
+	// to satisfying the goal
+	ClosestState WorldState
+
+	// ClosestDistance is the number of unsatisfied goal conditions in ClosestState
+	ClosestDistance int
+
+	// Iterations is the number of nodes the search expanded
+	Iterations int
+
+	// IterationLimitHit is true when the search stopped at its iteration
+	// limit rather than exhausting the reachable states
+	IterationLimitHit bool
+}
+
+// BlockedKey describes a desired-state key whose producers are unreachable.
+type BlockedKey struct {
+	Key string
+
+	// Chain explains the blockage step by step, from the producing action
+	// down to the precondition that nothing can satisfy
+	Chain []string
+}
+
+// Error implements the error interface.
+func (pf *PlanFailure) Error() string {
+	reasons := []string{}
+	if len(pf.UnproducibleKeys) > 0 {
+		reasons = append(reasons, fmt.Sprintf("unproducible keys %v", pf.UnproducibleKeys))
+	}
+	if len(pf.BlockedKeys) > 0 {
+		keys := make([]string, len(pf.BlockedKeys))
+		for i, blocked := range pf.BlockedKeys {
+			keys[i] = blocked.Key
+		}
+		reasons = append(reasons, fmt.Sprintf("blocked keys %v", keys))
+	}
+	if pf.IterationLimitHit {
+		reasons = append(reasons, fmt.Sprintf("iteration limit hit after %d iterations", pf.Iterations))
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "conflicting action effects")
+	}
+	return fmt.Sprintf("no plan found for goal %s: %s", pf.Goal, strings.Join(reasons, "; "))
+}
+
+// Report returns a multi-line, human-readable explanation of the failure.
+func (pf *PlanFailure) Report() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "No plan found for goal %s\n", pf.Goal)
+
+	if len(pf.UnproducibleKeys) > 0 {
+		b.WriteString("  Keys no action can produce:\n")
+		for _, key := range pf.UnproducibleKeys {
+			fmt.Fprintf(&b, "    - %s\n", key)
+		}
+	}
+
+	if len(pf.BlockedKeys) > 0 {
+		b.WriteString("  Keys blocked by unmet preconditions:\n")
+		for _, blocked := range pf.BlockedKeys {
+			fmt.Fprintf(&b, "    - %s\n", blocked.Key)
+			for _, step := range blocked.Chain {
+				fmt.Fprintf(&b, "        %s\n", step)
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "  Closest state (%d conditions unmet): %s\n", pf.ClosestDistance, pf.ClosestState)
+
+	if pf.IterationLimitHit {
+		fmt.Fprintf(&b, "  Search stopped at the iteration limit (%d iterations)\n", pf.Iterations)
+	} else {
+		fmt.Fprintf(&b, "  Search exhausted all reachable states (%d iterations)\n", pf.Iterations)
+	}
+
+	return b.String()
+}
+
+// diagnose builds a PlanFailure for a search that found no plan.
+func (p *Planner) diagnose(current WorldState, goal *Goal, result *searchResult) *PlanFailure {
+	failure := &PlanFailure{
+		Goal:              goal.Name(),
+		UnproducibleKeys:  []string{},
+		BlockedKeys:       []BlockedKey{},
+		Iterations:        result.iterations,
+		IterationLimitHit: result.iterationLimitHit,
+	}
+
+	if result.closest != nil {
+		failure.ClosestState = result.closest.state.Clone()
+		failure.ClosestDistance = goal.Distance(result.closest.state)
+	}
+
+	reachable := p.relaxedReachable(current)
+
+	for _, key := range sortedKeys(goal.DesiredState()) {
+		value := goal.DesiredState()[key]
+		if current.Has(key) && stateValuesEqual(current.Get(key), value) {
+			continue
+		}
+
+		if len(p.producers(key, value)) == 0 {
+			failure.UnproducibleKeys = append(failure.UnproducibleKeys, key)
+			continue
+		}
+
+		if !reachable[factKey(key, value)] {
+			failure.BlockedKeys = append(failure.BlockedKeys, BlockedKey{
+				Key:   key,
+				Chain: p.blockingChain(key, value, reachable, map[string]bool{}),
+			})
+		}
+	}
+
+	return failure
+}
+
+// producers returns the actions whose effects set key to value.
+func (p *Planner) producers(key string, value interface{}) []Action {
+	producers := []Action{}
+	for _, action := range p.actions {
+		effect, exists := action.Effects()[key]
+		if exists && stateValuesEqual(effect, value) {
+			producers = append(producers, action)
+		}
+	}
+	return producers
+}
+
+// relaxedReachable computes every key/value fact that could ever hold,
+// ignoring that later effects may overwrite earlier ones. A fact missing from
+// the result cannot be reached by any sequence of actions.
+func (p *Planner) relaxedReachable(current WorldState) map[string]bool {
+	reachable := make(map[string]bool)
+	for key, value := range current {
+		reachable[factKey(key, value)] = true
+	}
+
+	fired := make(map[int]bool)
+	for changed := true; changed; {
+		changed = false
+		for i, action := range p.actions {
+			if fired[i] || !allReachable(action.Preconditions(), reachable) {
+				continue
+			}
+			fired[i] = true
+			changed = true
+			for key, value := range action.Effects() {
+				reachable[factKey(key, value)] = true
+			}
+		}
+	}
+
+	return reachable
+}
+
+// blockingChain explains why key cannot reach value, following the first
+// unreachable precondition of each producer down to its root cause.
+func (p *Planner) blockingChain(key string, value interface{}, reachable map[string]bool, visiting map[string]bool) []string {
+	fact := factKey(key, value)
+	if visiting[fact] {
+		return []string{fmt.Sprintf("%s=%v is part of a precondition cycle", key, value)}
+	}
+	visiting[fact] = true
+	defer delete(visiting, fact)
+
+	producers := p.producers(key, value)
+	if len(producers) == 0 {
+		return []string{fmt.Sprintf("%s=%v: no action produces it", key, value)}
+	}
+
+	chain := []string{}
+	for _, producer := range producers {
+		preconditions := producer.Preconditions()
+		for _, preKey := range sortedKeys(preconditions) {
+			preValue := preconditions[preKey]
+			if reachable[factKey(preKey, preValue)] {
+				continue
+			}
+			chain = append(chain, fmt.Sprintf("%s requires %s=%v", producer.Name(), preKey, preValue))
+			chain = append(chain, p.blockingChain(preKey, preValue, reachable, visiting)...)
+			break
+		}
+	}
+
+	return chain
+}
+
+// allReachable reports whether every condition is in the reachable fact set.
+func allReachable(conditions WorldState, reachable map[string]bool) bool {
+	for key, value := range conditions {
+		if !reachable[factKey(key, value)] {
+			return false
+		}
+	}
+	return true
+}
+
+// factKey identifies a key/value pair, including the value's type so that
+// 1 and "1" are distinct facts, as they are for WorldState.Matches.
+func factKey(key string, value interface{}) string {
+	return fmt.Sprintf("%s=%T:%v", key, value, value)
+}
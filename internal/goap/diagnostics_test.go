@@ -0,0 +1,107 @@
+package goap
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPlanDiagnostics(t *testing.T) {
+	noop := func(ctx context.Context, ws WorldState) error { return nil }
+
+	t.Run("UnproducibleAndBlockedKeys", func(t *testing.T) {
+		write := NewSimpleAction("WriteCode", "Write code", WorldState{}, WorldState{"code_written": true}, 1.0, noop)
+		deploy := NewSimpleAction("Deploy", "Deploy", WorldState{"approved": true}, WorldState{"deployed": true}, 1.0, noop)
+		approve := NewSimpleAction("Approve", "Approve", WorldState{"reviewer_assigned": true}, WorldState{"approved": true}, 1.0, noop)
+
+		planner := NewPlanner([]Action{write, deploy, approve})
+		goal := NewGoal("Ship", "Ship it", WorldState{
+			"code_written": true,
+			"deployed":     true,
+			"documented":   true,
+		}, 1.0)
+
+		plans, err := planner.FindPlansWithDiagnostics(NewWorldState(), goal, 1)
+		if plans != nil {
+			t.Fatal("Expected no plans")
+		}
+
+		var failure *PlanFailure
+		if !errors.As(err, &failure) {
+			t.Fatalf("Expected *PlanFailure, got %T", err)
+		}
+
+		if len(failure.UnproducibleKeys) != 1 || failure.UnproducibleKeys[0] != "documented" {
+			t.Errorf("Expected [documented] unproducible, got %v", failure.UnproducibleKeys)
+		}
+
+		if len(failure.BlockedKeys) != 1 || failure.BlockedKeys[0].Key != "deployed" {
+			t.Fatalf("Expected deployed to be blocked, got %v", failure.BlockedKeys)
+		}
+
+		chain := strings.Join(failure.BlockedKeys[0].Chain, " | ")
+		if !strings.Contains(chain, "Deploy requires approved=true") ||
+			!strings.Contains(chain, "Approve requires reviewer_assigned=true") ||
+			!strings.Contains(chain, "reviewer_assigned=true: no action produces it") {
+			t.Errorf("Unexpected blocking chain: %s", chain)
+		}
+
+		if failure.ClosestState.Get("code_written") != true {
+			t.Errorf("Closest state should include code_written, got %s", failure.ClosestState)
+		}
+		if failure.ClosestDistance != 2 {
+			t.Errorf("Expected closest distance 2, got %d", failure.ClosestDistance)
+		}
+		if failure.IterationLimitHit {
+			t.Error("Search should have exhausted the state space, not hit the limit")
+		}
+
+		if !strings.Contains(failure.Report(), "Keys blocked by unmet preconditions") {
+			t.Error("Report should describe blocked keys")
+		}
+	})
+
+	t.Run("UncomparableValues", func(t *testing.T) {
+		stage := NewSimpleAction("Stage", "Stage a.go", WorldState{}, WorldState{"files": []string{"a.go"}}, 1.0, noop)
+		tag := NewSimpleAction("Tag", "Tag for core", WorldState{"labels": map[string]string{"team": "core"}}, WorldState{"tagged": true}, 1.0, noop)
+		planner := NewPlanner([]Action{stage, tag})
+		current := WorldState{"files": []string{"b.go"}, "labels": map[string]string{"team": "infra"}}
+
+		goal := NewGoal("Tagged", "Stage and tag", WorldState{"files": []string{"a.go"}, "tagged": true}, 1.0)
+		_, err := planner.FindPlansWithDiagnostics(current, goal, 1)
+		var failure *PlanFailure
+		if !errors.As(err, &failure) {
+			t.Fatalf("Expected *PlanFailure, got %v", err)
+		}
+		if len(failure.BlockedKeys) != 1 || failure.BlockedKeys[0].Key != "tagged" {
+			t.Errorf("Expected tagged to be blocked by the labels map, got %v", failure.BlockedKeys)
+		}
+
+		explaining := NewPlanner([]Action{stage, tag})
+		explaining.SetExplain(true)
+		explanation, err := explaining.Explain(current, NewGoal("Staged", "Stage", WorldState{"files": []string{"a.go"}}, 1.0))
+		if err != nil {
+			t.Fatalf("Explain failed: %v", err)
+		}
+		if len(explanation.Steps) != 1 || explanation.Steps[0].Satisfies[0] != "goal files=[a.go]" {
+			t.Errorf("Expected Stage to satisfy the slice goal, got %+v", explanation.Steps)
+		}
+	})
+
+	t.Run("HierarchicalPlannerWrapsFailure", func(t *testing.T) {
+		planner := NewPlanner([]Action{})
+		hp := NewHierarchicalPlanner(planner, NewMockGoalRefiner(), 3)
+
+		goal := NewGoal("Impossible", "Nothing produces this", WorldState{"x": true}, 1.0)
+		_, err := hp.PlanHierarchical(context.Background(), NewWorldState(), goal)
+
+		var failure *PlanFailure
+		if !errors.As(err, &failure) {
+			t.Fatalf("Expected wrapped *PlanFailure, got %v", err)
+		}
+		if failure.Goal != "Impossible" {
+			t.Errorf("Expected goal Impossible, got %s", failure.Goal)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package goap
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies a point in the execution lifecycle.
+type EventType string
+
+const (
+	// EventPlanStarted is published when execution of a plan graph starts.
+	// Progress holds the status of the graph's nodes at that point.
+	EventPlanStarted EventType = "plan_started"
+
+	// EventNodeStarted is published when a node starts executing.
+	EventNodeStarted EventType = "node_started"
+
+	// EventActionStarted is published before an action of an atomic node
+	// executes.
+	EventActionStarted EventType = "action_started"
+
+	// EventActionFinished is published after an action executed, with its
+	// duration and error, if any.
+	EventActionFinished EventType = "action_finished"
+
+	// EventNodeFinished is published when a node is completed, skipped or
+	// failed. Status says which.
+	EventNodeFinished EventType = "node_finished"
+
+	// EventReplan is published when a failed atomic node is replanned, with
+	// the new action sequence and the error that caused it.
+	EventReplan EventType = "replan"
+
+	// EventRunFinished is published when execution of the plan graph ends,
+	// with its duration, error and final Progress.
+	EventRunFinished EventType = "run_finished"
+)
+
+// Event describes something that happened during execution. Which fields
+// are set depends on the Type.
+type Event struct {
+	Type      EventType     `json:"type"`
+	RunID     string        `json:"run_id"`
+	Timestamp time.Time     `json:"timestamp"`
+	NodeID    string        `json:"node_id,omitempty"`
+	GoalName  string        `json:"goal_name,omitempty"`
+	Action    string        `json:"action,omitempty"`
+	Actions   []string      `json:"actions,omitempty"`
+	Attempt   int           `json:"attempt,omitempty"`
+	Status    NodeStatus    `json:"status,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Err       error         `json:"-"`
+	Progress  *GraphStatus  `json:"progress,omitempty"`
+
+	// Simulated is set on events of a dry run, in which no action executed
+	Simulated bool `json:"simulated,omitempty"`
+}
+
+// EventHandler receives published events.
+type EventHandler func(event Event)
+
+// EventBus delivers execution events to subscribers. Events are delivered
+// synchronously, in subscription order, on the goroutine that publishes them.
+// With parallel execution several goroutines publish, so handlers must be
+// safe for concurrent use and should return quickly.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []subscriber
+	nextID      int
+}
+
+type subscriber struct {
+	id      int
+	handler EventHandler
+}
+
+// NewEventBus creates an event bus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers a handler for all events. The returned function
+// removes the subscription.
+func (eb *EventBus) Subscribe(handler EventHandler) func() {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	eb.nextID++
+	id := eb.nextID
+	eb.subscribers = append(eb.subscribers, subscriber{id: id, handler: handler})
+
+	return func() {
+		eb.mu.Lock()
+		defer eb.mu.Unlock()
+
+		for i, sub := range eb.subscribers {
+			if sub.id == id {
+				eb.subscribers = append(eb.subscribers[:i:i], eb.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers an event to every subscriber, stamping it with the
+// current time if it has none.
+func (eb *EventBus) Publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	eb.mu.RLock()
+	subscribers := eb.subscribers
+	eb.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		sub.handler(event)
+	}
+}
+
+// Apply updates the status counts for a node event, so that subscribers can
+// track progress without reloading the graph. Plan and run events carrying a
+// Progress snapshot replace the counts.
+func (gs *GraphStatus) Apply(event Event) {
+	switch event.Type {
+	case EventPlanStarted, EventRunFinished:
+		if event.Progress != nil {
+			*gs = *event.Progress
+		}
+	case EventNodeStarted:
+		gs.PendingNodes--
+		gs.RunningNodes++
+	case EventNodeFinished:
+		gs.RunningNodes--
+		switch event.Status {
+		case StatusCompleted:
+			gs.CompletedNodes++
+		case StatusSkipped:
+			gs.SkippedNodes++
+		case StatusFailed:
+			gs.FailedNodes++
+		}
+	}
+}
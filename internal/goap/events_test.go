@@ -0,0 +1,117 @@
+package goap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestEventBus(t *testing.T) {
+	t.Run("SubscribeAndUnsubscribe", func(t *testing.T) {
+		bus := NewEventBus()
+
+		var first, second []EventType
+		unsubscribe := bus.Subscribe(func(event Event) { first = append(first, event.Type) })
+		bus.Subscribe(func(event Event) { second = append(second, event.Type) })
+
+		bus.Publish(Event{Type: EventNodeStarted})
+		unsubscribe()
+		bus.Publish(Event{Type: EventNodeFinished})
+
+		if len(first) != 1 || len(second) != 2 {
+			t.Errorf("Expected 1 and 2 delivered events, got %d and %d", len(first), len(second))
+		}
+	})
+
+	t.Run("StampsTimestamp", func(t *testing.T) {
+		bus := NewEventBus()
+		var received Event
+		bus.Subscribe(func(event Event) { received = event })
+
+		bus.Publish(Event{Type: EventRunFinished})
+		if received.Timestamp.IsZero() {
+			t.Error("Expected the event to be timestamped")
+		}
+	})
+
+	t.Run("ExecutionLifecycle", func(t *testing.T) {
+		persistence := NewGraphPersistence(t.TempDir())
+		runID := "test-events"
+
+		good := NewSimpleAction("Good", "Good", WorldState{}, WorldState{"a": true}, 1.0,
+			func(ctx context.Context, ws WorldState) error { return nil })
+		bad := NewSimpleAction("Bad", "Bad", WorldState{}, WorldState{"b": true}, 1.0,
+			func(ctx context.Context, ws WorldState) error { return errors.New("boom") })
+		plan := &HierarchicalPlan{
+			Goal: NewGoal("Root", "Root", WorldState{"a": true, "b": true}, 1.0),
+			Subplans: []*HierarchicalPlan{
+				{Goal: NewGoal("A", "A", WorldState{"a": true}, 1.0), Actions: []Action{good}, Depth: 1},
+				{Goal: NewGoal("B", "B", WorldState{"b": true}, 1.0), Actions: []Action{bad}, Depth: 1},
+			},
+		}
+		if err := persistence.SaveGraph(BuildGraphFromPlan(plan, "test-agent"), runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterActions([]Action{good, bad})
+
+		var mu sync.Mutex
+		events := []Event{}
+		progress := &GraphStatus{}
+		executor.Events().Subscribe(func(event Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+			progress.Apply(event)
+		})
+
+		if err := executor.Execute(context.Background(), WorldState{}); err == nil {
+			t.Fatal("Expected execution to fail")
+		}
+
+		types := []EventType{}
+		for _, event := range events {
+			types = append(types, event.Type)
+			if event.RunID != runID {
+				t.Errorf("Expected run ID %s, got %s", runID, event.RunID)
+			}
+			if event.Simulated {
+				t.Errorf("Expected %s of a real run not to be marked simulated", event.Type)
+			}
+		}
+		expected := []EventType{
+			EventPlanStarted,
+			EventNodeStarted,                                          // Root
+			EventNodeStarted, EventActionStarted, EventActionFinished, // A
+			EventNodeFinished,
+			EventNodeStarted, EventActionStarted, EventActionFinished, // B
+			EventNodeFinished,
+			EventNodeFinished, // Root
+			EventRunFinished,
+		}
+		if len(types) != len(expected) {
+			t.Fatalf("Expected events %v, got %v", expected, types)
+		}
+		for i := range expected {
+			if types[i] != expected[i] {
+				t.Fatalf("Expected events %v, got %v", expected, types)
+			}
+		}
+
+		badFinished := events[8]
+		if badFinished.Action != "Bad" || badFinished.Err == nil {
+			t.Errorf("Expected Bad to finish with an error, got %+v", badFinished)
+		}
+		if events[10].Status != StatusFailed || events[5].Status != StatusCompleted {
+			t.Errorf("Unexpected node statuses %s and %s", events[10].Status, events[5].Status)
+		}
+		if events[11].Err == nil || events[11].Progress == nil {
+			t.Error("Expected the run to finish with an error and progress")
+		}
+		if progress.CompletedNodes != 1 || progress.FailedNodes != 2 || progress.PendingNodes != 0 {
+			t.Errorf("Unexpected progress %+v", progress)
+		}
+	})
+}
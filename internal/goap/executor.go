@@ -3,6 +3,7 @@ package goap
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -14,21 +15,46 @@ import (
 type GraphExecutor struct {
 	persistence *GraphPersistence
 	actions     map[string]Action
+	instances   map[string]Action
+	instancesMu sync.Mutex
+	registry    *ActionRegistry
 	runID       string
+	planner     *Planner
+	maxReplans  int
+	sensor      StateSensor
+	state       WorldState
+	stateMu     sync.Mutex
+	maxWorkers  int
+	workers     chan struct{}
+	events      *EventBus
+	dryRun      bool
+	simulation  *SimulationReport
 }
 
+// StateSensor observes the real world and returns an up-to-date WorldState.
+// It receives the state the executor currently believes in, which it may use
+// as a starting point. Used to re-sense the world before replanning.
+type StateSensor func(ctx context.Context, believed WorldState) (WorldState, error)
+
 // NewGraphExecutor creates a new graph executor.
 func NewGraphExecutor(persistence *GraphPersistence, runID string) *GraphExecutor {
 	return &GraphExecutor{
 		persistence: persistence,
 		actions:     make(map[string]Action),
+		instances:   make(map[string]Action),
 		runID:       runID,
+		events:      NewEventBus(),
 	}
 }
 
 // RegisterAction registers an action that can be executed by name.
+// Serializable actions are also registered by spec, so that actions sharing
+// a name each resolve to their own instance.
 func (ge *GraphExecutor) RegisterAction(action Action) {
 	ge.actions[action.Name()] = action
+	if spec, err := ActionSpecOf(action); err == nil {
+		ge.instances[spec.key()] = action
+	}
 }
 
 // RegisterActions registers multiple actions.
@@ -38,18 +64,299 @@ func (ge *GraphExecutor) RegisterActions(actions []Action) {
 	}
 }
 
+// SetActionRegistry sets the registry used to rebuild serialized actions
+// that were not registered, e.g. when executing a graph planned by another
+// process.
+func (ge *GraphExecutor) SetActionRegistry(registry *ActionRegistry) {
+	ge.registry = registry
+}
+
+// resolveAction returns the action to run for a node's action name and
+// spec: the registered instance with that spec, an instance rebuilt from the
+// spec by the registry, or the action registered under the name. A
+// serializable action registered under the name is only used if its spec
+// matches, so that actions sharing a name are never confused.
+func (ge *GraphExecutor) resolveAction(name string, spec ActionSpec) (Action, error) {
+	if !spec.IsZero() {
+		ge.instancesMu.Lock()
+		defer ge.instancesMu.Unlock()
+		if action, exists := ge.instances[spec.key()]; exists {
+			return action, nil
+		}
+		if ge.registry != nil {
+			action, err := ge.registry.Build(spec)
+			if err != nil {
+				return nil, fmt.Errorf("failed to rebuild action %s: %w", name, err)
+			}
+			ge.instances[spec.key()] = action
+			return action, nil
+		}
+	}
+
+	action, exists := ge.actions[name]
+	if !exists {
+		return nil, fmt.Errorf("action not found: %s", name)
+	}
+	if registered, err := ActionSpecOf(action); err == nil && !spec.IsZero() && registered.key() != spec.key() {
+		return nil, fmt.Errorf("action not found: %s with parameters %s", name, spec.Params)
+	}
+	return action, nil
+}
+
+// ValidateGraph validates a graph with ValidateGraph against the actions the
+// executor would run for it: its registered actions and the instances the
+// graph's action specs resolve to, including those the registry rebuilds.
+func (ge *GraphExecutor) ValidateGraph(graph *PlanGraph, state WorldState) error {
+	actions := make([]Action, 0, len(ge.actions))
+	for _, action := range ge.actions {
+		actions = append(actions, action)
+	}
+
+	resolve := func(names []string, specs []ActionSpec) {
+		for i, name := range names {
+			if action, err := ge.resolveAction(name, specAt(specs, i)); err == nil {
+				actions = append(actions, action)
+			}
+		}
+	}
+	for _, id := range graph.nodeIDs() {
+		node := graph.Nodes[id]
+		resolve(node.ActionNames, node.ActionSpecs)
+		for i, alternative := range node.Alternatives {
+			var specs []ActionSpec
+			if i < len(node.AlternativeSpecs) {
+				specs = node.AlternativeSpecs[i]
+			}
+			resolve(alternative, specs)
+		}
+	}
+
+	return ValidateGraph(graph, actions, state)
+}
+
+// EnableReplanning turns on closed-loop execution. When an atomic node fails
+// after exhausting its precomputed alternatives, the executor re-senses the
+// world, asks the planner for a fresh action plan for the node's goal from the
+// actual state, persists it on the node and continues. Each node is replanned
+// at most maxReplans times. The planner's actions are registered so that new
+// plans can be executed.
+func (ge *GraphExecutor) EnableReplanning(planner *Planner, maxReplans int) {
+	ge.planner = planner
+	ge.maxReplans = maxReplans
+	ge.RegisterActions(planner.Actions())
+}
+
+// SetSensor sets the function used to re-sense the world before replanning.
+// Without a sensor the executor replans from the state it believes in.
+func (ge *GraphExecutor) SetSensor(sensor StateSensor) {
+	ge.sensor = sensor
+}
+
+// SetMaxWorkers sets how many atomic nodes may execute concurrently. With
+// more than one worker, sibling nodes are scheduled topologically by their
+// dependency edges and independent ones run in parallel. The default of one
+// worker executes children strictly in order.
+func (ge *GraphExecutor) SetMaxWorkers(maxWorkers int) {
+	ge.maxWorkers = maxWorkers
+}
+
+// Events returns the bus the executor publishes its lifecycle events on.
+func (ge *GraphExecutor) Events() *EventBus {
+	return ge.events
+}
+
+// SetEventBus makes the executor publish its lifecycle events on bus, e.g.
+// to share one bus between the executors of several runs.
+func (ge *GraphExecutor) SetEventBus(bus *EventBus) {
+	ge.events = bus
+}
+
+// publish stamps an event with the run ID and whether it is simulated, and
+// publishes it.
+func (ge *GraphExecutor) publish(event Event) {
+	event.RunID = ge.runID
+	event.Simulated = ge.dryRun
+	ge.events.Publish(event)
+}
+
+// SetDryRun turns simulation on or off. In a dry run, Execute and Resume
+// work on an in-memory copy of the graph and persist nothing: actions are
+// not executed, their preconditions are checked in order and their declared
+// effects applied symbolically, and nodes are executed sequentially,
+// continuing past failures so that every failing node is reported. The
+// outcome is available from SimulationReport.
+func (ge *GraphExecutor) SetDryRun(dryRun bool) {
+	ge.dryRun = dryRun
+}
+
+// SimulationReport returns the report of the last dry run, or nil.
+func (ge *GraphExecutor) SimulationReport() *SimulationReport {
+	return ge.simulation
+}
+
 // Execute executes the plan graph starting from the root node.
 func (ge *GraphExecutor) Execute(ctx context.Context, initialState WorldState) error {
+	if ge.dryRun {
+		return ge.simulate(ctx, initialState)
+	}
+	return ge.execute(ctx, initialState)
+}
+
+// simulate dry-runs the graph on an in-memory copy, collecting the outcome
+// of every node into a SimulationReport.
+func (ge *GraphExecutor) simulate(ctx context.Context, initialState WorldState) error {
+	graph, err := ge.persistence.LoadGraph(ge.runID)
+	if err != nil {
+		return fmt.Errorf("failed to load graph: %w", err)
+	}
+
+	persistence := NewGraphPersistenceWithStore("", NewMemoryGraphStore())
+	err = persistence.SaveGraph(graph, ge.runID)
+	if err != nil {
+		return fmt.Errorf("failed to copy graph for dry run: %w", err)
+	}
+	persisted := ge.persistence
+	ge.persistence = persistence
+	defer func() { ge.persistence = persisted }()
+
+	report := &SimulationReport{Nodes: []SimulatedNode{}}
+	unsubscribe := ge.events.Subscribe(func(event Event) {
+		if event.Type == EventNodeFinished && event.Simulated && event.RunID == ge.runID {
+			report.record(event, graph)
+		}
+	})
+
+	// A dry run must not be mistaken for a real execution in the journal
+	err = ge.execute(WithJournal(ctx, nil), initialState)
+	unsubscribe()
+
+	report.FinalState = ge.State()
+	ge.simulation = report
+	return err
+}
+
+func (ge *GraphExecutor) execute(ctx context.Context, initialState WorldState) error {
 	graph, err := ge.persistence.LoadGraph(ge.runID)
 	if err != nil {
 		return fmt.Errorf("failed to load graph: %w", err)
 	}
 
+	ge.workers = nil
+	if ge.maxWorkers > 1 && !ge.dryRun {
+		ge.workers = make(chan struct{}, ge.maxWorkers)
+	}
+
 	log.Info("Starting graph execution", "rootNode", graph.RootNodeID, "totalNodes", graph.Metadata.TotalNodes)
 
+	started := time.Now()
+	ge.publish(Event{Type: EventPlanStarted, Progress: graphStatus(graph)})
+
 	// Execute from root
 	currentState := initialState.Clone()
-	return ge.executeNode(ctx, graph, graph.RootNodeID, currentState)
+	ge.state = currentState
+	ge.checkpoint()
+	err = ge.executeNode(ctx, graph, graph.RootNodeID, currentState)
+
+	finished := Event{Type: EventRunFinished, Duration: time.Since(started), Err: err}
+	if status, statusErr := ge.GetGraphStatus(); statusErr == nil {
+		finished.Progress = status
+	}
+	ge.publish(finished)
+
+	return err
+}
+
+// Resume continues an interrupted execution of the run's graph. Nodes left
+// running are reset to pending, the latest WorldState checkpoint is restored,
+// and completed and skipped nodes are not executed again.
+func (ge *GraphExecutor) Resume(ctx context.Context) error {
+	reset := []string{}
+	if !ge.dryRun {
+		var err error
+		reset, err = ge.persistence.ResetRunningNodes(ge.runID)
+		if err != nil {
+			return fmt.Errorf("failed to reset stale nodes: %w", err)
+		}
+	}
+
+	state, err := ge.persistence.LoadLatestState(ge.runID)
+	if err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
+	}
+
+	log.Info("Resuming graph execution", "runID", ge.runID, "resetNodes", reset, "state", state)
+	return ge.Execute(ctx, state)
+}
+
+// State returns a copy of the world state as of the end of the last
+// execution, e.g. to re-refine a failed subtree from where execution stopped.
+func (ge *GraphExecutor) State() WorldState {
+	ge.stateMu.Lock()
+	defer ge.stateMu.Unlock()
+
+	if ge.state == nil {
+		return NewWorldState()
+	}
+	return ge.state.Clone()
+}
+
+// applyToState merges changes into a state that may be the executor's
+// believed state, which concurrently executing nodes also update.
+func (ge *GraphExecutor) applyToState(state, changes WorldState) {
+	ge.stateMu.Lock()
+	defer ge.stateMu.Unlock()
+
+	state.Apply(changes)
+	if ge.state != nil {
+		ge.state.Apply(changes)
+	}
+}
+
+// updateBelievedState merges changes into the executor's believed state
+// only.
+func (ge *GraphExecutor) updateBelievedState(changes WorldState) {
+	ge.stateMu.Lock()
+	defer ge.stateMu.Unlock()
+
+	if ge.state != nil {
+		ge.state.Apply(changes)
+	}
+}
+
+// cloneState copies a state that may be the executor's believed state.
+func (ge *GraphExecutor) cloneState(state WorldState) WorldState {
+	ge.stateMu.Lock()
+	defer ge.stateMu.Unlock()
+	return state.Clone()
+}
+
+// checkpoint persists the executor's believed state so that execution can
+// be resumed from it.
+func (ge *GraphExecutor) checkpoint() {
+	if ge.dryRun {
+		return
+	}
+
+	ge.stateMu.Lock()
+	defer ge.stateMu.Unlock()
+
+	if err := ge.persistence.SaveStateCheckpoint(ge.runID, ge.state); err != nil {
+		log.Warn("Failed to checkpoint world state", "error", err)
+	}
+}
+
+// checkpointNode saves a versioned snapshot of the state a node sees before
+// or after executing, and after execution also the executor's believed state.
+func (ge *GraphExecutor) checkpointNode(nodeID string, phase CheckpointPhase, state WorldState) {
+	if ge.dryRun {
+		return
+	}
+	if _, err := ge.persistence.SaveCheckpoint(ge.runID, nodeID, phase, ge.cloneState(state)); err != nil {
+		log.Warn("Failed to checkpoint node state", "nodeID", nodeID, "phase", phase, "error", err)
+	}
+	if phase == CheckpointAfter {
+		ge.checkpoint()
+	}
 }
 
 // executeNode executes a single node and its children recursively.
@@ -61,6 +368,11 @@ func (ge *GraphExecutor) executeNode(ctx context.Context, graph *PlanGraph, node
 	}
 
 	node := nodeContext.Node
+	if node.Status == StatusCompleted || node.Status == StatusSkipped {
+		log.Info("Node already done, skipping", "nodeID", nodeID, "status", node.Status)
+		return nil
+	}
+
 	log.Info("Executing node",
 		"id", node.ID,
 		"goal", node.GoalName,
@@ -73,6 +385,10 @@ func (ge *GraphExecutor) executeNode(ctx context.Context, graph *PlanGraph, node
 	if err != nil {
 		log.Warn("Failed to update node status", "error", err)
 	}
+	ge.checkpointNode(nodeID, CheckpointBefore, currentState)
+	started := time.Now()
+	before := ge.cloneState(currentState)
+	ge.publish(Event{Type: EventNodeStarted, NodeID: nodeID, GoalName: node.GoalName})
 
 	// Check if goal is already satisfied
 	goalState := NewWorldState()
@@ -83,18 +399,28 @@ func (ge *GraphExecutor) executeNode(ctx context.Context, graph *PlanGraph, node
 	if currentState.Matches(goalState) {
 		log.Info("Goal already satisfied, skipping node", "nodeID", nodeID)
 		err = ge.persistence.UpdateNodeStatus(ge.runID, nodeID, StatusSkipped, &NodeResult{
-			Success: true,
+			Success:  true,
+			Duration: time.Since(started),
 		})
 		if err != nil {
 			log.Warn("Failed to update node status", "error", err)
 		}
+		ge.checkpointNode(nodeID, CheckpointAfter, currentState)
+		ge.finishNode(ctx, node, StatusSkipped, started, before, currentState, nil)
 		return nil
 	}
 
 	// Execute based on node type
 	var execErr error
+	var replans []ReplanRecord
 	if node.IsAtomic {
-		execErr = ge.executeAtomicNode(ctx, node, currentState)
+		replans, execErr = ge.executeAtomicNode(ctx, node, currentState)
+		if ge.workers != nil {
+			// A concurrently executing child runs on its own copy of the
+			// state; publish its changes to the believed state now rather
+			// than when the parent merges them
+			ge.updateBelievedState(currentState.Changes(before))
+		}
 	} else {
 		execErr = ge.executeCompositeNode(ctx, graph, node, currentState)
 	}
@@ -105,17 +431,21 @@ func (ge *GraphExecutor) executeNode(ctx context.Context, graph *PlanGraph, node
 		err = ge.persistence.UpdateNodeStatus(ge.runID, nodeID, StatusFailed, &NodeResult{
 			Success:      false,
 			ErrorMessage: execErr.Error(),
+			Replans:      replans,
+			Duration:     time.Since(started),
 		})
 		if err != nil {
 			log.Warn("Failed to update node status", "error", err)
 		}
+		ge.checkpointNode(nodeID, CheckpointAfter, currentState)
+		ge.finishNode(ctx, node, StatusFailed, started, before, currentState, execErr)
 		return execErr
 	}
 
 	// Success - capture state changes
 	stateChanges := make(map[string]interface{})
 	for k, v := range goalState {
-		if currentState.Get(k) != v {
+		if !stateValuesEqual(currentState.Get(k), v) {
 			stateChanges[k] = v
 		}
 	}
@@ -123,31 +453,248 @@ func (ge *GraphExecutor) executeNode(ctx context.Context, graph *PlanGraph, node
 	err = ge.persistence.UpdateNodeStatus(ge.runID, nodeID, StatusCompleted, &NodeResult{
 		Success:      true,
 		StateChanges: stateChanges,
+		Replans:      replans,
+		Duration:     time.Since(started),
 	})
 	if err != nil {
 		log.Warn("Failed to update node status", "error", err)
 	}
 
+	ge.checkpointNode(nodeID, CheckpointAfter, currentState)
+	ge.finishNode(ctx, node, StatusCompleted, started, before, currentState, nil)
+
 	log.Info("Node execution completed", "nodeID", nodeID, "goal", node.GoalName)
 	return nil
 }
 
-// executeAtomicNode executes an atomic node by running its actions.
-func (ge *GraphExecutor) executeAtomicNode(ctx context.Context, node *GraphNode, currentState WorldState) error {
-	log.Info("Executing atomic node actions", "nodeID", node.ID, "numActions", len(node.ActionNames))
+// finishNode publishes a node's final status and journals it along with how
+// the state changed while the node executed.
+func (ge *GraphExecutor) finishNode(ctx context.Context, node *GraphNode, status NodeStatus, started time.Time, before, currentState WorldState, err error) {
+	duration := time.Since(started)
+	ge.publish(Event{Type: EventNodeFinished, NodeID: node.ID, GoalName: node.GoalName, Status: status, Duration: duration, Err: err})
+
+	changes := ge.cloneState(currentState).Changes(before)
+	if len(changes) > 0 {
+		recordJournal(ctx, JournalEntry{
+			Kind:     JournalStateDelta,
+			NodeID:   node.ID,
+			GoalName: node.GoalName,
+			Data:     map[string]interface{}{"changes": changes},
+		})
+	}
+
+	entry := JournalEntry{
+		Kind:     JournalNodeFinished,
+		NodeID:   node.ID,
+		GoalName: node.GoalName,
+		Data:     map[string]interface{}{"status": status},
+		Duration: duration,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	recordJournal(ctx, entry)
+}
+
+// executeAtomicNode executes an atomic node by running its actions. If the
+// primary action sequence fails and the node carries precomputed alternatives,
+// each alternative is tried in turn from the resulting state. If replanning is
+// enabled and every alternative fails, the node is replanned from the actual
+// state. It returns a record of each replan attempt.
+func (ge *GraphExecutor) executeAtomicNode(ctx context.Context, node *GraphNode, currentState WorldState) ([]ReplanRecord, error) {
+	err := ge.runActionSequence(ctx, node, node.ActionNames, node.ActionSpecs, currentState)
+	if err == nil {
+		return nil, nil
+	}
+
+	for i, alternative := range node.Alternatives {
+		log.Warn("Falling back to alternative plan",
+			"nodeID", node.ID,
+			"alternative", i+1,
+			"numActions", len(alternative),
+			"previousError", err,
+		)
+
+		var specs []ActionSpec
+		if i < len(node.AlternativeSpecs) {
+			specs = node.AlternativeSpecs[i]
+		}
+		altErr := ge.runActionSequence(ctx, node, alternative, specs, currentState)
+		if altErr == nil {
+			return nil, nil
+		}
+		err = fmt.Errorf("alternative %d failed: %w", i+1, altErr)
+	}
+
+	return ge.replan(ctx, node, currentState, err)
+}
+
+// replan repeatedly plans a fresh action sequence for a failed atomic node
+// from the actual world state and executes it, up to the replan limit. A
+// sequence that already failed for the node is never chosen again; the
+// planner's next best plan is used instead.
+func (ge *GraphExecutor) replan(ctx context.Context, node *GraphNode, currentState WorldState, err error) ([]ReplanRecord, error) {
+	if ge.planner == nil || ge.dryRun {
+		return nil, err
+	}
+
+	goal := NewGoal(node.GoalName, node.GoalDesc, WorldState(node.DesiredState).Clone(), 0)
+	replans := []ReplanRecord{}
+
+	failed := map[string]bool{sequenceSignature(node.ActionNames): true}
+	for _, alternative := range node.Alternatives {
+		failed[sequenceSignature(alternative)] = true
+	}
+
+	for attempt := 1; attempt <= ge.maxReplans; attempt++ {
+		record := ReplanRecord{
+			Attempt:   attempt,
+			Timestamp: time.Now().Format(time.RFC3339),
+			Cause:     err.Error(),
+		}
+
+		if ge.sensor != nil {
+			believed := ge.cloneState(currentState)
+			sensed, senseErr := ge.sensor(ctx, believed.Clone())
+			if senseErr != nil {
+				record.Error = fmt.Sprintf("failed to sense world state: %v", senseErr)
+				replans = append(replans, record)
+				return replans, fmt.Errorf("replan %d: failed to sense world state: %w", attempt, senseErr)
+			}
+			// The sensed state replaces the believed one; apply only the
+			// difference so concurrently executing nodes see a consistent state
+			ge.applyToState(currentState, sensed.Changes(believed))
+		}
+
+		snapshot := ge.cloneState(currentState)
+		if goal.IsSatisfied(snapshot) {
+			log.Info("Goal satisfied after re-sensing, no replan needed", "nodeID", node.ID)
+			record.ActionNames = []string{}
+			replans = append(replans, record)
+			return replans, nil
+		}
+
+		plans, planErr := ge.planner.FindPlansWithDiagnostics(snapshot, goal, len(failed)+1)
+		if planErr != nil {
+			record.Error = planErr.Error()
+			replans = append(replans, record)
+			return replans, fmt.Errorf("replan %d found no plan after %v: %w", attempt, err, planErr)
+		}
+
+		var plan *Plan
+		for _, candidate := range plans {
+			if !failed[planSignature(candidate)] {
+				plan = candidate
+				break
+			}
+		}
+		if plan == nil {
+			record.Error = "every plan found already failed"
+			replans = append(replans, record)
+			return replans, fmt.Errorf("replan %d found only plans that already failed after %w", attempt, err)
+		}
+		failed[planSignature(plan)] = true
+
+		record.ActionNames = make([]string, len(plan.Actions))
+		for i, action := range plan.Actions {
+			record.ActionNames[i] = action.Name()
+		}
+
+		log.Warn("Replanning failed node",
+			"nodeID", node.ID,
+			"attempt", attempt,
+			"maxReplans", ge.maxReplans,
+			"actions", record.ActionNames,
+		)
+		ge.publish(Event{Type: EventReplan, NodeID: node.ID, GoalName: node.GoalName, Actions: record.ActionNames, Attempt: attempt, Err: err})
+		recordJournal(ctx, JournalEntry{
+			Kind:     JournalReplan,
+			NodeID:   node.ID,
+			GoalName: node.GoalName,
+			Data:     map[string]interface{}{"attempt": attempt, "actions": record.ActionNames, "cause": record.Cause},
+		})
+
+		specs := actionSpecs(plan.Actions)
+		if updateErr := ge.persistence.UpdateNodeActions(ge.runID, node.ID, record.ActionNames, specs); updateErr != nil {
+			log.Warn("Failed to persist replanned actions", "nodeID", node.ID, "error", updateErr)
+		}
+		node.ActionNames = record.ActionNames
+		node.ActionSpecs = specs
+
+		err = ge.runActionSequence(ctx, node, record.ActionNames, specs, currentState)
+		if err != nil {
+			record.Error = err.Error()
+		}
+		replans = append(replans, record)
+
+		if err == nil {
+			log.Info("Replanned node succeeded", "nodeID", node.ID, "attempt", attempt)
+			return replans, nil
+		}
+	}
+
+	if ge.maxReplans > 0 {
+		err = fmt.Errorf("node failed after %d replans: %w", ge.maxReplans, err)
+	}
+	return replans, err
+}
+
+// runActionSequence executes the named actions in order. specs parallels
+// actionNames and may be nil.
+func (ge *GraphExecutor) runActionSequence(ctx context.Context, node *GraphNode, actionNames []string, specs []ActionSpec, currentState WorldState) error {
+	log.Info("Executing atomic node actions", "nodeID", node.ID, "numActions", len(actionNames))
 
-	for i, actionName := range node.ActionNames {
-		action, exists := ge.actions[actionName]
-		if !exists {
-			return fmt.Errorf("action not found: %s", actionName)
+	for i, actionName := range actionNames {
+		action, err := ge.resolveAction(actionName, specAt(specs, i))
+		if err != nil {
+			return err
 		}
 
 		log.Info("Executing action", "index", i, "action", actionName)
+		ge.publish(Event{Type: EventActionStarted, NodeID: node.ID, GoalName: node.GoalName, Action: actionName})
+		inputs := make(map[string]interface{})
+		for key := range action.Preconditions() {
+			inputs[key] = currentState.Get(key)
+		}
+		recordJournal(ctx, JournalEntry{
+			Kind:     JournalActionInvoked,
+			NodeID:   node.ID,
+			GoalName: node.GoalName,
+			Action:   actionName,
+			Data:     map[string]interface{}{"inputs": inputs},
+		})
 
-		err := action.Execute(ctx, currentState)
+		started := time.Now()
+		if ge.dryRun {
+			err = checkPreconditions(action, currentState)
+			if err == nil {
+				ApplyEffects(currentState, action)
+			}
+		} else {
+			err = action.Execute(ctx, currentState)
+		}
+		duration := time.Since(started)
+		ge.publish(Event{Type: EventActionFinished, NodeID: node.ID, GoalName: node.GoalName, Action: actionName, Duration: duration, Err: err})
+
+		finished := JournalEntry{
+			Kind:     JournalActionFinished,
+			NodeID:   node.ID,
+			GoalName: node.GoalName,
+			Action:   actionName,
+			Data:     map[string]interface{}{"effects": action.Effects()},
+			Duration: duration,
+		}
+		if err != nil {
+			finished.Error = err.Error()
+		}
+		recordJournal(ctx, finished)
 		if err != nil {
 			return fmt.Errorf("action %s failed: %w", actionName, err)
 		}
+		if ge.dryRun {
+			continue
+		}
+		currentState.Invalidate(ActionInvalidates(action))
 
 		// Small delay between actions to avoid rate limiting
 		time.Sleep(100 * time.Millisecond)
@@ -160,18 +707,130 @@ func (ge *GraphExecutor) executeAtomicNode(ctx context.Context, node *GraphNode,
 func (ge *GraphExecutor) executeCompositeNode(ctx context.Context, graph *PlanGraph, node *GraphNode, currentState WorldState) error {
 	log.Info("Executing composite node children", "nodeID", node.ID, "numChildren", len(node.ChildIDs))
 
+	if ge.workers != nil {
+		return ge.executeChildrenConcurrently(ctx, graph, node, currentState)
+	}
+
+	var firstErr error
 	for i, childID := range node.ChildIDs {
 		log.Info("Executing child node", "index", i, "childID", childID)
 
 		err := ge.executeNode(ctx, graph, childID, currentState)
 		if err != nil {
-			return fmt.Errorf("child node %s failed: %w", childID, err)
+			err = fmt.Errorf("child node %s failed: %w", childID, err)
+			if !ge.dryRun {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
 		}
 	}
 
+	return firstErr
+}
+
+// childOutcome is the result of executing one child node concurrently.
+type childOutcome struct {
+	nodeID  string
+	changes WorldState
+	err     error
+}
+
+// executeChildrenConcurrently executes a composite node's children in
+// topological order of their dependency edges, starting each child as soon
+// as its dependencies have completed. Each child runs on a copy of the state
+// and its changes are merged back when it finishes, so concurrently running
+// children never share a WorldState. After a failure no further children
+// are started.
+//
+// An atomic child is only started once it has a worker slot, so at most
+// maxWorkers atomic nodes run across the graph. Composite children take no
+// slot, since they only wait for their own children.
+func (ge *GraphExecutor) executeChildrenConcurrently(ctx context.Context, graph *PlanGraph, node *GraphNode, currentState WorldState) error {
+	siblings := make(map[string]bool, len(node.ChildIDs))
+	for _, childID := range node.ChildIDs {
+		siblings[childID] = true
+	}
+
+	pending := append([]string{}, node.ChildIDs...)
+	completed := make(map[string]bool, len(node.ChildIDs))
+	outcomes := make(chan childOutcome)
+	running := 0
+	var firstErr error
+
+	for {
+		if firstErr == nil && ctx.Err() == nil {
+			waiting := pending[:0]
+			for _, childID := range pending {
+				if !dependenciesCompleted(graph.Nodes[childID], siblings, completed) {
+					waiting = append(waiting, childID)
+					continue
+				}
+
+				atomic := graph.Nodes[childID] != nil && graph.Nodes[childID].IsAtomic
+				if atomic {
+					ge.workers <- struct{}{}
+				}
+
+				log.Info("Starting child node", "childID", childID, "running", running+1)
+				running++
+				go func(childID string, state WorldState) {
+					before := state.Clone()
+					err := ge.executeNode(ctx, graph, childID, state)
+					if atomic {
+						<-ge.workers
+					}
+					outcomes <- childOutcome{nodeID: childID, changes: state.Changes(before), err: err}
+				}(childID, ge.cloneState(currentState))
+			}
+			pending = waiting
+		}
+
+		if running == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		running--
+		ge.applyToState(currentState, outcome.changes)
+
+		if outcome.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("child node %s failed: %w", outcome.nodeID, outcome.err)
+			}
+			continue
+		}
+		completed[outcome.nodeID] = true
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("children %v of node %s have unsatisfiable dependencies", pending, node.ID)
+	}
+
 	return nil
 }
 
+// dependenciesCompleted reports whether every sibling a node depends on has
+// completed. Dependencies outside the sibling set are ignored.
+func dependenciesCompleted(node *GraphNode, siblings, completed map[string]bool) bool {
+	if node == nil {
+		return true
+	}
+	for _, dependency := range node.DependsOn {
+		if siblings[dependency] && !completed[dependency] {
+			return false
+		}
+	}
+	return true
+}
+
 // GetGraphStatus returns the current execution status of the graph.
 func (ge *GraphExecutor) GetGraphStatus() (*GraphStatus, error) {
 	graph, err := ge.persistence.LoadGraph(ge.runID)
@@ -179,6 +838,11 @@ func (ge *GraphExecutor) GetGraphStatus() (*GraphStatus, error) {
 		return nil, fmt.Errorf("failed to load graph: %w", err)
 	}
 
+	return graphStatus(graph), nil
+}
+
+// graphStatus counts the graph's nodes by status.
+func graphStatus(graph *PlanGraph) *GraphStatus {
 	status := &GraphStatus{
 		TotalNodes:     len(graph.Nodes),
 		PendingNodes:   0,
@@ -203,7 +867,7 @@ func (ge *GraphExecutor) GetGraphStatus() (*GraphStatus, error) {
 		}
 	}
 
-	return status, nil
+	return status
 }
 
 // GraphStatus represents the execution status of a plan graph.
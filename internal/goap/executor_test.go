@@ -2,7 +2,11 @@ package goap
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestGraphExecutor(t *testing.T) {
@@ -276,4 +280,566 @@ func TestGraphExecutor(t *testing.T) {
 			t.Error("Status should indicate failures")
 		}
 	})
+
+	t.Run("FallbackToAlternative", func(t *testing.T) {
+		runID := "test-fallback-alternative"
+
+		primary := NewSimpleAction(
+			"Primary",
+			"Fails at runtime",
+			WorldState{},
+			WorldState{"done": true},
+			1.0,
+			func(ctx context.Context, ws WorldState) error {
+				return fmt.Errorf("primary broke")
+			},
+		)
+
+		fallbackRan := false
+		fallback := NewSimpleAction(
+			"Fallback",
+			"Costlier but works",
+			WorldState{},
+			WorldState{"done": true},
+			3.0,
+			func(ctx context.Context, ws WorldState) error {
+				fallbackRan = true
+				return nil
+			},
+		)
+
+		planner := NewPlanner([]Action{primary, fallback})
+		hp := NewHierarchicalPlanner(planner, NewMockGoalRefiner(), 3)
+		hp.SetAlternatives(1)
+
+		goal := NewGoal("Done", "Get it done", WorldState{"done": true}, 1.0)
+		plan, err := hp.PlanHierarchical(context.Background(), NewWorldState(), goal)
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+		if len(plan.Alternatives) != 1 {
+			t.Fatalf("Expected 1 alternative plan, got %d", len(plan.Alternatives))
+		}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		if len(graph.Nodes[graph.RootNodeID].Alternatives) != 1 {
+			t.Fatal("Alternatives should be persisted on the graph node")
+		}
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterActions(plan.AllActions())
+		executor.RegisterActions(plan.AlternativeActions())
+
+		if err := executor.Execute(context.Background(), NewWorldState()); err != nil {
+			t.Fatalf("Execution should succeed via the alternative: %v", err)
+		}
+
+		if !fallbackRan {
+			t.Error("Fallback action should have been executed")
+		}
+
+		status, err := executor.GetGraphStatus()
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		if status.CompletedNodes != 1 {
+			t.Errorf("Expected 1 completed node, got %d", status.CompletedNodes)
+		}
+	})
+
+	t.Run("ReplanOnFailure", func(t *testing.T) {
+		runID := "test-replan"
+
+		download := NewSimpleAction(
+			"Download",
+			"Fetch over the network",
+			WorldState{"network": true},
+			WorldState{"fetched": true},
+			1.0,
+			func(ctx context.Context, ws WorldState) error {
+				return fmt.Errorf("connection reset")
+			},
+		)
+		useCache := NewSimpleAction(
+			"UseCache",
+			"Fetch from the local cache",
+			WorldState{},
+			WorldState{"fetched": true},
+			5.0,
+			func(ctx context.Context, ws WorldState) error { return nil },
+		)
+
+		planner := NewPlanner([]Action{download, useCache})
+		goal := NewGoal("Fetch", "Fetch the dependency", WorldState{"fetched": true}, 1.0)
+		plan := &HierarchicalPlan{Goal: goal, Actions: []Action{download}}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.EnableReplanning(planner, 2)
+		executor.SetSensor(func(ctx context.Context, believed WorldState) (WorldState, error) {
+			believed.Set("network", false)
+			return believed, nil
+		})
+
+		err := executor.Execute(context.Background(), WorldState{"network": true})
+		if err != nil {
+			t.Fatalf("Execution should recover by replanning: %v", err)
+		}
+
+		reloaded, err := persistence.LoadGraph(runID)
+		if err != nil {
+			t.Fatalf("Failed to reload graph: %v", err)
+		}
+		node := reloaded.Nodes[reloaded.RootNodeID]
+
+		if len(node.ActionNames) != 1 || node.ActionNames[0] != "UseCache" {
+			t.Errorf("Replanned actions should be persisted, got %v", node.ActionNames)
+		}
+		if node.Result == nil || len(node.Result.Replans) != 1 {
+			t.Fatalf("Expected 1 replan record, got %+v", node.Result)
+		}
+		if node.Result.Replans[0].Cause == "" {
+			t.Error("Replan record should capture the failure that caused it")
+		}
+	})
+
+	t.Run("SensedStateReplacesBelieved", func(t *testing.T) {
+		runID := "test-replan-sensed"
+
+		download := NewSimpleAction(
+			"Download",
+			"Fetch over the network",
+			WorldState{},
+			WorldState{"fetched": true},
+			1.0,
+			func(ctx context.Context, ws WorldState) error {
+				return fmt.Errorf("connection reset")
+			},
+		)
+		useCache := NewSimpleAction(
+			"UseCache",
+			"Fetch from the local cache",
+			WorldState{},
+			WorldState{"fetched": true},
+			5.0,
+			func(ctx context.Context, ws WorldState) error { return nil },
+		)
+
+		planner := NewPlanner([]Action{download, useCache})
+		goal := NewGoal("Fetch", "Fetch the dependency", WorldState{"fetched": true}, 1.0)
+		plan := &HierarchicalPlan{Goal: goal, Actions: []Action{download}}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.EnableReplanning(planner, 2)
+		executor.SetSensor(func(ctx context.Context, believed WorldState) (WorldState, error) {
+			return WorldState{"network": false}, nil
+		})
+
+		err := executor.Execute(context.Background(), WorldState{"network": true, "proxy": "corp"})
+		if err != nil {
+			t.Fatalf("Execution should recover by replanning: %v", err)
+		}
+
+		state := executor.State()
+		if state.Get("network") != false {
+			t.Errorf("Expected sensed network false, got %v", state.Get("network"))
+		}
+		if state.Has("proxy") {
+			t.Errorf("Expected proxy no longer sensed to be removed, got %v", state.Get("proxy"))
+		}
+		if state.Get("fetched") != true {
+			t.Errorf("Expected fetched true, got %v", state.Get("fetched"))
+		}
+	})
+
+	t.Run("ReplanLimit", func(t *testing.T) {
+		runID := "test-replan-limit"
+
+		attempts := 0
+		var flaky []Action
+		for i, name := range []string{"FlakyA", "FlakyB", "FlakyC", "FlakyD"} {
+			flaky = append(flaky, NewSimpleAction(
+				name,
+				"Always fails",
+				WorldState{},
+				WorldState{"done": true},
+				float64(i+1),
+				func(ctx context.Context, ws WorldState) error {
+					attempts++
+					return fmt.Errorf("attempt %d failed", attempts)
+				},
+			))
+		}
+
+		goal := NewGoal("Done", "Do it", WorldState{"done": true}, 1.0)
+		plan := &HierarchicalPlan{Goal: goal, Actions: []Action{flaky[0]}}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.EnableReplanning(NewPlanner(flaky), 2)
+
+		if err := executor.Execute(context.Background(), NewWorldState()); err == nil {
+			t.Fatal("Execution should fail once the replan limit is exhausted")
+		}
+		if attempts != 3 {
+			t.Errorf("Expected 1 initial attempt and 2 replans, got %d attempts", attempts)
+		}
+
+		reloaded, _ := persistence.LoadGraph(runID)
+		node := reloaded.Nodes[reloaded.RootNodeID]
+		if node.Status != StatusFailed || len(node.Result.Replans) != 2 {
+			t.Errorf("Expected failed node with 2 replan records, got %s with %+v", node.Status, node.Result)
+		}
+		if actions := node.Result.Replans[1].ActionNames; len(actions) != 1 || actions[0] != "FlakyC" {
+			t.Errorf("Expected each replan to try a new plan, got %v", actions)
+		}
+	})
+
+	t.Run("RejectsRepeatedPlan", func(t *testing.T) {
+		runID := "test-replan-repeated"
+
+		attempts := 0
+		flaky := NewSimpleAction(
+			"Flaky",
+			"Always fails",
+			WorldState{},
+			WorldState{"done": true},
+			1.0,
+			func(ctx context.Context, ws WorldState) error {
+				attempts++
+				return fmt.Errorf("attempt %d failed", attempts)
+			},
+		)
+
+		goal := NewGoal("Done", "Do it", WorldState{"done": true}, 1.0)
+		graph := BuildGraphFromPlan(&HierarchicalPlan{Goal: goal, Actions: []Action{flaky}}, "test-agent")
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.EnableReplanning(NewPlanner([]Action{flaky}), 2)
+
+		err := executor.Execute(context.Background(), NewWorldState())
+		if err == nil || !strings.Contains(err.Error(), "already failed") {
+			t.Errorf("Expected replanning to reject the plan that failed, got %v", err)
+		}
+		if attempts != 1 {
+			t.Errorf("Expected the failed plan not to be retried, got %d attempts", attempts)
+		}
+	})
+
+	t.Run("ResumeKeepsCompletedNodes", func(t *testing.T) {
+		runID := "test-resume-completed"
+
+		failing := true
+		actionA := NewSimpleAction("DoA", "Do A", WorldState{}, WorldState{"a": true}, 1.0,
+			func(ctx context.Context, ws WorldState) error { return nil })
+		actionB := NewSimpleAction("DoB", "Do B", WorldState{}, WorldState{"b": true}, 1.0,
+			func(ctx context.Context, ws WorldState) error {
+				if failing {
+					return fmt.Errorf("not yet")
+				}
+				return nil
+			})
+
+		plan := &HierarchicalPlan{
+			Goal: NewGoal("Root", "Both", WorldState{"a": true, "b": true}, 1.0),
+			Subplans: []*HierarchicalPlan{
+				{Goal: NewGoal("GoalA", "A", WorldState{"a": true}, 1.0), Actions: []Action{actionA}, Depth: 1},
+				{Goal: NewGoal("GoalB", "B", WorldState{"b": true}, 1.0), Actions: []Action{actionB}, Depth: 1},
+			},
+		}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterActions([]Action{actionA, actionB})
+
+		ctx := context.Background()
+		if err := executor.Execute(ctx, NewWorldState()); err == nil {
+			t.Fatal("First execution should fail")
+		}
+		if executor.State().Get("a") != true {
+			t.Errorf("Executor state should reflect completed work, got %s", executor.State())
+		}
+
+		failing = false
+		if err := executor.Execute(ctx, executor.State()); err != nil {
+			t.Fatalf("Second execution failed: %v", err)
+		}
+
+		status, err := executor.GetGraphStatus()
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		if status.CompletedNodes != 3 || status.SkippedNodes != 0 {
+			t.Errorf("Expected 3 completed and 0 skipped nodes, got %+v", status)
+		}
+	})
+
+	t.Run("ParallelIndependentNodes", func(t *testing.T) {
+		runID := "test-parallel"
+
+		// A and B each wait until both have started, so they only succeed
+		// when executed concurrently
+		var started sync.WaitGroup
+		started.Add(2)
+		barrier := func(key string) func(ctx context.Context, ws WorldState) error {
+			return func(ctx context.Context, ws WorldState) error {
+				started.Done()
+				wait := make(chan struct{})
+				go func() { started.Wait(); close(wait) }()
+				select {
+				case <-wait:
+					return nil
+				case <-time.After(5 * time.Second):
+					return fmt.Errorf("%s was not executed concurrently", key)
+				}
+			}
+		}
+
+		actionA := NewSimpleAction("DoA", "Do A", WorldState{}, WorldState{"a": true}, 1.0, barrier("a"))
+		actionB := NewSimpleAction("DoB", "Do B", WorldState{}, WorldState{"b": true}, 1.0, barrier("b"))
+		actionC := NewSimpleAction("DoC", "Do C", WorldState{}, WorldState{"c": true}, 1.0,
+			func(ctx context.Context, ws WorldState) error {
+				if ws.Get("a") != true || ws.Get("b") != true {
+					return fmt.Errorf("C started before its dependencies completed: %s", ws)
+				}
+				return nil
+			})
+
+		goalA := NewGoal("GoalA", "A", WorldState{"a": true}, 1.0)
+		goalA.SetDependsOn()
+		goalB := NewGoal("GoalB", "B", WorldState{"b": true}, 1.0)
+		goalB.SetDependsOn()
+		goalC := NewGoal("GoalC", "C", WorldState{"c": true}, 1.0)
+		goalC.SetDependsOn("GoalA", "GoalB")
+
+		plan := &HierarchicalPlan{
+			Goal: NewGoal("Root", "All", WorldState{"a": true, "b": true, "c": true}, 1.0),
+			Subplans: []*HierarchicalPlan{
+				{Goal: goalA, Actions: []Action{actionA}, Depth: 1},
+				{Goal: goalB, Actions: []Action{actionB}, Depth: 1},
+				{Goal: goalC, Actions: []Action{actionC}, Depth: 1},
+			},
+		}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterActions([]Action{actionA, actionB, actionC})
+		executor.SetMaxWorkers(2)
+
+		if err := executor.Execute(context.Background(), NewWorldState()); err != nil {
+			t.Fatalf("Execution failed: %v", err)
+		}
+
+		state := executor.State()
+		if state.Get("a") != true || state.Get("b") != true || state.Get("c") != true {
+			t.Errorf("Expected merged state from all children, got %s", state)
+		}
+
+		status, err := executor.GetGraphStatus()
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		if status.CompletedNodes != 4 {
+			t.Errorf("Expected 4 completed nodes, got %+v", status)
+		}
+	})
+
+	t.Run("WorkersBoundRunningNodes", func(t *testing.T) {
+		runID := "test-parallel-bound"
+
+		var mu sync.Mutex
+		maxRunning := 0
+		countRunning := func(ctx context.Context, ws WorldState) error {
+			graph, err := persistence.LoadGraph(runID)
+			if err != nil {
+				return err
+			}
+			running := 0
+			for _, node := range graph.Nodes {
+				if node.IsAtomic && node.Status == StatusRunning {
+					running++
+				}
+			}
+			mu.Lock()
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}
+
+		var actions []Action
+		var subplans []*HierarchicalPlan
+		desired := NewWorldState()
+		for i := 0; i < 6; i++ {
+			key := fmt.Sprintf("k%d", i)
+			action := NewSimpleAction("Do"+key, "Do "+key, WorldState{}, WorldState{key: true}, 1.0, countRunning)
+			goal := NewGoal("Goal"+key, key, WorldState{key: true}, 1.0)
+			goal.SetDependsOn()
+			actions = append(actions, action)
+			subplans = append(subplans, &HierarchicalPlan{Goal: goal, Actions: []Action{action}, Depth: 1})
+			desired.Set(key, true)
+		}
+		plan := &HierarchicalPlan{Goal: NewGoal("Root", "All", desired, 1.0), Subplans: subplans}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterActions(actions)
+		executor.SetMaxWorkers(2)
+
+		if err := executor.Execute(context.Background(), NewWorldState()); err != nil {
+			t.Fatalf("Execution failed: %v", err)
+		}
+		if maxRunning > 2 {
+			t.Errorf("Expected at most 2 atomic nodes running at once, got %d", maxRunning)
+		}
+		if !executor.State().Matches(desired) {
+			t.Errorf("Expected merged state from all children, got %s", executor.State())
+		}
+	})
+
+	t.Run("ResumeInterruptedRun", func(t *testing.T) {
+		runID := "test-resume-interrupted"
+
+		actionA := NewSimpleAction("DoA", "Do A", WorldState{}, WorldState{"a": true}, 1.0,
+			func(ctx context.Context, ws WorldState) error {
+				t.Error("Completed node should not execute again")
+				return nil
+			})
+		actionB := NewSimpleAction("DoB", "Do B", WorldState{}, WorldState{"b": true}, 1.0,
+			func(ctx context.Context, ws WorldState) error {
+				if ws.Get("a") != true {
+					return fmt.Errorf("state checkpoint was not restored: %s", ws)
+				}
+				return nil
+			})
+
+		plan := &HierarchicalPlan{
+			Goal: NewGoal("Root", "Both", WorldState{"a": true, "b": true}, 1.0),
+			Subplans: []*HierarchicalPlan{
+				{Goal: NewGoal("GoalA", "A", WorldState{"a": true}, 1.0), Actions: []Action{actionA}, Depth: 1},
+				{Goal: NewGoal("GoalB", "B", WorldState{"b": true}, 1.0), Actions: []Action{actionB}, Depth: 1},
+			},
+		}
+
+		// Simulate a crash while GoalB was running
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		nodeByGoal(t, graph, "Root").Status = StatusRunning
+		nodeByGoal(t, graph, "GoalA").Status = StatusCompleted
+		nodeByGoal(t, graph, "GoalB").Status = StatusRunning
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+		if err := persistence.SaveStateCheckpoint(runID, WorldState{"a": true}); err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterActions([]Action{actionA, actionB})
+
+		if err := executor.Resume(context.Background()); err != nil {
+			t.Fatalf("Resume failed: %v", err)
+		}
+
+		status, err := executor.GetGraphStatus()
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		if status.CompletedNodes != 3 || status.RunningNodes != 0 {
+			t.Errorf("Expected all 3 nodes completed, got %+v", status)
+		}
+
+		state, err := persistence.LoadLatestState(runID)
+		if err != nil {
+			t.Fatalf("Failed to load checkpoint: %v", err)
+		}
+		if state.Get("a") != true || state.Get("b") != true {
+			t.Errorf("Expected the latest checkpoint to include both keys, got %s", state)
+		}
+	})
+
+	t.Run("ResumeKeepsIntValues", func(t *testing.T) {
+		runID := "test-resume-int"
+
+		listen := NewSimpleAction("Listen", "Pick a port", WorldState{}, WorldState{"port": 80}, 1.0,
+			func(ctx context.Context, ws WorldState) error { return nil })
+		serve := NewSimpleAction("Serve", "Serve on port 80", WorldState{"port": 80}, WorldState{"served": true}, 1.0,
+			func(ctx context.Context, ws WorldState) error { return nil })
+
+		plan := &HierarchicalPlan{
+			Goal: NewGoal("Root", "Serve", WorldState{"port": 80, "served": true}, 1.0),
+			Subplans: []*HierarchicalPlan{
+				{Goal: NewGoal("Listen", "Listen", WorldState{"port": 80}, 1.0), Actions: []Action{listen}, Depth: 1},
+				{Goal: NewGoal("Serve", "Serve", WorldState{"served": true}, 1.0), Actions: []Action{serve}, Depth: 1},
+			},
+		}
+
+		// Simulate a crash while Serve was running
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		nodeByGoal(t, graph, "Root").Status = StatusRunning
+		nodeByGoal(t, graph, "Listen").Status = StatusCompleted
+		nodeByGoal(t, graph, "Serve").Status = StatusRunning
+		if err := persistence.SaveGraph(graph, runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+		if err := persistence.SaveStateCheckpoint(runID, WorldState{"port": 80}); err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+
+		state, err := persistence.LoadLatestState(runID)
+		if err != nil {
+			t.Fatalf("Failed to load checkpoint: %v", err)
+		}
+		if port, ok := state.Get("port").(int); !ok || port != 80 {
+			t.Fatalf("Expected int port 80 after reload, got %T %v", state.Get("port"), state.Get("port"))
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterActions([]Action{listen, serve})
+
+		if err := executor.Resume(context.Background()); err != nil {
+			t.Fatalf("Resume failed: %v", err)
+		}
+		if executor.State().Get("served") != true {
+			t.Errorf("Expected Serve to run after resume, got %s", executor.State())
+		}
+
+		status, err := executor.GetGraphStatus()
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		if status.CompletedNodes != 3 {
+			t.Errorf("Expected all 3 nodes completed, got %+v", status)
+		}
+	})
 }
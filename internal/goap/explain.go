@@ -0,0 +1,241 @@
+package goap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// explainRunnerUps is how many runner-up plans Planner.Explain searches for
+// in addition to the chosen one.
+const explainRunnerUps = 3
+
+// PlanExplanation explains why the planner chose a plan: what each action
+// contributes, what it costs, and which alternatives lost and why.
+type PlanExplanation struct {
+	Goal      string                `json:"goal"`
+	Steps     []StepExplanation     `json:"steps"`
+	TotalCost float64               `json:"total_cost"`
+	Rejected  []RejectedAlternative `json:"rejected,omitempty"`
+}
+
+// StepExplanation describes a single action in a plan.
+type StepExplanation struct {
+	Action string  `json:"action"`
+	Cost   float64 `json:"cost"`
+
+	// Satisfies lists the goal keys and downstream preconditions this step
+	// establishes, e.g. "goal tests_written=true" or
+	// "precondition code_written=true of RunGoTests"
+	Satisfies []string `json:"satisfies"`
+}
+
+// RejectedAlternative describes an action or plan the planner did not choose.
+type RejectedAlternative struct {
+	// Alternative is an action name, or a plan rendered as "A → B → C"
+	Alternative string  `json:"alternative"`
+	Cost        float64 `json:"cost"`
+	Reason      string  `json:"reason"`
+}
+
+// String returns a human-readable rendering of the explanation.
+func (pe *PlanExplanation) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Plan for %s (cost: %.2f):\n", pe.Goal, pe.TotalCost)
+	for i, step := range pe.Steps {
+		share := 0.0
+		if pe.TotalCost > 0 {
+			share = step.Cost / pe.TotalCost * 100
+		}
+		fmt.Fprintf(&b, "%d. %s (cost: %.2f, %.0f%%)\n", i+1, step.Action, step.Cost, share)
+		for _, satisfied := range step.Satisfies {
+			fmt.Fprintf(&b, "   → %s\n", satisfied)
+		}
+	}
+
+	if len(pe.Rejected) > 0 {
+		b.WriteString("Rejected alternatives:\n")
+		for _, rejected := range pe.Rejected {
+			fmt.Fprintf(&b, "- %s (cost: %.2f): %s\n", rejected.Alternative, rejected.Cost, rejected.Reason)
+		}
+	}
+
+	return b.String()
+}
+
+// Explain finds the best plan for the goal and explains it, including the
+// runner-up plans the search found and competing actions it passed over.
+// Returns a *PlanFailure error if no plan exists.
+func (p *Planner) Explain(current WorldState, goal *Goal) (*PlanExplanation, error) {
+	plans, err := p.FindPlansWithDiagnostics(current, goal, explainRunnerUps+1)
+	if err != nil {
+		return nil, err
+	}
+	if plans[0].Explanation != nil {
+		return plans[0].Explanation, nil
+	}
+	return p.explainPlan(current, goal, plans[0], plans[1:]), nil
+}
+
+// explainPlan builds the explanation for a chosen plan. runnersUp are the
+// costlier plans found by the same search.
+func (p *Planner) explainPlan(current WorldState, goal *Goal, chosen *Plan, runnersUp []*Plan) *PlanExplanation {
+	explanation := &PlanExplanation{
+		Goal:      goal.Name(),
+		Steps:     make([]StepExplanation, len(chosen.Actions)),
+		TotalCost: chosen.Cost,
+		Rejected:  []RejectedAlternative{},
+	}
+
+	// Replay the plan, remembering the state each step ran in and which
+	// step last established each key/value fact.
+	states := make([]WorldState, len(chosen.Actions))
+	establishedBy := make(map[string]int)
+	state := current.Clone()
+
+	for i, action := range chosen.Actions {
+		states[i] = state.Clone()
+		explanation.Steps[i] = StepExplanation{
+			Action:    action.Name(),
+			Cost:      ActionCost(action, state),
+			Satisfies: []string{},
+		}
+
+		for _, key := range sortedKeys(action.Preconditions()) {
+			value := action.Preconditions()[key]
+			if supplier, ok := establishedBy[factKey(key, value)]; ok {
+				explanation.Steps[supplier].Satisfies = append(explanation.Steps[supplier].Satisfies,
+					fmt.Sprintf("precondition %s=%v of %s", key, value, action.Name()))
+			}
+		}
+
+		for _, key := range sortedKeys(action.Effects()) {
+			value := action.Effects()[key]
+			if state.Has(key) && stateValuesEqual(state.Get(key), value) {
+				continue
+			}
+			establishedBy[factKey(key, value)] = i
+		}
+		for _, key := range ActionInvalidates(action) {
+			if state.Has(key) {
+				delete(establishedBy, factKey(key, state.Get(key)))
+			}
+		}
+		ApplyEffects(state, action)
+	}
+
+	for _, key := range sortedKeys(goal.DesiredState()) {
+		value := goal.DesiredState()[key]
+		if supplier, ok := establishedBy[factKey(key, value)]; ok {
+			explanation.Steps[supplier].Satisfies = append(explanation.Steps[supplier].Satisfies,
+				fmt.Sprintf("goal %s=%v", key, value))
+		}
+	}
+
+	for i := range explanation.Steps {
+		if len(explanation.Steps[i].Satisfies) == 0 {
+			explanation.Steps[i].Satisfies = append(explanation.Steps[i].Satisfies, "no goal key or downstream precondition")
+		}
+	}
+
+	for _, plan := range runnersUp {
+		explanation.Rejected = append(explanation.Rejected, RejectedAlternative{
+			Alternative: planRoute(plan),
+			Cost:        plan.Cost,
+			Reason:      fmt.Sprintf("higher cost (+%.2f)", plan.Cost-chosen.Cost),
+		})
+	}
+
+	explanation.Rejected = append(explanation.Rejected, p.rejectedActions(chosen, states)...)
+
+	return explanation
+}
+
+// rejectedActions finds unused actions that could have established one of
+// the facts a chosen step established, and explains why each lost.
+func (p *Planner) rejectedActions(chosen *Plan, states []WorldState) []RejectedAlternative {
+	used := make(map[string]bool, len(chosen.Actions))
+	for _, action := range chosen.Actions {
+		used[action.Name()] = true
+	}
+
+	rejected := []RejectedAlternative{}
+	reported := make(map[string]bool)
+
+	for i, step := range chosen.Actions {
+		state := states[i]
+		stepCost := ActionCost(step, state)
+
+		for _, candidate := range p.actions {
+			if used[candidate.Name()] || reported[candidate.Name()] || !sharesEffect(step, candidate, state) {
+				continue
+			}
+			reported[candidate.Name()] = true
+
+			cost := ActionCost(candidate, state)
+			reason := fmt.Sprintf("higher cost than %s (%.2f vs %.2f)", step.Name(), cost, stepCost)
+			if unmet := unmetConditions(candidate.Preconditions(), state); len(unmet) > 0 {
+				reason = fmt.Sprintf("precondition conflict: requires %s where %s ran", strings.Join(unmet, ", "), step.Name())
+			} else if cost <= stepCost {
+				reason = fmt.Sprintf("equal or lower cost than %s (%.2f vs %.2f) but led to a costlier overall plan", step.Name(), cost, stepCost)
+			}
+
+			rejected = append(rejected, RejectedAlternative{
+				Alternative: candidate.Name(),
+				Cost:        cost,
+				Reason:      reason,
+			})
+		}
+	}
+
+	return rejected
+}
+
+// sharesEffect reports whether candidate would establish at least one fact
+// that step newly establishes in the given state.
+func sharesEffect(step, candidate Action, state WorldState) bool {
+	for key, value := range step.Effects() {
+		if state.Has(key) && stateValuesEqual(state.Get(key), value) {
+			continue
+		}
+		if effect, exists := candidate.Effects()[key]; exists && stateValuesEqual(effect, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// unmetConditions lists the conditions not satisfied by state, sorted by key.
+func unmetConditions(conditions, state WorldState) []string {
+	unmet := []string{}
+	for _, key := range sortedKeys(conditions) {
+		value := conditions[key]
+		if !state.Has(key) || !stateValuesEqual(state.Get(key), value) {
+			unmet = append(unmet, fmt.Sprintf("%s=%v", key, value))
+		}
+	}
+	return unmet
+}
+
+// planRoute renders a plan's actions as "A → B → C".
+func planRoute(plan *Plan) string {
+	if len(plan.Actions) == 0 {
+		return "(no actions)"
+	}
+	names := make([]string, len(plan.Actions))
+	for i, action := range plan.Actions {
+		names[i] = action.Name()
+	}
+	return strings.Join(names, " → ")
+}
+
+// sortedKeys returns the keys of a WorldState in sorted order.
+func sortedKeys(ws WorldState) []string {
+	keys := make([]string, 0, len(ws))
+	for key := range ws {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,99 @@
+package goap
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPlanExplanation(t *testing.T) {
+	noop := func(ctx context.Context, ws WorldState) error { return nil }
+
+	writeTests := NewSimpleAction("WriteTests", "Write tests", WorldState{"code_written": true}, WorldState{"tests_written": true}, 10.0, noop)
+	improveCoverage := NewSimpleAction("ImproveCoverage", "Iterate on coverage", WorldState{"code_written": true}, WorldState{"tests_written": true}, 20.0, noop)
+	generateTests := NewSimpleAction("GenerateTests", "Needs a spec", WorldState{"spec_written": true}, WorldState{"tests_written": true}, 1.0, noop)
+	writeCode := NewSimpleAction("WriteCode", "Write code", WorldState{}, WorldState{"code_written": true}, 5.0, noop)
+
+	planner := NewPlanner([]Action{writeTests, improveCoverage, generateTests, writeCode})
+	goal := NewGoal("Tested", "Code with tests", WorldState{"code_written": true, "tests_written": true}, 1.0)
+
+	t.Run("StepsAndCosts", func(t *testing.T) {
+		explanation, err := planner.Explain(NewWorldState(), goal)
+		if err != nil {
+			t.Fatalf("Explain failed: %v", err)
+		}
+
+		if len(explanation.Steps) != 2 {
+			t.Fatalf("Expected 2 steps, got %d", len(explanation.Steps))
+		}
+		if explanation.TotalCost != 15.0 {
+			t.Errorf("Expected total cost 15.0, got %.1f", explanation.TotalCost)
+		}
+
+		writeCodeStep := explanation.Steps[0]
+		if writeCodeStep.Action != "WriteCode" || writeCodeStep.Cost != 5.0 {
+			t.Errorf("Unexpected first step: %+v", writeCodeStep)
+		}
+		satisfies := strings.Join(writeCodeStep.Satisfies, "; ")
+		if !strings.Contains(satisfies, "precondition code_written=true of WriteTests") ||
+			!strings.Contains(satisfies, "goal code_written=true") {
+			t.Errorf("WriteCode should satisfy the precondition and goal key, got %s", satisfies)
+		}
+	})
+
+	t.Run("RejectedAlternatives", func(t *testing.T) {
+		explanation, err := planner.Explain(NewWorldState(), goal)
+		if err != nil {
+			t.Fatalf("Explain failed: %v", err)
+		}
+
+		reasons := make(map[string]string)
+		for _, rejected := range explanation.Rejected {
+			reasons[rejected.Alternative] = rejected.Reason
+		}
+
+		if !strings.HasPrefix(reasons["ImproveCoverage"], "higher cost than WriteTests") {
+			t.Errorf("ImproveCoverage should be rejected for cost, got %q", reasons["ImproveCoverage"])
+		}
+		if !strings.HasPrefix(reasons["GenerateTests"], "precondition conflict") {
+			t.Errorf("GenerateTests should be rejected for a precondition conflict, got %q", reasons["GenerateTests"])
+		}
+		if !strings.HasPrefix(reasons["WriteCode → ImproveCoverage"], "higher cost") {
+			t.Errorf("Runner-up plan should be listed, got %v", reasons)
+		}
+
+		if !strings.Contains(explanation.String(), "Rejected alternatives:") {
+			t.Error("String output should list rejected alternatives")
+		}
+	})
+
+	t.Run("HierarchicalPlanCarriesExplanation", func(t *testing.T) {
+		hp := NewHierarchicalPlanner(planner, NewMockGoalRefiner(), 3)
+		plan, err := hp.PlanHierarchical(context.Background(), NewWorldState(), goal)
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+		if plan.Explanation != nil {
+			t.Error("Plans should not be explained unless asked")
+		}
+
+		explaining := NewPlanner(planner.Actions())
+		explaining.SetExplain(true)
+		plan, err = NewHierarchicalPlanner(explaining, NewMockGoalRefiner(), 3).PlanHierarchical(context.Background(), NewWorldState(), goal)
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+		if plan.Explanation == nil {
+			t.Fatal("Atomic plan should carry an explanation")
+		}
+		if !strings.Contains(plan.Explain(), "WriteTests") {
+			t.Error("Explain should render the chosen actions")
+		}
+		if !strings.Contains(plan.Explain(), "WriteCode → ImproveCoverage") {
+			t.Errorf("Explanation should compare the plan with a runner-up, got %s", plan.Explain())
+		}
+		if len(plan.Alternatives) != 0 {
+			t.Errorf("The runner-up should not become an alternative, got %d", len(plan.Alternatives))
+		}
+	})
+}
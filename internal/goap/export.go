@@ -0,0 +1,265 @@
+package goap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// GraphFormat is a text format a PlanGraph can be exported to.
+type GraphFormat string
+
+const (
+	FormatDOT     GraphFormat = "dot"
+	FormatMermaid GraphFormat = "mermaid"
+	FormatGraphML GraphFormat = "graphml"
+)
+
+// statusColors are the fill colours of nodes in exported graphs, by status.
+var statusColors = map[NodeStatus]string{
+	StatusPending:   "#e9ecef",
+	StatusRunning:   "#ffe08a",
+	StatusCompleted: "#b7e4c7",
+	StatusFailed:    "#f4a3a3",
+	StatusSkipped:   "#cfe2ff",
+}
+
+// ExportGraph writes the graph in the given format. Nodes are coloured by
+// status and labelled with their goal, actions, cost and, once executed,
+// duration. Parent-child edges are solid; dependency edges between siblings
+// are dashed and point from the node that must complete first.
+func ExportGraph(w io.Writer, graph *PlanGraph, format GraphFormat) error {
+	switch format {
+	case FormatDOT:
+		return exportDOT(w, graph)
+	case FormatMermaid:
+		return exportMermaid(w, graph)
+	case FormatGraphML:
+		return exportGraphML(w, graph)
+	default:
+		return fmt.Errorf("unknown graph format: %s", format)
+	}
+}
+
+func exportDOT(w io.Writer, graph *PlanGraph) error {
+	var b strings.Builder
+	b.WriteString("digraph plan {\n")
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+
+	order := graph.exportOrder()
+	for _, node := range order {
+		fmt.Fprintf(&b, "  %q [label=%s, fillcolor=%q];\n",
+			node.ID, dotQuote(strings.Join(nodeLabelLines(node), "\n")), statusColor(node.Status))
+	}
+	for _, node := range order {
+		for _, childID := range node.ChildIDs {
+			fmt.Fprintf(&b, "  %q -> %q;\n", node.ID, childID)
+		}
+	}
+	for _, edge := range graph.Edges() {
+		fmt.Fprintf(&b, "  %q -> %q [style=dashed, constraint=false];\n", edge.To, edge.From)
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func exportMermaid(w io.Writer, graph *PlanGraph) error {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	order := graph.exportOrder()
+	byStatus := make(map[NodeStatus][]string)
+	for _, node := range order {
+		lines := nodeLabelLines(node)
+		for i, line := range lines {
+			lines[i] = strings.ReplaceAll(line, `"`, "#quot;")
+		}
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", node.ID, strings.Join(lines, "<br/>"))
+		byStatus[node.Status] = append(byStatus[node.Status], node.ID)
+	}
+	for _, node := range order {
+		for _, childID := range node.ChildIDs {
+			fmt.Fprintf(&b, "    %s --> %s\n", node.ID, childID)
+		}
+	}
+	for _, edge := range graph.Edges() {
+		fmt.Fprintf(&b, "    %s -.-> %s\n", edge.To, edge.From)
+	}
+
+	for _, status := range []NodeStatus{StatusPending, StatusRunning, StatusCompleted, StatusFailed, StatusSkipped} {
+		ids, exists := byStatus[status]
+		if !exists {
+			continue
+		}
+		fmt.Fprintf(&b, "    classDef %s fill:%s\n", status, statusColor(status))
+		fmt.Fprintf(&b, "    class %s %s\n", strings.Join(ids, ","), status)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func exportGraphML(w io.Writer, graph *PlanGraph) error {
+	doc := graphMLDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "goal", For: "node", AttrName: "goal", AttrType: "string"},
+			{ID: "description", For: "node", AttrName: "description", AttrType: "string"},
+			{ID: "status", For: "node", AttrName: "status", AttrType: "string"},
+			{ID: "color", For: "node", AttrName: "color", AttrType: "string"},
+			{ID: "actions", For: "node", AttrName: "actions", AttrType: "string"},
+			{ID: "cost", For: "node", AttrName: "cost", AttrType: "double"},
+			{ID: "duration_ms", For: "node", AttrName: "duration_ms", AttrType: "long"},
+			{ID: "depth", For: "node", AttrName: "depth", AttrType: "int"},
+			{ID: "atomic", For: "node", AttrName: "atomic", AttrType: "boolean"},
+			{ID: "kind", For: "edge", AttrName: "kind", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "plan", EdgeDefault: "directed"},
+	}
+
+	order := graph.exportOrder()
+	for _, node := range order {
+		data := []graphMLData{
+			{Key: "goal", Value: node.GoalName},
+			{Key: "description", Value: node.GoalDesc},
+			{Key: "status", Value: string(node.Status)},
+			{Key: "color", Value: statusColor(node.Status)},
+			{Key: "actions", Value: strings.Join(node.ActionNames, ",")},
+			{Key: "cost", Value: fmt.Sprintf("%g", node.Cost)},
+			{Key: "depth", Value: fmt.Sprintf("%d", node.Depth)},
+			{Key: "atomic", Value: fmt.Sprintf("%t", node.IsAtomic)},
+		}
+		if node.Result != nil && node.Result.Duration > 0 {
+			data = append(data, graphMLData{Key: "duration_ms", Value: fmt.Sprintf("%d", node.Result.Duration.Milliseconds())})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: node.ID, Data: data})
+	}
+	for _, node := range order {
+		for _, childID := range node.ChildIDs {
+			doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+				Source: node.ID,
+				Target: childID,
+				Data:   []graphMLData{{Key: "kind", Value: "child"}},
+			})
+		}
+	}
+	for _, edge := range graph.Edges() {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: edge.To,
+			Target: edge.From,
+			Data:   []graphMLData{{Key: "kind", Value: "dependency"}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode GraphML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// exportOrder returns the graph's nodes in depth-first order from the root,
+// followed by any nodes unreachable from it, so exports are stable and read
+// top-down.
+func (g *PlanGraph) exportOrder() []*GraphNode {
+	order := make([]*GraphNode, 0, len(g.Nodes))
+	visited := make(map[string]bool, len(g.Nodes))
+
+	var visit func(string)
+	visit = func(id string) {
+		node, exists := g.Nodes[id]
+		if !exists || visited[id] {
+			return
+		}
+		visited[id] = true
+		order = append(order, node)
+		for _, childID := range node.ChildIDs {
+			visit(childID)
+		}
+	}
+	visit(g.RootNodeID)
+	for _, id := range g.nodeIDs() {
+		visit(id)
+	}
+
+	return order
+}
+
+// nodeLabelLines returns the lines of a node's label: goal name, actions,
+// cost and status with the duration once executed.
+func nodeLabelLines(node *GraphNode) []string {
+	lines := []string{node.GoalName}
+	if len(node.ActionNames) > 0 {
+		lines = append(lines, strings.Join(node.ActionNames, " → "))
+	}
+	lines = append(lines, fmt.Sprintf("cost: %.2f", node.Cost))
+
+	status := string(node.Status)
+	if node.Result != nil && node.Result.Duration > 0 {
+		status += fmt.Sprintf(" in %s", node.Result.Duration.Round(time.Millisecond))
+	}
+	return append(lines, status)
+}
+
+func statusColor(status NodeStatus) string {
+	if color, exists := statusColors[status]; exists {
+		return color
+	}
+	return statusColors[StatusPending]
+}
+
+// dotQuote quotes a DOT label, escaping quotes and turning newlines into
+// centred line breaks.
+func dotQuote(label string) string {
+	label = strings.ReplaceAll(label, `\`, `\\`)
+	label = strings.ReplaceAll(label, `"`, `\"`)
+	label = strings.ReplaceAll(label, "\n", `\n`)
+	return `"` + label + `"`
+}
@@ -0,0 +1,112 @@
+package goap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildExportGraph builds a two-child graph with one completed, timed child.
+func buildExportGraph(t *testing.T) *PlanGraph {
+	t.Helper()
+	design := NewSimpleAction("Design", "Design", WorldState{}, WorldState{"designed": true}, 2.0, nil)
+	build := NewSimpleAction("Build", "Build", WorldState{"designed": true}, WorldState{"built": true}, 3.5, nil)
+	plan := &HierarchicalPlan{
+		Goal: NewGoal("Ship", `Ship the "feature"`, WorldState{"designed": true, "built": true}, 1.0),
+		Subplans: []*HierarchicalPlan{
+			{Goal: NewGoal("DesignIt", "Design it", WorldState{"designed": true}, 1.0), Actions: []Action{design}, Depth: 1},
+			{Goal: NewGoal("BuildIt", "Build it", WorldState{"built": true}, 1.0), Actions: []Action{build}, Depth: 1},
+		},
+	}
+
+	graph := BuildGraphFromPlan(plan, "test-agent")
+	nodeByGoal(t, graph, "DesignIt").Status = StatusCompleted
+	nodeByGoal(t, graph, "DesignIt").Result = &NodeResult{Success: true, Duration: 1500 * time.Millisecond}
+	nodeByGoal(t, graph, "BuildIt").Status = StatusFailed
+	return graph
+}
+
+func TestExportGraph(t *testing.T) {
+	graph := buildExportGraph(t)
+	ship, design, build := graph.RootNodeID, nodeByGoal(t, graph, "DesignIt").ID, nodeByGoal(t, graph, "BuildIt").ID
+
+	t.Run("Costs", func(t *testing.T) {
+		if graph.Nodes[ship].Cost != 5.5 {
+			t.Errorf("Expected the root to sum its children's cost 5.5, got %v", graph.Nodes[ship].Cost)
+		}
+	})
+
+	t.Run("DOT", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportGraph(&buf, graph, FormatDOT); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		out := buf.String()
+
+		for _, want := range []string{
+			"digraph plan {",
+			fmt.Sprintf(`"%s" -> "%s";`, ship, design),
+			fmt.Sprintf(`"%s" -> "%s" [style=dashed, constraint=false];`, design, build),
+			`fillcolor="` + statusColors[StatusCompleted] + `"`,
+			`fillcolor="` + statusColors[StatusFailed] + `"`,
+			`DesignIt\nDesign\ncost: 2.00\ncompleted in 1.5s`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected DOT to contain %s, got:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("Mermaid", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportGraph(&buf, graph, FormatMermaid); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		out := buf.String()
+
+		for _, want := range []string{
+			"flowchart TD",
+			build + `["BuildIt<br/>Build<br/>cost: 3.50<br/>failed"]`,
+			ship + " --> " + build,
+			design + " -.-> " + build,
+			"class " + design + " completed",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected Mermaid to contain %s, got:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("GraphML", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportGraph(&buf, graph, FormatGraphML); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+
+		var doc graphMLDocument
+		if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("GraphML is not valid XML: %v", err)
+		}
+		if len(doc.Graph.Nodes) != 3 {
+			t.Errorf("Expected 3 nodes, got %d", len(doc.Graph.Nodes))
+		}
+		if len(doc.Graph.Edges) != 3 {
+			t.Errorf("Expected 2 child edges and 1 dependency edge, got %d", len(doc.Graph.Edges))
+		}
+		if !strings.Contains(buf.String(), `<data key="duration_ms">1500</data>`) {
+			t.Errorf("Expected the completed node's duration, got:\n%s", buf.String())
+		}
+		if !strings.Contains(buf.String(), "Ship the &#34;feature&#34;") {
+			t.Errorf("Expected the description to be escaped, got:\n%s", buf.String())
+		}
+	})
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		if err := ExportGraph(&bytes.Buffer{}, graph, GraphFormat("svg")); err == nil {
+			t.Error("Unknown format should fail")
+		}
+	})
+}
@@ -17,6 +17,11 @@ type Goal struct {
 	// Priority indicates the importance of this goal (higher = more important)
 	// Can be used when an agent has multiple competing goals
 	priority float64
+
+	// dependsOn names sibling goals that must be achieved before this one.
+	// It is nil unless dependencies were declared, in which case siblings
+	// without dependencies may be pursued concurrently.
+	dependsOn []string
 }
 
 // NewGoal creates a new Goal with the given parameters.
@@ -49,6 +54,23 @@ func (g *Goal) Priority() float64 {
 	return g.priority
 }
 
+// SetDependsOn declares the names of sibling goals that must be achieved
+// before this one. Calling it with no names declares the goal independent.
+func (g *Goal) SetDependsOn(names ...string) {
+	g.dependsOn = append([]string{}, names...)
+}
+
+// DependsOn returns the names of sibling goals this goal depends on.
+func (g *Goal) DependsOn() []string {
+	return g.dependsOn
+}
+
+// DeclaresDependencies reports whether the goal's dependencies were declared
+// explicitly, as opposed to being implied by its position among its siblings.
+func (g *Goal) DeclaresDependencies() bool {
+	return g.dependsOn != nil
+}
+
 // IsSatisfied checks if the goal is satisfied by the current WorldState.
 func (g *Goal) IsSatisfied(current WorldState) bool {
 	return current.Matches(g.desiredState)
@@ -66,13 +88,19 @@ func (g *Goal) String() string {
 		g.name, g.description, g.desiredState, g.priority)
 }
 
-// Clone creates a copy of this goal.
+// Clone creates a copy of this goal. A goal that declares no dependencies
+// is cloned as one that does not either.
 func (g *Goal) Clone() *Goal {
+	var dependsOn []string
+	if g.dependsOn != nil {
+		dependsOn = append([]string{}, g.dependsOn...)
+	}
 	return &Goal{
 		name:         g.name,
 		description:  g.description,
 		desiredState: g.desiredState.Clone(),
 		priority:     g.priority,
+		dependsOn:    dependsOn,
 	}
 }
 
@@ -0,0 +1,379 @@
+package goap
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GoalDefinition describes a goal in a YAML or JSON file. It may be
+// parameterized: every string in it, keys included, is a Go template over
+// the parameters, e.g. "Raise coverage of {{.package}} to {{.percent}}%".
+// A templated value that renders as a number or boolean becomes one.
+type GoalDefinition struct {
+	Name        string          `yaml:"name" json:"name"`
+	Description string          `yaml:"description,omitempty" json:"description,omitempty"`
+	Priority    float64         `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Parameters  []GoalParameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	// DesiredState is matched exactly, like the desired state of any Goal
+	DesiredState WorldState `yaml:"desired_state,omitempty" json:"desired_state,omitempty"`
+	// Conditions are comparisons the goal also requires, each planned as a
+	// boolean fact checked by a ConditionAction
+	Conditions []Condition `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+	// State is merged into the initial world state, so that actions can read
+	// the parameters, e.g. which test to fix
+	State WorldState `yaml:"state,omitempty" json:"state,omitempty"`
+}
+
+// GoalParameter declares a parameter of a goal definition. Parameters
+// without a default are required.
+type GoalParameter struct {
+	Name        string  `yaml:"name" json:"name"`
+	Description string  `yaml:"description,omitempty" json:"description,omitempty"`
+	Default     *string `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// GoalInstance is a goal definition instantiated with parameters.
+type GoalInstance struct {
+	Goal *Goal
+	// State is to be merged into the initial world state
+	State WorldState
+	// Actions check the goal's conditions and must be added to the planner
+	Actions []Action
+}
+
+// Validate checks that the definition is complete and its templates parse.
+func (gd *GoalDefinition) Validate() error {
+	if gd.Name == "" {
+		return fmt.Errorf("goal has no name")
+	}
+	if len(gd.DesiredState) == 0 && len(gd.Conditions) == 0 {
+		return fmt.Errorf("goal %s has neither a desired state nor conditions", gd.Name)
+	}
+
+	declared := make(map[string]bool, len(gd.Parameters))
+	for _, parameter := range gd.Parameters {
+		if parameter.Name == "" {
+			return fmt.Errorf("goal %s has a parameter without a name", gd.Name)
+		}
+		if declared[parameter.Name] {
+			return fmt.Errorf("goal %s declares parameter %s twice", gd.Name, parameter.Name)
+		}
+		declared[parameter.Name] = true
+	}
+
+	// Rendering with placeholder values parses every template and catches
+	// references to undeclared parameters
+	placeholders := make(map[string]string, len(gd.Parameters))
+	for _, parameter := range gd.Parameters {
+		placeholders[parameter.Name] = "0"
+	}
+	rendered, err := gd.render(placeholders)
+	if err != nil {
+		return err
+	}
+	for _, condition := range rendered.Conditions {
+		if err := condition.Validate(); err != nil {
+			return fmt.Errorf("goal %s: %w", gd.Name, err)
+		}
+	}
+	return nil
+}
+
+// Instantiate renders the definition with the given parameters, which are
+// checked against the declared ones, and creates the goal and the actions
+// that check its conditions.
+func (gd *GoalDefinition) Instantiate(params map[string]string) (*GoalInstance, error) {
+	values := make(map[string]string, len(gd.Parameters))
+	declared := make(map[string]bool, len(gd.Parameters))
+	for _, parameter := range gd.Parameters {
+		declared[parameter.Name] = true
+		if value, exists := params[parameter.Name]; exists {
+			values[parameter.Name] = value
+		} else if parameter.Default != nil {
+			values[parameter.Name] = *parameter.Default
+		} else {
+			return nil, fmt.Errorf("goal %s requires parameter %s", gd.Name, parameter.Name)
+		}
+	}
+	for _, name := range sortedStringKeys(params) {
+		if !declared[name] {
+			return nil, fmt.Errorf("goal %s has no parameter %s", gd.Name, name)
+		}
+	}
+
+	rendered, err := gd.render(values)
+	if err != nil {
+		return nil, err
+	}
+
+	desiredState := rendered.DesiredState.Clone()
+	if desiredState == nil {
+		desiredState = NewWorldState()
+	}
+	actions := make([]Action, 0, len(rendered.Conditions))
+	for _, condition := range rendered.Conditions {
+		if err := condition.Validate(); err != nil {
+			return nil, fmt.Errorf("goal %s: %w", gd.Name, err)
+		}
+		desiredState.Set(condition.FactKey(), true)
+		actions = append(actions, NewConditionAction(condition))
+	}
+
+	state := rendered.State.Clone()
+	if state == nil {
+		state = NewWorldState()
+	}
+	return &GoalInstance{
+		Goal:    NewGoal(rendered.Name, rendered.Description, desiredState, rendered.Priority),
+		State:   state,
+		Actions: actions,
+	}, nil
+}
+
+// render returns a copy of the definition with every string rendered.
+func (gd *GoalDefinition) render(values map[string]string) (*GoalDefinition, error) {
+	r := &goalRenderer{goal: gd.Name, values: values}
+	rendered := &GoalDefinition{
+		Name:         r.text(gd.Name),
+		Description:  r.text(gd.Description),
+		Priority:     gd.Priority,
+		Parameters:   gd.Parameters,
+		DesiredState: r.state(gd.DesiredState),
+		State:        r.state(gd.State),
+	}
+	for _, condition := range gd.Conditions {
+		rendered.Conditions = append(rendered.Conditions, Condition{
+			Key:   r.text(condition.Key),
+			Op:    r.text(condition.Op),
+			Value: r.value(condition.Value),
+			Fact:  r.text(condition.Fact),
+			After: r.state(condition.After),
+		})
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return rendered, nil
+}
+
+// goalRenderer renders the templates of a goal definition, keeping the
+// first error.
+type goalRenderer struct {
+	goal   string
+	values map[string]string
+	err    error
+}
+
+func (r *goalRenderer) text(text string) string {
+	if r.err != nil || !strings.Contains(text, "{{") {
+		return text
+	}
+	tmpl, err := template.New(r.goal).Option("missingkey=error").Parse(text)
+	if err != nil {
+		r.err = fmt.Errorf("failed to parse template of goal %s: %w", r.goal, err)
+		return text
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r.values); err != nil {
+		r.err = fmt.Errorf("failed to render goal %s: %w", r.goal, err)
+		return text
+	}
+	return buf.String()
+}
+
+// value renders a templated string value, converting numbers and booleans.
+func (r *goalRenderer) value(value interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok || !strings.Contains(text, "{{") {
+		return value
+	}
+	rendered := r.text(text)
+	if i, err := strconv.Atoi(rendered); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(rendered, 64); err == nil {
+		return f
+	}
+	if rendered == "true" || rendered == "false" {
+		return rendered == "true"
+	}
+	return rendered
+}
+
+func (r *goalRenderer) state(state WorldState) WorldState {
+	if state == nil {
+		return nil
+	}
+	rendered := make(WorldState, len(state))
+	for key, value := range state {
+		rendered[r.text(key)] = r.value(value)
+	}
+	return rendered
+}
+
+// LoadGoalFile reads the goal definitions in a YAML or JSON file, which
+// holds either one definition or a list of them.
+func LoadGoalFile(path string) ([]GoalDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read goal file: %w", err)
+	}
+	return decodeGoalDefinitions(path, data)
+}
+
+// decodeGoalDefinitions decodes and validates the definitions in a file.
+// YAML is a superset of JSON, so both go through the YAML decoder.
+func decodeGoalDefinitions(path string, data []byte) ([]GoalDefinition, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(node.Content) == 0 {
+		return nil, nil
+	}
+
+	var definitions []GoalDefinition
+	var err error
+	if node.Content[0].Kind == yaml.SequenceNode {
+		err = node.Content[0].Decode(&definitions)
+	} else {
+		var definition GoalDefinition
+		err = node.Content[0].Decode(&definition)
+		definitions = []GoalDefinition{definition}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	for i := range definitions {
+		if err := definitions[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid goal in %s: %w", path, err)
+		}
+	}
+	return definitions, nil
+}
+
+//go:embed goals/*.yaml
+var builtinGoals embed.FS
+
+// GoalLibrary holds reusable goal definitions by name. It is safe for
+// concurrent use.
+type GoalLibrary struct {
+	mu          sync.RWMutex
+	definitions map[string]GoalDefinition
+}
+
+// NewGoalLibrary creates a library with the built-in goal definitions, such
+// as AddHTTPEndpoint, RaiseCoverage and FixFailingTest.
+func NewGoalLibrary() *GoalLibrary {
+	gl := &GoalLibrary{definitions: make(map[string]GoalDefinition)}
+
+	paths, _ := fs.Glob(builtinGoals, "goals/*.yaml")
+	for _, path := range paths {
+		data, err := builtinGoals.ReadFile(path)
+		if err != nil {
+			panic(fmt.Sprintf("failed to read built-in goal %s: %v", path, err))
+		}
+		definitions, err := decodeGoalDefinitions(path, data)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in goal: %v", err))
+		}
+		for _, definition := range definitions {
+			gl.definitions[definition.Name] = definition
+		}
+	}
+	return gl
+}
+
+// Add validates a definition and adds it, replacing any definition of the
+// same name.
+func (gl *GoalLibrary) Add(definition GoalDefinition) error {
+	if err := definition.Validate(); err != nil {
+		return err
+	}
+	gl.mu.Lock()
+	defer gl.mu.Unlock()
+	gl.definitions[definition.Name] = definition
+	return nil
+}
+
+// LoadDir adds the definitions of every .yaml, .yml and .json file in dir,
+// in file name order. Definitions replace built-in ones of the same name.
+func (gl *GoalLibrary) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read goal directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+
+		definitions, err := LoadGoalFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		for _, definition := range definitions {
+			if err := gl.Add(definition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Get returns the definition with the given name.
+func (gl *GoalLibrary) Get(name string) (GoalDefinition, bool) {
+	gl.mu.RLock()
+	defer gl.mu.RUnlock()
+	definition, exists := gl.definitions[name]
+	return definition, exists
+}
+
+// Names returns the names of all definitions, sorted.
+func (gl *GoalLibrary) Names() []string {
+	gl.mu.RLock()
+	defer gl.mu.RUnlock()
+
+	names := make([]string, 0, len(gl.definitions))
+	for name := range gl.definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Instantiate instantiates the named definition with the given parameters.
+func (gl *GoalLibrary) Instantiate(name string, params map[string]string) (*GoalInstance, error) {
+	definition, exists := gl.Get(name)
+	if !exists {
+		return nil, fmt.Errorf("goal %s not found in library (have %s)", name, strings.Join(gl.Names(), ", "))
+	}
+	return definition.Instantiate(params)
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
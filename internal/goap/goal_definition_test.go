@@ -0,0 +1,187 @@
+package goap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoalDefinitions(t *testing.T) {
+	t.Run("BuiltinLibrary", func(t *testing.T) {
+		names := NewGoalLibrary().Names()
+		for _, name := range []string{"AddHTTPEndpoint", "DeliverQualityFeature", "FixFailingTest", "RaiseCoverage"} {
+			found := false
+			for _, have := range names {
+				found = found || have == name
+			}
+			if !found {
+				t.Errorf("Expected built-in goal %s, got %v", name, names)
+			}
+		}
+	})
+
+	t.Run("InstantiatesParameters", func(t *testing.T) {
+		instance, err := NewGoalLibrary().Instantiate("RaiseCoverage", map[string]string{"percent": "75"})
+		if err != nil {
+			t.Fatalf("Failed to instantiate goal: %v", err)
+		}
+
+		if instance.Goal.Description() != "Raise the test coverage of ./... to 75%" {
+			t.Errorf("Expected rendered description, got %q", instance.Goal.Description())
+		}
+		if instance.Goal.DesiredState().Get("test_coverage_ge_75") != true {
+			t.Errorf("Expected the condition's fact in the desired state, got %v", instance.Goal.DesiredState())
+		}
+		if instance.State.Get("coverage_target") != 75 {
+			t.Errorf("Expected coverage_target to render as the number 75, got %#v", instance.State.Get("coverage_target"))
+		}
+		if len(instance.Actions) != 1 || instance.Actions[0].Name() != "Check_test_coverage_ge_75" {
+			t.Errorf("Expected one condition action, got %v", instance.Actions)
+		}
+	})
+
+	t.Run("ChecksParameters", func(t *testing.T) {
+		library := NewGoalLibrary()
+		_, err := library.Instantiate("RaiseCoverage", nil)
+		if err == nil || !strings.Contains(err.Error(), "requires parameter percent") {
+			t.Errorf("Expected missing parameter error, got %v", err)
+		}
+		_, err = library.Instantiate("RaiseCoverage", map[string]string{"percent": "75", "pkg": "x"})
+		if err == nil || !strings.Contains(err.Error(), "has no parameter pkg") {
+			t.Errorf("Expected unknown parameter error, got %v", err)
+		}
+		_, err = library.Instantiate("Missing", nil)
+		if err == nil {
+			t.Error("Expected error for unknown goal")
+		}
+	})
+
+	t.Run("RejectsUndeclaredParameters", func(t *testing.T) {
+		definition := GoalDefinition{
+			Name:         "Broken",
+			DesiredState: WorldState{"endpoint_{{.path}}_added": true},
+		}
+		if err := definition.Validate(); err == nil {
+			t.Error("Expected validation error for undeclared parameter")
+		}
+	})
+
+	t.Run("LoadsDirectory", func(t *testing.T) {
+		dir := t.TempDir()
+		yamlGoal := `
+- name: Deploy
+  description: "Deploy to {{.env}}"
+  parameters:
+    - name: env
+  desired_state:
+    deployed: true
+`
+		jsonGoal := `{"name": "RaiseCoverage", "desired_state": {"coverage_raised": true}}`
+		os.WriteFile(filepath.Join(dir, "deploy.yaml"), []byte(yamlGoal), 0644)
+		os.WriteFile(filepath.Join(dir, "coverage.json"), []byte(jsonGoal), 0644)
+		os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a goal"), 0644)
+
+		library := NewGoalLibrary()
+		if err := library.LoadDir(dir); err != nil {
+			t.Fatalf("Failed to load goals: %v", err)
+		}
+
+		instance, err := library.Instantiate("Deploy", map[string]string{"env": "staging"})
+		if err != nil {
+			t.Fatalf("Failed to instantiate goal: %v", err)
+		}
+		if instance.Goal.Description() != "Deploy to staging" {
+			t.Errorf("Expected rendered description, got %q", instance.Goal.Description())
+		}
+
+		// Files replace built-in goals of the same name
+		instance, err = library.Instantiate("RaiseCoverage", nil)
+		if err != nil {
+			t.Fatalf("Failed to instantiate overriding goal: %v", err)
+		}
+		if !instance.Goal.DesiredState().Matches(WorldState{"coverage_raised": true}) {
+			t.Errorf("Expected overriding goal, got %v", instance.Goal.DesiredState())
+		}
+	})
+}
+
+func TestConditions(t *testing.T) {
+	coverage := Condition{Key: "test_coverage", Op: ">=", Value: 75, After: WorldState{"tests_run": true}}
+
+	t.Run("Holds", func(t *testing.T) {
+		cases := []struct {
+			condition Condition
+			state     WorldState
+			expected  bool
+		}{
+			{coverage, WorldState{"test_coverage": 80.5}, true},
+			{coverage, WorldState{"test_coverage": 60.0}, false},
+			{Condition{Key: "status", Op: "==", Value: "green"}, WorldState{"status": "green"}, true},
+			{Condition{Key: "count", Op: "!=", Value: 3}, WorldState{"count": 3.0}, false},
+		}
+		for _, c := range cases {
+			holds, err := c.condition.Holds(c.state)
+			if err != nil {
+				t.Errorf("Unexpected error for %s: %v", c.condition, err)
+			}
+			if holds != c.expected {
+				t.Errorf("Expected %s to be %v in %v, got %v", c.condition, c.expected, c.state, holds)
+			}
+		}
+
+		if _, err := coverage.Holds(WorldState{}); err == nil {
+			t.Error("Expected error for missing key")
+		}
+		if _, err := coverage.Holds(WorldState{"test_coverage": "high"}); err == nil {
+			t.Error("Expected error for non-numeric value")
+		}
+	})
+
+	t.Run("PlannedAsFact", func(t *testing.T) {
+		runTests := NewSimpleAction("RunTests", "Run tests", WorldState{}, WorldState{"tests_run": true}, 1.0, func(ctx context.Context, ws WorldState) error {
+			ws.Set("test_coverage", 80.0)
+			return nil
+		})
+		check := NewConditionAction(coverage)
+
+		goal := NewGoal("Covered", "Covered", WorldState{coverage.FactKey(): true}, 1.0)
+		plan := NewPlanner([]Action{check, runTests}).FindPlan(NewWorldState(), goal)
+		if plan == nil || len(plan.Actions) != 2 || plan.Actions[1].Name() != check.Name() {
+			t.Fatalf("Expected RunTests then the check, got %v", plan)
+		}
+
+		state := NewWorldState()
+		for _, action := range plan.Actions {
+			if err := action.Execute(context.Background(), state); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if !goal.IsSatisfied(state) {
+			t.Errorf("Expected goal to be satisfied, got %v", state)
+		}
+
+		state.Set("test_coverage", 50.0)
+		if err := check.Execute(context.Background(), state); err == nil {
+			t.Error("Expected the check to fail when the condition does not hold")
+		}
+		if state.Get(coverage.FactKey()) != false {
+			t.Errorf("Expected the fact to be false, got %v", state.Get(coverage.FactKey()))
+		}
+	})
+
+	t.Run("Serializable", func(t *testing.T) {
+		spec, err := ActionSpecOf(NewConditionAction(coverage))
+		if err != nil {
+			t.Fatalf("Expected ConditionAction to be serializable, got %v", err)
+		}
+		rebuilt, err := BuildConditionAction(spec.Params)
+		if err != nil {
+			t.Fatalf("Failed to rebuild condition action: %v", err)
+		}
+		if rebuilt.Name() != "Check_test_coverage_ge_75" {
+			t.Errorf("Expected the same fact after a round trip, got %s", rebuilt.Name())
+		}
+	})
+}
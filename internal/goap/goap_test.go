@@ -2,7 +2,10 @@ package goap
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestWorldState(t *testing.T) {
@@ -69,6 +72,58 @@ func TestWorldState(t *testing.T) {
 			t.Errorf("Expected distance 2, got %d", distance)
 		}
 	})
+
+	t.Run("Deleted And Unknown Effects", func(t *testing.T) {
+		ws := WorldState{"a": 1, "b": 2}
+		ws.Apply(WorldState{"a": Deleted, "b": Unknown, "c": 3})
+
+		if ws.Has("a") {
+			t.Error("Deleted effect should remove the key")
+		}
+		if ws.Matches(WorldState{"b": 2}) {
+			t.Error("Unknown value should not satisfy the old condition")
+		}
+		if !ws.Matches(WorldState{"b": Unknown, "c": 3}) {
+			t.Error("Unknown value should only match Unknown")
+		}
+
+		ws.Invalidate([]string{"c", "missing"})
+		if ws.Has("c") {
+			t.Error("Invalidate should remove the key")
+		}
+	})
+
+	t.Run("Markers Survive Round Trips", func(t *testing.T) {
+		effects := WorldState{"a": Deleted, "b": Unknown, "c": "<text>"}
+
+		data, _ := json.Marshal(effects)
+		var fromJSON WorldState
+		if err := json.Unmarshal(data, &fromJSON); err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %v", err)
+		}
+		data, _ = yaml.Marshal(effects)
+		var fromYAML WorldState
+		if err := yaml.Unmarshal(data, &fromYAML); err != nil {
+			t.Fatalf("Failed to unmarshal YAML: %v", err)
+		}
+
+		// Plain maps, such as decoded action specs, hold the strings
+		plain := WorldState{"a": "<deleted>", "b": "<unknown>", "c": "<text>"}
+
+		for name, decoded := range map[string]WorldState{"json": fromJSON, "yaml": fromYAML, "plain": plain} {
+			ws := WorldState{"a": 1, "b": 2}
+			ws.Apply(decoded)
+			if ws.Has("a") {
+				t.Errorf("Expected %s Deleted to remove the key, got %v", name, ws)
+			}
+			if !ws.Matches(WorldState{"b": Unknown, "c": "<text>"}) {
+				t.Errorf("Expected %s Unknown to be the marker, got %#v", name, ws.Get("b"))
+			}
+		}
+		if !fromJSON.Matches(WorldState{"b": Unknown}) || !fromYAML.Matches(WorldState{"b": Unknown}) {
+			t.Error("Expected decoded states to hold the markers")
+		}
+	})
 }
 
 func TestGoal(t *testing.T) {
@@ -107,6 +162,25 @@ func TestGoal(t *testing.T) {
 			t.Error("Goal should be satisfied")
 		}
 	})
+
+	t.Run("Clone Dependencies", func(t *testing.T) {
+		goal := NewGoal("TestGoal", "Test", NewWorldState(), 1.0)
+		if goal.Clone().DeclaresDependencies() {
+			t.Error("Clone of a goal without declared dependencies should declare none")
+		}
+
+		goal.SetDependsOn()
+		if !goal.Clone().DeclaresDependencies() {
+			t.Error("Clone should keep an empty dependency declaration")
+		}
+
+		goal.SetDependsOn("Design", "Build")
+		clone := goal.Clone()
+		clone.DependsOn()[0] = "Changed"
+		if goal.DependsOn()[0] != "Design" {
+			t.Errorf("Expected the original's dependencies to be unchanged, got %v", goal.DependsOn())
+		}
+	})
 }
 
 func TestSimpleAction(t *testing.T) {
@@ -281,6 +355,127 @@ func TestPlanner(t *testing.T) {
 			t.Error("Should return nil when no plan exists")
 		}
 	})
+
+	t.Run("K-Best Plans", func(t *testing.T) {
+		noop := func(ctx context.Context, ws WorldState) error { return nil }
+		cheap := NewSimpleAction("Cheap", "Cheap route", NewWorldState(), WorldState{"done": true}, 1.0, noop)
+		medium := NewSimpleAction("Medium", "Medium route", NewWorldState(), WorldState{"done": true}, 3.0, noop)
+		expensive := NewSimpleAction("Expensive", "Expensive route", NewWorldState(), WorldState{"done": true}, 5.0, noop)
+
+		planner := NewPlanner([]Action{expensive, cheap, medium})
+		goal := NewGoal("Done", "Get it done", WorldState{"done": true}, 1.0)
+
+		plans := planner.FindPlans(NewWorldState(), goal, 2)
+		if len(plans) != 2 {
+			t.Fatalf("Expected 2 plans, got %d", len(plans))
+		}
+		if plans[0].Actions[0].Name() != "Cheap" {
+			t.Errorf("Expected Cheap first, got %s", plans[0].Actions[0].Name())
+		}
+		if plans[1].Actions[0].Name() != "Medium" {
+			t.Errorf("Expected Medium second, got %s", plans[1].Actions[0].Name())
+		}
+		if plans[0].Cost > plans[1].Cost {
+			t.Errorf("Plans should be ranked by cost, got %.1f then %.1f", plans[0].Cost, plans[1].Cost)
+		}
+
+		all := planner.FindPlans(NewWorldState(), goal, 10)
+		if len(all) != 3 {
+			t.Errorf("Expected all 3 plans when k exceeds alternatives, got %d", len(all))
+		}
+	})
+
+	t.Run("Diverse Plans", func(t *testing.T) {
+		noop := func(ctx context.Context, ws WorldState) error { return nil }
+		build := NewSimpleAction("Build", "Build", NewWorldState(), WorldState{"built": true}, 1.0, noop)
+		lint := NewSimpleAction("Lint", "Lint", NewWorldState(), WorldState{"linted": true}, 1.0, noop)
+		notify := NewSimpleAction("Notify", "Side effect only", NewWorldState(), WorldState{"notified": true}, 0.5, noop)
+		buildAll := NewSimpleAction("BuildAll", "Build and lint", NewWorldState(), WorldState{"built": true, "linted": true}, 4.0, noop)
+
+		planner := NewPlanner([]Action{build, lint, notify, buildAll})
+		goal := NewGoal("Ready", "Built and linted", WorldState{"built": true, "linted": true}, 1.0)
+
+		plans := planner.FindPlans(NewWorldState(), goal, 3)
+		if len(plans) < 2 {
+			t.Fatalf("Expected several plans without diversity, got %d", len(plans))
+		}
+		if len(plans[1].Actions) != 2 {
+			t.Errorf("Without diversity the reordered Build/Lint plan should rank second, got %s", plans[1])
+		}
+
+		planner.SetDiverse(true)
+		plans = planner.FindPlans(NewWorldState(), goal, 2)
+		if len(plans) != 2 {
+			t.Fatalf("Expected 2 diverse plans, got %d", len(plans))
+		}
+		if len(plans[1].Actions) != 1 || plans[1].Actions[0].Name() != "BuildAll" {
+			t.Errorf("Expected BuildAll as the diverse alternative, got %s", plans[1])
+		}
+	})
+
+	t.Run("Dynamic Costs", func(t *testing.T) {
+		noop := func(ctx context.Context, ws WorldState) error { return nil }
+		summarize := NewSimpleAction("Summarize", "LLM summary", NewWorldState(), WorldState{"summary": true}, 1.0, noop)
+		summarize.SetCostFunc(func(state WorldState) float64 {
+			if state.Get("ticket_large") == true {
+				return 20.0
+			}
+			return 1.0
+		})
+		skim := NewSimpleAction("Skim", "Heuristic summary", NewWorldState(), WorldState{"summary": true}, 5.0, noop)
+
+		planner := NewPlanner([]Action{summarize, skim})
+		goal := NewGoal("Summarized", "Have a summary", WorldState{"summary": true}, 1.0)
+
+		plan := planner.FindPlan(WorldState{"ticket_large": false}, goal)
+		if plan == nil || plan.Actions[0].Name() != "Summarize" {
+			t.Fatalf("Expected Summarize for a small ticket, got %v", plan)
+		}
+		if plan.Cost != 1.0 {
+			t.Errorf("Expected cost 1.0, got %.1f", plan.Cost)
+		}
+
+		plan = planner.FindPlan(WorldState{"ticket_large": true}, goal)
+		if plan == nil || plan.Actions[0].Name() != "Skim" {
+			t.Fatalf("Expected Skim for a large ticket, got %v", plan)
+		}
+
+		if ActionCost(summarize.Clone(), WorldState{"ticket_large": true}) != 20.0 {
+			t.Error("Clone should preserve the cost function")
+		}
+		if summarize.Cost() != 1.0 {
+			t.Errorf("Static cost should remain the fallback, got %.1f", summarize.Cost())
+		}
+	})
+
+	t.Run("Invalidating Actions", func(t *testing.T) {
+		noop := func(ctx context.Context, ws WorldState) error { return nil }
+		runTests := NewSimpleAction("RunTests", "Run tests", WorldState{"code_written": true}, WorldState{"tests_passed": true}, 1.0, noop)
+		editCode := NewSimpleAction("EditCode", "Edit code", WorldState{"code_written": true}, WorldState{"code_edited": true}, 1.0, noop)
+		editCode.SetInvalidates("tests_passed")
+
+		planner := NewPlanner([]Action{runTests, editCode})
+		goal := NewGoal("EditedAndTested", "Edit then verify", WorldState{"code_edited": true, "tests_passed": true}, 1.0)
+
+		current := WorldState{"code_written": true, "tests_passed": true}
+		plan := planner.FindPlan(current, goal)
+		if plan == nil {
+			t.Fatal("Planner should find a plan")
+		}
+		if len(plan.Actions) != 2 || plan.Actions[0].Name() != "EditCode" || plan.Actions[1].Name() != "RunTests" {
+			t.Fatalf("Expected EditCode then RunTests, got %s", plan)
+		}
+
+		if err := editCode.Execute(context.Background(), current); err != nil {
+			t.Fatalf("EditCode failed: %v", err)
+		}
+		if current.Has("tests_passed") {
+			t.Error("Executing EditCode should invalidate tests_passed")
+		}
+		if len(editCode.Clone().(*SimpleAction).Invalidates()) != 1 {
+			t.Error("Clone should preserve the invalidation list")
+		}
+	})
 }
 
 func TestCompositeAction(t *testing.T) {
@@ -0,0 +1,280 @@
+package goap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// JournalFile is the name of the execution journal in a run directory.
+const JournalFile = "journal.jsonl"
+
+// JournalKind identifies what a journal entry records.
+type JournalKind string
+
+const (
+	// JournalPlanDecision records the action sequence the planner chose for
+	// an atomic goal, its cost and the alternatives it rejected.
+	JournalPlanDecision JournalKind = "plan_decision"
+
+	// JournalRefinementDecision records the subgoals a goal was refined into.
+	JournalRefinementDecision JournalKind = "refinement_decision"
+
+	// JournalRefinementQuery records a refinement prompt sent to the LLM and
+	// its raw response.
+	JournalRefinementQuery JournalKind = "refinement_query"
+
+	// JournalActionInvoked records an action about to execute, with the
+	// values of the state keys its preconditions read.
+	JournalActionInvoked JournalKind = "action_invoked"
+
+	// JournalActionFinished records an executed action's declared effects,
+	// timing and error.
+	JournalActionFinished JournalKind = "action_finished"
+
+	// JournalStateDelta records how the WorldState changed while a node
+	// executed.
+	JournalStateDelta JournalKind = "state_delta"
+
+	// JournalNodeFinished records a node's final status, timing and error.
+	JournalNodeFinished JournalKind = "node_finished"
+
+	// JournalReplan records a replan of a failed atomic node.
+	JournalReplan JournalKind = "replan"
+)
+
+// JournalEntry is one line of the execution journal. Seq increases by one
+// for every entry of a run, including across resumed executions.
+type JournalEntry struct {
+	Seq       int64                  `json:"seq"`
+	Timestamp time.Time              `json:"timestamp"`
+	Kind      JournalKind            `json:"kind"`
+	NodeID    string                 `json:"node_id,omitempty"`
+	GoalName  string                 `json:"goal_name,omitempty"`
+	Action    string                 `json:"action,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Duration  time.Duration          `json:"duration,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// Journal is an append-only JSONL audit trail of a run. Unlike the plan
+// graph, which status updates overwrite, entries are never changed once
+// written, so a run's timeline can be reconstructed from the journal alone.
+// It is safe for concurrent use.
+type Journal struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  int64
+}
+
+// OpenJournal opens the journal at path for appending, creating it if
+// needed. Sequence numbers continue from the last entry already in the file.
+func OpenJournal(path string) (*Journal, error) {
+	entries, err := ReadJournal(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	// Drop a line truncated by a crash, so that new entries do not follow a
+	// corrupt line that would make the journal unreadable
+	if err := truncatePartialLine(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to repair journal: %w", err)
+	}
+
+	journal := &Journal{file: file}
+	if len(entries) > 0 {
+		journal.seq = entries[len(entries)-1].Seq
+	}
+	return journal, nil
+}
+
+// truncatePartialLine truncates a file after its last newline.
+func truncatePartialLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+
+	data := make([]byte, info.Size())
+	if _, err := file.ReadAt(data, 0); err != nil {
+		return err
+	}
+	end := int64(bytes.LastIndexByte(data, '\n') + 1)
+	if end == info.Size() {
+		return nil
+	}
+	log.Warn("Dropping partial journal line left by a crash", "path", file.Name(), "bytes", info.Size()-end)
+	return file.Truncate(end)
+}
+
+// Record appends an entry, assigning its sequence number and, if unset, its
+// timestamp. Each entry is synced to disk before Record returns.
+func (j *Journal) Record(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.seq++
+	entry.Seq = j.seq
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		j.seq--
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+
+	_, err = j.file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return j.file.Sync()
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// ReadJournal reads every entry of the journal at path, in order. A
+// truncated last line, left by a crash mid-write, is ignored.
+func ReadJournal(path string) ([]JournalEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []JournalEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	var pending error
+	for scanner.Scan() {
+		if pending != nil {
+			return nil, pending
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			pending = fmt.Errorf("failed to unmarshal journal entry after seq %d: %w", len(entries), err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// OpenJournal opens the journal in a run's directory.
+func (gp *GraphPersistence) OpenJournal(runID string) (*Journal, error) {
+	return OpenJournal(filepath.Join(gp.basePath, runID, JournalFile))
+}
+
+// ReadJournal reads every entry of a run's journal.
+func (gp *GraphPersistence) ReadJournal(runID string) ([]JournalEntry, error) {
+	return ReadJournal(filepath.Join(gp.basePath, runID, JournalFile))
+}
+
+type journalContextKey struct{}
+
+// WithJournal returns a context carrying a journal, so that code without
+// access to the run, such as goal refiners, can record entries in it.
+func WithJournal(ctx context.Context, journal *Journal) context.Context {
+	return context.WithValue(ctx, journalContextKey{}, journal)
+}
+
+// JournalFromContext returns the journal carried by ctx, or nil.
+func JournalFromContext(ctx context.Context) *Journal {
+	journal, _ := ctx.Value(journalContextKey{}).(*Journal)
+	return journal
+}
+
+// recordJournal records an entry in the journal carried by ctx, if any.
+// Journal failures are logged, never returned, so that auditing cannot fail
+// a run.
+func recordJournal(ctx context.Context, entry JournalEntry) {
+	journal := JournalFromContext(ctx)
+	if journal == nil {
+		return
+	}
+	if err := journal.Record(entry); err != nil {
+		log.Warn("Failed to record journal entry", "kind", entry.Kind, "error", err)
+	}
+}
+
+// journalPlan records the refinement and planning decisions of a
+// hierarchical plan, depth first.
+func journalPlan(ctx context.Context, plan *HierarchicalPlan) {
+	if plan.IsAtomic() {
+		data := map[string]interface{}{
+			"desired_state": plan.Goal.DesiredState(),
+			"actions":       actionNames(plan.Actions),
+			"cost":          plan.Cost,
+		}
+		if plan.Explanation != nil {
+			data["rejected"] = plan.Explanation.Rejected
+		}
+		alternatives := [][]string{}
+		for _, alternative := range plan.Alternatives {
+			alternatives = append(alternatives, actionNames(alternative.Actions))
+		}
+		if len(alternatives) > 0 {
+			data["alternatives"] = alternatives
+		}
+		recordJournal(ctx, JournalEntry{Kind: JournalPlanDecision, GoalName: plan.Goal.Name(), Data: data})
+		return
+	}
+
+	subgoals := make([]map[string]interface{}, 0, len(plan.Subplans))
+	for _, subplan := range plan.Subplans {
+		subgoals = append(subgoals, map[string]interface{}{
+			"name":          subplan.Goal.Name(),
+			"desired_state": subplan.Goal.DesiredState(),
+			"depends_on":    subplan.Goal.DependsOn(),
+		})
+	}
+	recordJournal(ctx, JournalEntry{
+		Kind:     JournalRefinementDecision,
+		GoalName: plan.Goal.Name(),
+		Data:     map[string]interface{}{"desired_state": plan.Goal.DesiredState(), "subgoals": subgoals},
+	})
+
+	for _, subplan := range plan.Subplans {
+		journalPlan(ctx, subplan)
+	}
+}
+
+func actionNames(actions []Action) []string {
+	names := make([]string, len(actions))
+	for i, action := range actions {
+		names[i] = action.Name()
+	}
+	return names
+}
@@ -0,0 +1,150 @@
+package goap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	t.Run("SequenceNumbersContinueAfterReopen", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "run", JournalFile)
+
+		journal, err := OpenJournal(path)
+		if err != nil {
+			t.Fatalf("Failed to open journal: %v", err)
+		}
+		journal.Record(JournalEntry{Kind: JournalNodeFinished, NodeID: "node_1"})
+		journal.Record(JournalEntry{Kind: JournalNodeFinished, NodeID: "node_2"})
+		journal.Close()
+
+		journal, err = OpenJournal(path)
+		if err != nil {
+			t.Fatalf("Failed to reopen journal: %v", err)
+		}
+		journal.Record(JournalEntry{Kind: JournalNodeFinished, NodeID: "node_3"})
+		journal.Close()
+
+		entries, err := ReadJournal(path)
+		if err != nil {
+			t.Fatalf("Failed to read journal: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("Expected 3 entries, got %d", len(entries))
+		}
+		for i, entry := range entries {
+			if entry.Seq != int64(i+1) {
+				t.Errorf("Expected seq %d, got %d", i+1, entry.Seq)
+			}
+			if entry.Timestamp.IsZero() {
+				t.Error("Expected entries to be timestamped")
+			}
+		}
+	})
+
+	t.Run("TruncatedLastLine", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), JournalFile)
+
+		journal, _ := OpenJournal(path)
+		journal.Record(JournalEntry{Kind: JournalNodeFinished})
+		journal.Close()
+
+		// Simulate a crash in the middle of writing an entry
+		f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		f.Write([]byte(`{"seq":2,"kin`))
+		f.Close()
+
+		journal, err := OpenJournal(path)
+		if err != nil {
+			t.Fatalf("A truncated last line should be tolerated, got %v", err)
+		}
+		journal.Record(JournalEntry{Kind: JournalNodeFinished})
+		journal.Close()
+
+		// The journal stays readable and reopenable after appending
+		journal, err = OpenJournal(path)
+		if err != nil {
+			t.Fatalf("Failed to reopen journal after appending: %v", err)
+		}
+		journal.Close()
+
+		entries, err := ReadJournal(path)
+		if err != nil {
+			t.Fatalf("Failed to read journal: %v", err)
+		}
+		if len(entries) != 2 || entries[1].Seq != 2 {
+			t.Errorf("Expected the partial line to be replaced by entry 2, got %v", entries)
+		}
+
+		// A corrupt line in the middle is not a crash and still fails
+		data, _ := os.ReadFile(path)
+		os.WriteFile(path, append([]byte("{corrupt\n"), data...), 0644)
+		if _, err := ReadJournal(path); err == nil {
+			t.Error("A corrupt line followed by entries should fail to read")
+		}
+	})
+
+	t.Run("ExecutionIsJournaled", func(t *testing.T) {
+		persistence := NewGraphPersistence(t.TempDir())
+		runID := "test-journal"
+
+		action := NewSimpleAction("Build", "Build", WorldState{"designed": true}, WorldState{"built": true}, 2.0,
+			func(ctx context.Context, ws WorldState) error {
+				ws.Set("built", true)
+				return nil
+			})
+		plan := &HierarchicalPlan{
+			Goal:    NewGoal("BuildIt", "Build it", WorldState{"built": true}, 1.0),
+			Actions: []Action{action},
+		}
+		if err := persistence.SaveGraph(BuildGraphFromPlan(plan, "test-agent"), runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		journal, err := persistence.OpenJournal(runID)
+		if err != nil {
+			t.Fatalf("Failed to open journal: %v", err)
+		}
+		ctx := WithJournal(context.Background(), journal)
+		journalPlan(ctx, plan)
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterAction(action)
+		if err := executor.Execute(ctx, WorldState{"designed": true}); err != nil {
+			t.Fatalf("Execution failed: %v", err)
+		}
+		journal.Close()
+
+		entries, err := persistence.ReadJournal(runID)
+		if err != nil {
+			t.Fatalf("Failed to read journal: %v", err)
+		}
+
+		kinds := []JournalKind{}
+		for _, entry := range entries {
+			kinds = append(kinds, entry.Kind)
+		}
+		expected := []JournalKind{JournalPlanDecision, JournalActionInvoked, JournalActionFinished, JournalStateDelta, JournalNodeFinished}
+		if len(kinds) != len(expected) {
+			t.Fatalf("Expected %v, got %v", expected, kinds)
+		}
+		for i := range expected {
+			if kinds[i] != expected[i] {
+				t.Fatalf("Expected %v, got %v", expected, kinds)
+			}
+		}
+
+		inputs := entries[1].Data["inputs"].(map[string]interface{})
+		if inputs["designed"] != true {
+			t.Errorf("Expected the action's inputs to be recorded, got %v", entries[1].Data)
+		}
+		changes := entries[3].Data["changes"].(map[string]interface{})
+		if changes["built"] != true {
+			t.Errorf("Expected the state delta to record built, got %v", entries[3].Data)
+		}
+		if entries[4].Data["status"] != string(StatusCompleted) || entries[4].Duration <= 0 {
+			t.Errorf("Unexpected node entry %+v", entries[4])
+		}
+	})
+}
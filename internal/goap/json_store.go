@@ -0,0 +1,245 @@
+package goap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"upside-down-research.com/oss/agentic/internal/fsutil"
+)
+
+// JSONGraphStore stores each run's graph as JSON files in the run directory:
+// graph/plan_graph.json with the full graph and graph/nodes/<id>.json with
+// each node's minimal context.
+type JSONGraphStore struct {
+	basePath string
+	mu       sync.Mutex
+}
+
+// NewJSONGraphStore creates a JSON graph store rooted at basePath.
+func NewJSONGraphStore(basePath string) *JSONGraphStore {
+	return &JSONGraphStore{
+		basePath: basePath,
+	}
+}
+
+// SaveGraph writes the graph file and every node file.
+func (s *JSONGraphStore) SaveGraph(graph *PlanGraph, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.writeGraph(graph, runID)
+	if err != nil {
+		return err
+	}
+
+	nodeIDs := make([]string, 0, len(graph.Nodes))
+	for nodeID := range graph.Nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	err = s.writeNodeContexts(graph, runID, nodeIDs)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Plan graph saved", "path", s.graphDir(runID), "nodes", len(graph.Nodes))
+	return nil
+}
+
+// LoadGraph reads the graph file.
+func (s *JSONGraphStore) LoadGraph(runID string) (*PlanGraph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadGraph(runID)
+}
+
+func (s *JSONGraphStore) loadGraph(runID string) (*PlanGraph, error) {
+	graphPath := filepath.Join(s.graphDir(runID), "plan_graph.json")
+
+	data, err := os.ReadFile(graphPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read graph file: %w", err)
+	}
+
+	var graph PlanGraph
+	err = json.Unmarshal(data, &graph)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal graph: %w", err)
+	}
+
+	log.Info("Plan graph loaded", "nodes", len(graph.Nodes))
+	return &graph, nil
+}
+
+// LoadNodeContext reads a node file.
+func (s *JSONGraphStore) LoadNodeContext(runID, nodeID string) (*NodeContext, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nodePath := filepath.Join(s.graphDir(runID), "nodes", nodeID+".json")
+
+	data, err := os.ReadFile(nodePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node context: %w", err)
+	}
+
+	var context NodeContext
+	err = json.Unmarshal(data, &context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal node context: %w", err)
+	}
+
+	return &context, nil
+}
+
+// UpdateNode updates a node in the graph file and rewrites only the node
+// files whose context includes the node: its own, its parent's, its
+// children's and its siblings'.
+func (s *JSONGraphStore) UpdateNode(runID, nodeID string, update func(node *GraphNode) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	graph, err := s.loadGraph(runID)
+	if err != nil {
+		return err
+	}
+
+	node, exists := graph.Nodes[nodeID]
+	if !exists {
+		return fmt.Errorf("node not found: %s", nodeID)
+	}
+
+	err = update(node)
+	if err != nil {
+		return err
+	}
+
+	err = s.writeGraph(graph, runID)
+	if err != nil {
+		return err
+	}
+
+	affected := []string{nodeID}
+	affected = append(affected, node.ChildIDs...)
+	if parent, exists := graph.Nodes[node.ParentID]; exists {
+		affected = append(affected, parent.ID)
+		for _, siblingID := range parent.ChildIDs {
+			if siblingID != nodeID {
+				affected = append(affected, siblingID)
+			}
+		}
+	}
+	return s.writeNodeContexts(graph, runID, affected)
+}
+
+// UpdateGraph updates the whole graph and rewrites every file.
+func (s *JSONGraphStore) UpdateGraph(runID string, update func(graph *PlanGraph) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	graph, err := s.loadGraph(runID)
+	if err != nil {
+		return err
+	}
+
+	err = update(graph)
+	if err != nil {
+		return err
+	}
+
+	err = s.writeGraph(graph, runID)
+	if err != nil {
+		return err
+	}
+
+	nodeIDs := make([]string, 0, len(graph.Nodes))
+	for nodeID := range graph.Nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	return s.writeNodeContexts(graph, runID, nodeIDs)
+}
+
+// Runs lists the run directories that contain a graph file.
+func (s *JSONGraphStore) Runs() ([]string, error) {
+	entries, err := os.ReadDir(s.basePath)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs directory: %w", err)
+	}
+
+	runIDs := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.graphDir(entry.Name()), "plan_graph.json")); err == nil {
+			runIDs = append(runIDs, entry.Name())
+		}
+	}
+	sort.Strings(runIDs)
+	return runIDs, nil
+}
+
+// Close does nothing; the JSON store holds no open resources.
+func (s *JSONGraphStore) Close() error {
+	return nil
+}
+
+func (s *JSONGraphStore) graphDir(runID string) string {
+	return filepath.Join(s.basePath, runID, "graph")
+}
+
+// writeGraph writes the graph file.
+func (s *JSONGraphStore) writeGraph(graph *PlanGraph, runID string) error {
+	graphDir := s.graphDir(runID)
+	err := os.MkdirAll(graphDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create graph directory: %w", err)
+	}
+
+	graphJSON, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal graph: %w", err)
+	}
+
+	err = fsutil.WriteFileAtomic(filepath.Join(graphDir, "plan_graph.json"), graphJSON, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write graph file: %w", err)
+	}
+
+	return nil
+}
+
+// writeNodeContexts writes the node files of the given nodes for minimal
+// context loading.
+func (s *JSONGraphStore) writeNodeContexts(graph *PlanGraph, runID string, nodeIDs []string) error {
+	nodesDir := filepath.Join(s.graphDir(runID), "nodes")
+	err := os.MkdirAll(nodesDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create nodes directory: %w", err)
+	}
+
+	for _, nodeID := range nodeIDs {
+		nodeContext := buildNodeContext(graph, nodeID)
+		nodePath := filepath.Join(nodesDir, nodeID+".json")
+		nodeJSON, err := json.MarshalIndent(nodeContext, "", "  ")
+		if err != nil {
+			log.Error("Failed to marshal node context", "nodeID", nodeID, "error", err)
+			continue
+		}
+
+		err = fsutil.WriteFileAtomic(nodePath, nodeJSON, 0644)
+		if err != nil {
+			log.Error("Failed to write node file", "nodeID", nodeID, "error", err)
+			continue
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/log"
@@ -18,6 +19,10 @@ type LLMGoalRefiner struct {
 	jobname     string
 	agentID     string
 	atomicTypes map[string]bool // Types of goals that are atomic (cannot be refined)
+	actions     []Action        // Vocabulary refinements are grounded in (nil disables grounding)
+	maxAttempts int             // Queries per refinement before giving up on invalid responses
+	cache       *RefinementCache
+	version     string
 }
 
 // NewLLMGoalRefiner creates a new LLM-based goal refiner.
@@ -33,9 +38,51 @@ func NewLLMGoalRefiner(llmServer llm.Server, jobname, agentID string) *LLMGoalRe
 			"llm_prompt":      true,
 			"simple_action":   true,
 		},
+		maxAttempts: defaultRefinementAttempts,
+		version:     LLMRefinerVersion,
 	}
 }
 
+// LLMRefinerVersion is part of every refinement cache key. Bump it whenever
+// the refinement prompt or validation changes, so that refinements cached by
+// older versions are no longer used.
+const LLMRefinerVersion = "llm-refiner/1"
+
+// defaultRefinementAttempts is how many times a refinement is requested
+// before invalid responses are given up on.
+const defaultRefinementAttempts = 3
+
+// SetActions grounds refinement in the actions available to the planner. The
+// prompt then lists their preconditions and effects, and every subgoal's
+// desired state must be produced by one of them or already hold. Without
+// actions, only the parsing and coverage of refinements are checked.
+func (r *LLMGoalRefiner) SetActions(actions []Action) {
+	r.actions = actions
+}
+
+// SetCache makes the refiner answer from the cache when it has a refinement
+// of the same goal in the same relevant state, and store the refinements it
+// gets from the LLM. Reruns then plan without querying the LLM and refine
+// goals the same way every time.
+func (r *LLMGoalRefiner) SetCache(cache *RefinementCache) {
+	r.cache = cache
+}
+
+// SetVersion overrides LLMRefinerVersion in cache keys, e.g. to tell apart
+// refiners with different configurations sharing a cache.
+func (r *LLMGoalRefiner) SetVersion(version string) {
+	r.version = version
+}
+
+// SetMaxAttempts sets how many times a refinement is requested, each time
+// with feedback on the problems of the previous response, before giving up.
+func (r *LLMGoalRefiner) SetMaxAttempts(attempts int) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	r.maxAttempts = attempts
+}
+
 // AddAtomicType registers a goal type as atomic (cannot be refined further).
 func (r *LLMGoalRefiner) AddAtomicType(goalType string) {
 	r.atomicTypes[goalType] = true
@@ -65,13 +112,135 @@ func (r *LLMGoalRefiner) IsAtomic(goal *Goal, current WorldState) bool {
 	return false
 }
 
-// Refine uses the LLM to decompose a goal into subgoals.
+// Refine uses the LLM to decompose a goal into subgoals, or the cached
+// refinement of the goal if there is one.
 func (r *LLMGoalRefiner) Refine(ctx context.Context, goal *Goal, current WorldState) ([]*Goal, error) {
+	var key RefinementCacheKey
+	if r.cache != nil {
+		key = r.cacheKey(goal, current)
+		if refinement, hit := r.cache.Get(key); hit {
+			log.Info("Using cached refinement", "goal", goal.Name(), "numSubgoals", len(refinement.Subgoals))
+			recordJournal(ctx, JournalEntry{
+				Kind:     JournalRefinementQuery,
+				GoalName: goal.Name(),
+				Data:     map[string]interface{}{"cache_key": key.Hash(), "cached": true},
+			})
+			return r.subgoals(goal, current, refinement), nil
+		}
+	}
+
 	log.Info("Refining goal with LLM", "goal", goal.Name())
 
-	prompt := r.buildRefinementPrompt(goal, current)
+	refinement, err := r.refine(ctx, goal, current, r.buildRefinementPrompt(goal, current))
+	if err != nil {
+		return nil, err
+	}
+	r.store(key, refinement)
+	return r.subgoals(goal, current, refinement), nil
+}
+
+// RefineWithFeedback asks the LLM for a different decomposition of a goal
+// whose previous decomposition failed, including the failure in the prompt.
+// The cache is bypassed, and the new refinement replaces the cached one, so
+// that later runs do not repeat the failed decomposition.
+func (r *LLMGoalRefiner) RefineWithFeedback(ctx context.Context, goal *Goal, current WorldState, failure string) ([]*Goal, error) {
+	log.Info("Re-refining goal with LLM", "goal", goal.Name(), "failure", failure)
+
+	prompt := r.buildRefinementPrompt(goal, current) + fmt.Sprintf(`
+
+A previous attempt to achieve this goal failed:
+%s
 
-	// Query the LLM
+Propose a different decomposition that avoids this failure.`, failure)
+
+	refinement, err := r.refine(ctx, goal, current, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if r.cache != nil {
+		r.store(r.cacheKey(goal, current), refinement)
+	}
+	return r.subgoals(goal, current, refinement), nil
+}
+
+// store caches a refinement, if there is a cache. Failing to cache does not
+// fail the refinement.
+func (r *LLMGoalRefiner) store(key RefinementCacheKey, refinement *GoalRefinement) {
+	if r.cache == nil {
+		return
+	}
+	if err := r.cache.Put(key, refinement); err != nil {
+		log.Warn("Failed to cache refinement", "goal", key.GoalName, "error", err)
+	}
+}
+
+// cacheKey builds the cache key of a refinement request. The relevant state
+// keys are those of the goal's desired state and, when grounded, those the
+// actions read or write; the version covers the prompt, the model and the
+// action vocabulary.
+func (r *LLMGoalRefiner) cacheKey(goal *Goal, current WorldState) RefinementCacheKey {
+	relevant := make(map[string]bool)
+	for key := range goal.DesiredState() {
+		relevant[key] = true
+	}
+	for _, action := range r.actions {
+		for key := range action.Preconditions() {
+			relevant[key] = true
+		}
+		for key := range action.Effects() {
+			relevant[key] = true
+		}
+	}
+	keys := make([]string, 0, len(relevant))
+	for key := range relevant {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	version := r.version
+	if r.llm != nil {
+		version += "|" + r.llm.Model()
+	}
+	if len(r.actions) > 0 {
+		version += "|" + vocabularyFingerprint(r.actions)
+	}
+
+	return RefinementCacheKey{
+		GoalName:        goal.Name(),
+		GoalDescription: goal.Description(),
+		DesiredState:    map[string]interface{}(goal.DesiredState().Clone()),
+		State:           relevantState(current, keys),
+		RefinerVersion:  version,
+	}
+}
+
+// refine queries the LLM with a refinement prompt, parses and validates its
+// subgoals, and re-prompts with the problems found until a response is valid
+// or the attempts run out.
+func (r *LLMGoalRefiner) refine(ctx context.Context, goal *Goal, current WorldState, prompt string) (*GoalRefinement, error) {
+	feedback := ""
+	for attempt := 1; ; attempt++ {
+		refinement, problems, err := r.query(ctx, goal, current, prompt+feedback, attempt)
+		if err != nil {
+			return nil, err
+		}
+		if len(problems) == 0 {
+			log.Info("Goal refined successfully", "goal", goal.Name(), "numSubgoals", len(refinement.Subgoals), "attempts", attempt)
+			return refinement, nil
+		}
+
+		log.Warn("LLM refinement is invalid", "goal", goal.Name(), "attempt", attempt, "problems", len(problems))
+		if attempt >= r.maxAttempts {
+			return nil, fmt.Errorf("refinement of %s still invalid after %d attempts: %s", goal.Name(), attempt, strings.Join(problems, "; "))
+		}
+		feedback = buildRefinementFeedback(problems)
+	}
+}
+
+// query sends one refinement prompt and returns the parsed refinement and
+// its problems. Unparseable responses are problems too, so that they are
+// re-prompted; only a failing LLM is an error.
+func (r *LLMGoalRefiner) query(ctx context.Context, goal *Goal, current WorldState, prompt string, attempt int) (*GoalRefinement, []string, error) {
 	response, err := llm.AnswerMe(&llm.AnswerMeParams{
 		LLM:     r.llm,
 		Jobname: r.jobname,
@@ -79,24 +248,41 @@ func (r *LLMGoalRefiner) Refine(ctx context.Context, goal *Goal, current WorldSt
 		Query:   prompt,
 	})
 
+	entry := JournalEntry{
+		Kind:     JournalRefinementQuery,
+		GoalName: goal.Name(),
+		Data:     map[string]interface{}{"prompt": prompt, "response": response, "attempt": attempt},
+	}
 	if err != nil {
-		return nil, fmt.Errorf("LLM query failed: %w", err)
+		entry.Error = err.Error()
+		recordJournal(ctx, entry)
+		return nil, nil, fmt.Errorf("LLM query failed: %w", err)
 	}
 
-	// Parse the response
 	var refinement GoalRefinement
-	err = json.Unmarshal([]byte(response), &refinement)
-	if err != nil {
+	var problems []string
+	if err := json.Unmarshal([]byte(response), &refinement); err != nil {
 		log.Error("Failed to parse LLM response", "error", err, "response", response)
-		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+		problems = []string{fmt.Sprintf("the response is not valid JSON: %v", err)}
+	} else {
+		problems = r.validateRefinement(goal, current, &refinement)
 	}
+	if len(problems) > 0 {
+		entry.Data["problems"] = problems
+	}
+	recordJournal(ctx, entry)
+
+	return &refinement, problems, nil
+}
 
-	// Convert the refinement to Goal objects
+// subgoals converts a refinement to Goal objects, with numbers typed as
+// typedValue types them.
+func (r *LLMGoalRefiner) subgoals(goal *Goal, current WorldState, refinement *GoalRefinement) []*Goal {
 	subgoals := make([]*Goal, 0, len(refinement.Subgoals))
 	for i, subgoalSpec := range refinement.Subgoals {
 		desiredState := NewWorldState()
 		for key, value := range subgoalSpec.DesiredState {
-			desiredState.Set(key, value)
+			desiredState.Set(key, r.typedValue(goal, current, key, value))
 		}
 
 		subgoal := NewGoal(
@@ -106,11 +292,160 @@ func (r *LLMGoalRefiner) Refine(ctx context.Context, goal *Goal, current WorldSt
 			float64(len(refinement.Subgoals)-i), // Earlier subgoals have higher priority
 		)
 
+		if refinement.declaresDependencies() {
+			subgoal.SetDependsOn(subgoalSpec.DependsOn...)
+		}
+
 		subgoals = append(subgoals, subgoal)
 	}
+	return subgoals
+}
+
+// validateRefinement returns the problems of a refinement: subgoals without
+// a desired state or with unknown dependencies, desired values no action
+// produces (when grounded in actions), and parent desired values that no
+// subgoal covers.
+func (r *LLMGoalRefiner) validateRefinement(goal *Goal, current WorldState, refinement *GoalRefinement) []string {
+	if len(refinement.Subgoals) == 0 {
+		return []string{"the response contains no subgoals"}
+	}
+
+	problems := []string{}
+	names := make(map[string]bool, len(refinement.Subgoals))
+	for _, subgoal := range refinement.Subgoals {
+		names[subgoal.Name] = true
+	}
+
+	for _, subgoal := range refinement.Subgoals {
+		if len(subgoal.DesiredState) == 0 {
+			problems = append(problems, fmt.Sprintf("subgoal %q has an empty desired_state", subgoal.Name))
+		}
+		for _, dependency := range subgoal.DependsOn {
+			if !names[dependency] {
+				problems = append(problems, fmt.Sprintf("subgoal %q depends on %q, which is not one of the subgoals", subgoal.Name, dependency))
+			}
+		}
+		if r.actions == nil {
+			continue
+		}
+		for _, key := range sortedKeys(subgoal.DesiredState) {
+			value := r.typedValue(goal, current, key, subgoal.DesiredState[key])
+			if current.Has(key) && stateValuesEqual(current.Get(key), value) {
+				continue
+			}
+			if problem := r.unproducible(key, value); problem != "" {
+				problems = append(problems, fmt.Sprintf("subgoal %q desires %s=%v, but %s", subgoal.Name, key, value, problem))
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(goal.DesiredState()) {
+		value := goal.DesiredState()[key]
+		if current.Has(key) && stateValuesEqual(current.Get(key), value) {
+			continue
+		}
+		covered := false
+		for _, subgoal := range refinement.Subgoals {
+			desired, exists := subgoal.DesiredState[key]
+			covered = covered || (exists && stateValuesEqual(r.typedValue(goal, current, key, desired), value))
+		}
+		if !covered {
+			problems = append(problems, fmt.Sprintf("no subgoal desires %s=%v, which the goal requires", key, value))
+		}
+	}
+	return problems
+}
+
+// unproducible explains why no action sets key to value, or returns "" if
+// one does.
+func (r *LLMGoalRefiner) unproducible(key string, value interface{}) string {
+	var others []string
+	for _, action := range r.actions {
+		effect, exists := action.Effects()[key]
+		if !exists {
+			continue
+		}
+		if stateValuesEqual(effect, value) {
+			return ""
+		}
+		others = append(others, fmt.Sprintf("%v (%s)", effect, action.Name()))
+	}
+	if len(others) > 0 {
+		return fmt.Sprintf("actions only set %s to %s", key, strings.Join(others, ", "))
+	}
+	return fmt.Sprintf("no action produces %s", key)
+}
+
+// typedValue gives a number from a refinement the type the same number has
+// for the key in the goal, the state or an action effect. JSON decodes every
+// number as float64, while the planner matches values exactly, so a desired
+// float64(80) would never match an effect of int 80.
+func (r *LLMGoalRefiner) typedValue(goal *Goal, current WorldState, key string, value interface{}) interface{} {
+	number, ok := value.(float64)
+	if !ok {
+		return value
+	}
 
-	log.Info("Goal refined successfully", "goal", goal.Name(), "numSubgoals", len(subgoals))
-	return subgoals, nil
+	var candidates []interface{}
+	if goal.DesiredState().Has(key) {
+		candidates = append(candidates, goal.DesiredState().Get(key))
+	}
+	if current.Has(key) {
+		candidates = append(candidates, current.Get(key))
+	}
+	for _, action := range r.actions {
+		if effect, exists := action.Effects()[key]; exists {
+			candidates = append(candidates, effect)
+		}
+	}
+	for _, candidate := range candidates {
+		if typed, ok := toFloat(candidate); ok && typed == number {
+			return candidate
+		}
+	}
+	return value
+}
+
+// buildRefinementFeedback turns the problems of a response into a prompt
+// suffix asking for a corrected one.
+func buildRefinementFeedback(problems []string) string {
+	var b strings.Builder
+	b.WriteString("\n\nYour previous response was rejected because:\n")
+	for _, problem := range problems {
+		fmt.Fprintf(&b, "- %s\n", problem)
+	}
+	b.WriteString("\nFix these problems and respond again with the complete JSON object.")
+	return b.String()
+}
+
+// buildActionVocabulary describes the available actions and the state
+// values they produce, or returns "" when refinement is not grounded.
+func (r *LLMGoalRefiner) buildActionVocabulary() string {
+	if len(r.actions) == 0 {
+		return ""
+	}
+
+	actions := append([]Action(nil), r.actions...)
+	sort.SliceStable(actions, func(i, j int) bool { return actions[i].Name() < actions[j].Name() })
+
+	var b strings.Builder
+	b.WriteString("\nAvailable Actions (the only way the world state changes):\n")
+	produced := make(map[string]bool)
+	var producible []string
+	for _, action := range actions {
+		fmt.Fprintf(&b, "- %s: %s\n    requires: %s\n    produces: %s\n",
+			action.Name(), action.Description(), action.Preconditions().String(), action.Effects().String())
+		for _, key := range sortedKeys(action.Effects()) {
+			pair := fmt.Sprintf("%s=%v", key, action.Effects()[key])
+			if !produced[pair] {
+				produced[pair] = true
+				producible = append(producible, pair)
+			}
+		}
+	}
+	sort.Strings(producible)
+	fmt.Fprintf(&b, "\nProducible State: %s\n", strings.Join(producible, ", "))
+	return b.String()
 }
 
 func (r *LLMGoalRefiner) buildRefinementPrompt(goal *Goal, current WorldState) string {
@@ -123,13 +458,13 @@ Goal to Achieve:
 Name: %s
 Description: %s
 Desired State: %s
-
+%s
 Instructions:
 1. Analyze the current state and the goal
 2. Break down the goal into a logical sequence of subgoals
 3. Each subgoal should be simpler and more concrete than the parent goal
 4. Subgoals should be ordered such that achieving them in sequence accomplishes the parent goal
-5. Consider dependencies between subgoals (earlier subgoals may be prerequisites for later ones)
+5. Declare dependencies between subgoals: list in depends_on the names of the subgoals that must be achieved first
 
 Respond with a JSON object in this format:
 {
@@ -141,41 +476,71 @@ Respond with a JSON object in this format:
       "desired_state": {
         "key1": "value1",
         "key2": "value2"
-      }
+      },
+      "depends_on": []
     },
     {
       "name": "Subgoal2Name",
       "description": "What this subgoal accomplishes",
       "desired_state": {
         "key3": "value3"
-      }
+      },
+      "depends_on": ["Subgoal1Name"]
     }
   ]
 }
 
 Important:
-- The subgoals should be ordered sequentially
+- The subgoals should be ordered sequentially, with dependencies before the subgoals that need them
+- Subgoals that do not depend on each other (e.g. independent modules) will be executed in parallel, so only declare real dependencies
 - Each subgoal's desired_state should represent a meaningful intermediate state
 - Make subgoals concrete and achievable
 - Aim for 2-5 subgoals (avoid over-decomposition)
+- Together, the subgoals' desired_state must include every key/value of the goal's desired state that does not already hold%s
 
 Return ONLY valid JSON, starting with '{' and ending with '}'.`,
 		current.String(),
 		goal.Name(),
 		goal.Description(),
 		goal.DesiredState().String(),
+		r.buildActionVocabulary(),
+		r.groundingInstruction(),
 	)
 }
 
+// groundingInstruction asks for producible desired states when refinement
+// is grounded in actions.
+func (r *LLMGoalRefiner) groundingInstruction() string {
+	if len(r.actions) == 0 {
+		return ""
+	}
+	return "\n- Only use desired_state key/value pairs listed under Producible State, or that already hold in the current state; do not invent keys"
+}
+
 // GoalRefinement represents the LLM's response when refining a goal.
 type GoalRefinement struct {
 	Rationale string        `json:"rationale"`
 	Subgoals  []SubgoalSpec `json:"subgoals"`
 }
 
+// declaresDependencies reports whether any subgoal declares a depends_on
+// list. If none does, the subgoals are treated as a sequence.
+func (gr *GoalRefinement) declaresDependencies() bool {
+	for _, subgoal := range gr.Subgoals {
+		if subgoal.DependsOn != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // SubgoalSpec represents a subgoal specification from the LLM.
 type SubgoalSpec struct {
 	Name         string                 `json:"name"`
 	Description  string                 `json:"description"`
 	DesiredState map[string]interface{} `json:"desired_state"`
+
+	// DependsOn names the subgoals that must be achieved before this one.
+	// Subgoals without dependencies may be executed concurrently.
+	DependsOn []string `json:"depends_on"`
 }
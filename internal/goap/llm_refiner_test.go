@@ -0,0 +1,157 @@
+package goap
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"upside-down-research.com/oss/agentic/internal/llm"
+)
+
+// scriptedLLM answers queries with canned responses, in order, and records
+// the prompts it was sent.
+type scriptedLLM struct {
+	responses []string
+	prompts   []string
+}
+
+func (s *scriptedLLM) Completion(query *llm.Query) (string, error) {
+	s.prompts = append(s.prompts, query.Messages[len(query.Messages)-1].Content)
+	if len(s.responses) == 0 {
+		return "", nil
+	}
+	response := s.responses[0]
+	s.responses = s.responses[1:]
+	return response, nil
+}
+
+func (s *scriptedLLM) Model() string {
+	return "scripted"
+}
+
+func TestLLMGoalRefinerGrounding(t *testing.T) {
+	noop := func(ctx context.Context, ws WorldState) error { return nil }
+	actions := []Action{
+		NewSimpleAction("WriteCode", "Write the code", WorldState{}, WorldState{"code_written": true}, 1.0, noop),
+		NewSimpleAction("RunTests", "Run the tests", WorldState{"code_written": true}, WorldState{"tests_passed": true}, 1.0, noop),
+	}
+	goal := NewGoal("Deliver", "Deliver tested code", WorldState{"code_written": true, "tests_passed": true}, 1.0)
+
+	valid := `{"subgoals": [
+		{"name": "Write", "description": "Write", "desired_state": {"code_written": true}},
+		{"name": "Test", "description": "Test", "desired_state": {"tests_passed": true}, "depends_on": ["Write"]}
+	]}`
+
+	t.Run("PromptListsVocabulary", func(t *testing.T) {
+		server := &scriptedLLM{responses: []string{valid}}
+		refiner := NewLLMGoalRefiner(server, "job", "agent")
+		refiner.SetActions(actions)
+
+		subgoals, err := refiner.Refine(context.Background(), goal, NewWorldState())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(subgoals) != 2 {
+			t.Errorf("Expected 2 subgoals, got %d", len(subgoals))
+		}
+
+		prompt := server.prompts[0]
+		for _, expected := range []string{"RunTests: Run the tests", "requires: {code_written: true}", "Producible State: code_written=true, tests_passed=true"} {
+			if !strings.Contains(prompt, expected) {
+				t.Errorf("Expected prompt to contain %q", expected)
+			}
+		}
+	})
+
+	t.Run("RepromptsWithFeedback", func(t *testing.T) {
+		invented := `{"subgoals": [
+			{"name": "Write", "description": "Write", "desired_state": {"code_written": true}},
+			{"name": "Review", "description": "Review", "desired_state": {"code_reviewed": true, "code_written": false}}
+		]}`
+		server := &scriptedLLM{responses: []string{"not json", invented, valid}}
+		refiner := NewLLMGoalRefiner(server, "job", "agent")
+		refiner.SetActions(actions)
+
+		subgoals, err := refiner.Refine(context.Background(), goal, NewWorldState())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(subgoals) != 2 || len(server.prompts) != 3 {
+			t.Fatalf("Expected a valid refinement on the third attempt, got %d subgoals after %d prompts", len(subgoals), len(server.prompts))
+		}
+
+		if !strings.Contains(server.prompts[1], "not valid JSON") {
+			t.Errorf("Expected feedback on the unparseable response, got %s", server.prompts[1])
+		}
+		feedback := server.prompts[2]
+		for _, expected := range []string{
+			`"Review" desires code_reviewed=true, but no action produces code_reviewed`,
+			`"Review" desires code_written=false, but actions only set code_written to true (WriteCode)`,
+			"no subgoal desires tests_passed=true",
+		} {
+			if !strings.Contains(feedback, expected) {
+				t.Errorf("Expected feedback to contain %q, got %s", expected, feedback)
+			}
+		}
+	})
+
+	t.Run("GivesUpAfterMaxAttempts", func(t *testing.T) {
+		partial := `{"subgoals": [{"name": "Write", "description": "Write", "desired_state": {"code_written": true}}]}`
+		server := &scriptedLLM{responses: []string{partial, partial}}
+		refiner := NewLLMGoalRefiner(server, "job", "agent")
+		refiner.SetActions(actions)
+		refiner.SetMaxAttempts(2)
+
+		_, err := refiner.Refine(context.Background(), goal, NewWorldState())
+		if err == nil || !strings.Contains(err.Error(), "still invalid after 2 attempts") {
+			t.Errorf("Expected error after 2 attempts, got %v", err)
+		}
+	})
+
+	t.Run("HoldingValuesNeedNoCoverage", func(t *testing.T) {
+		testOnly := `{"subgoals": [{"name": "Test", "description": "Test", "desired_state": {"tests_passed": true}}]}`
+		server := &scriptedLLM{responses: []string{testOnly}}
+		refiner := NewLLMGoalRefiner(server, "job", "agent")
+		refiner.SetActions(actions)
+
+		_, err := refiner.Refine(context.Background(), goal, WorldState{"code_written": true})
+		if err != nil {
+			t.Errorf("Expected values that already hold to need no subgoal, got %v", err)
+		}
+	})
+
+	t.Run("NumbersMatchEffectTypes", func(t *testing.T) {
+		measure := NewSimpleAction("Measure", "Measure coverage", WorldState{"code_written": true}, WorldState{"coverage": 80}, 1.0, noop)
+		grounded := append(append([]Action{}, actions...), measure)
+		covered := NewGoal("Covered", "Covered code", WorldState{"code_written": true, "coverage": 80}, 1.0)
+
+		response := `{"subgoals": [
+			{"name": "Write", "description": "Write", "desired_state": {"code_written": true}},
+			{"name": "Measure", "description": "Measure", "desired_state": {"coverage": 80}, "depends_on": ["Write"]}
+		]}`
+		server := &scriptedLLM{responses: []string{response}}
+		refiner := NewLLMGoalRefiner(server, "job", "agent")
+		refiner.SetActions(grounded)
+
+		subgoals, err := refiner.Refine(context.Background(), covered, NewWorldState())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if value := subgoals[1].DesiredState().Get("coverage"); value != 80 {
+			t.Errorf("Expected the JSON number to become the effect's int 80, got %#v", value)
+		}
+		if NewPlanner(grounded).FindPlan(WorldState{"code_written": true}, subgoals[1]) == nil {
+			t.Error("Expected the planner to find a plan for the grounded subgoal")
+		}
+
+		// A number no effect has is still rejected
+		wrong := strings.Replace(response, `{"coverage": 80}`, `{"coverage": 80.5}`, 1)
+		server = &scriptedLLM{responses: []string{wrong}}
+		refiner = NewLLMGoalRefiner(server, "job", "agent")
+		refiner.SetActions(grounded)
+		refiner.SetMaxAttempts(1)
+		if _, err := refiner.Refine(context.Background(), covered, NewWorldState()); err == nil {
+			t.Error("Expected a number no action produces to be rejected")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package goap
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MemoryGraphStore keeps graphs in memory, e.g. for dry runs that must not
+// touch a run's persisted graph. Graphs are stored as JSON so that callers
+// see the same value types as with the persistent stores.
+type MemoryGraphStore struct {
+	mu     sync.Mutex
+	graphs map[string][]byte
+}
+
+// NewMemoryGraphStore creates an empty in-memory graph store.
+func NewMemoryGraphStore() *MemoryGraphStore {
+	return &MemoryGraphStore{
+		graphs: make(map[string][]byte),
+	}
+}
+
+// SaveGraph stores a copy of the graph.
+func (s *MemoryGraphStore) SaveGraph(graph *PlanGraph, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.putGraph(graph, runID)
+}
+
+// LoadGraph returns a copy of the run's graph.
+func (s *MemoryGraphStore) LoadGraph(runID string) (*PlanGraph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getGraph(runID)
+}
+
+// LoadNodeContext builds the node's context from a copy of the run's graph.
+func (s *MemoryGraphStore) LoadNodeContext(runID, nodeID string) (*NodeContext, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	graph, err := s.getGraph(runID)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := graph.Nodes[nodeID]; !exists {
+		return nil, fmt.Errorf("node not found: %s", nodeID)
+	}
+	return buildNodeContext(graph, nodeID), nil
+}
+
+// UpdateNode applies update to a single node of the run's graph.
+func (s *MemoryGraphStore) UpdateNode(runID, nodeID string, update func(node *GraphNode) error) error {
+	return s.UpdateGraph(runID, func(graph *PlanGraph) error {
+		node, exists := graph.Nodes[nodeID]
+		if !exists {
+			return fmt.Errorf("node not found: %s", nodeID)
+		}
+		return update(node)
+	})
+}
+
+// UpdateGraph applies update to a copy of the run's graph and stores it if
+// update succeeds.
+func (s *MemoryGraphStore) UpdateGraph(runID string, update func(graph *PlanGraph) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	graph, err := s.getGraph(runID)
+	if err != nil {
+		return err
+	}
+	if err := update(graph); err != nil {
+		return err
+	}
+	return s.putGraph(graph, runID)
+}
+
+// Runs lists the stored run IDs, sorted.
+func (s *MemoryGraphStore) Runs() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runIDs := make([]string, 0, len(s.graphs))
+	for runID := range s.graphs {
+		runIDs = append(runIDs, runID)
+	}
+	sort.Strings(runIDs)
+	return runIDs, nil
+}
+
+// Close does nothing; the graphs are discarded with the store.
+func (s *MemoryGraphStore) Close() error {
+	return nil
+}
+
+func (s *MemoryGraphStore) putGraph(graph *PlanGraph, runID string) error {
+	data, err := json.Marshal(graph)
+	if err != nil {
+		return fmt.Errorf("failed to marshal graph: %w", err)
+	}
+	s.graphs[runID] = data
+	return nil
+}
+
+func (s *MemoryGraphStore) getGraph(runID string) (*PlanGraph, error) {
+	data, exists := s.graphs[runID]
+	if !exists {
+		return nil, fmt.Errorf("graph not found for run %s", runID)
+	}
+
+	var graph PlanGraph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal graph: %w", err)
+	}
+	return &graph, nil
+}
@@ -0,0 +1,333 @@
+package goap
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Method is a hand-authored decomposition of a goal, in the style of HTN
+// planning: when the goal is pursued in a state where the method applies,
+// it is refined into the method's subgoals. Methods are defined in Go or in
+// YAML or JSON files.
+type Method struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Goal is the name of the goal the method decomposes
+	Goal string `yaml:"goal" json:"goal"`
+	// When holds values the state must have for the method to apply
+	When WorldState `yaml:"when,omitempty" json:"when,omitempty"`
+	// Conditions are comparisons that must also hold; a condition on a
+	// missing key does not hold
+	Conditions []Condition `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+	// Applicable is an additional check for methods defined in Go
+	Applicable func(goal *Goal, current WorldState) bool `yaml:"-" json:"-"`
+	// Ordered makes each subgoal depend on the one before it. Otherwise
+	// subgoals are partially ordered by their DependsOn, and subgoals without
+	// dependencies may be pursued concurrently.
+	Ordered  bool              `yaml:"ordered,omitempty" json:"ordered,omitempty"`
+	Subgoals []SubgoalTemplate `yaml:"subgoals" json:"subgoals"`
+}
+
+// SubgoalTemplate describes a subgoal of a method. Its strings, keys
+// included, are Go templates over the world state the goal is refined in,
+// e.g. "Reproduce {{.failing_test}}". A templated value that renders as a
+// number or boolean becomes one.
+type SubgoalTemplate struct {
+	Name         string     `yaml:"name" json:"name"`
+	Description  string     `yaml:"description,omitempty" json:"description,omitempty"`
+	DesiredState WorldState `yaml:"desired_state" json:"desired_state"`
+	DependsOn    []string   `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+}
+
+// Validate checks that the method is complete, its templates parse and its
+// subgoal dependencies are acyclic.
+func (m *Method) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("method has no name")
+	}
+	if m.Goal == "" {
+		return fmt.Errorf("method %s has no goal", m.Name)
+	}
+	if len(m.Subgoals) == 0 {
+		return fmt.Errorf("method %s has no subgoals", m.Name)
+	}
+	for _, condition := range m.Conditions {
+		if err := condition.Validate(); err != nil {
+			return fmt.Errorf("method %s: %w", m.Name, err)
+		}
+	}
+
+	// Placeholder goals check names and dependencies the way planning will
+	placeholders := make([]*Goal, len(m.Subgoals))
+	for i, subgoal := range m.Subgoals {
+		if subgoal.Name == "" {
+			return fmt.Errorf("method %s has a subgoal without a name", m.Name)
+		}
+		if len(subgoal.DesiredState) == 0 {
+			return fmt.Errorf("subgoal %s of method %s has no desired state", subgoal.Name, m.Name)
+		}
+		texts := []string{subgoal.Name, subgoal.Description}
+		for key, value := range subgoal.DesiredState {
+			texts = append(texts, key)
+			if text, ok := value.(string); ok {
+				texts = append(texts, text)
+			}
+		}
+		for _, text := range texts {
+			if _, err := template.New(m.Name).Parse(text); err != nil {
+				return fmt.Errorf("failed to parse template of method %s: %w", m.Name, err)
+			}
+		}
+		placeholders[i] = NewGoal(subgoal.Name, subgoal.Description, subgoal.DesiredState, 1.0)
+	}
+	m.order(placeholders)
+	if _, err := orderSubgoals(placeholders); err != nil {
+		return fmt.Errorf("method %s: %w", m.Name, err)
+	}
+	return nil
+}
+
+// Applies reports whether the method decomposes the goal in the state.
+func (m *Method) Applies(goal *Goal, current WorldState) bool {
+	if m.Goal != goal.Name() || !current.Matches(m.When) {
+		return false
+	}
+	for _, condition := range m.Conditions {
+		holds, err := condition.Holds(current)
+		if err != nil || !holds {
+			return false
+		}
+	}
+	return m.Applicable == nil || m.Applicable(goal, current)
+}
+
+// Instantiate renders the method's subgoals in the state and declares their
+// dependencies.
+func (m *Method) Instantiate(goal *Goal, current WorldState) ([]*Goal, error) {
+	values := make(map[string]string, len(current))
+	for key, value := range current {
+		values[key] = fmt.Sprint(value)
+	}
+	r := &goalRenderer{goal: m.Name, values: values}
+
+	subgoals := make([]*Goal, len(m.Subgoals))
+	for i, subgoal := range m.Subgoals {
+		subgoals[i] = NewGoal(r.text(subgoal.Name), r.text(subgoal.Description), r.state(subgoal.DesiredState), goal.Priority())
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	m.order(subgoals)
+	return subgoals, nil
+}
+
+// order declares the dependencies of a method's subgoals, whose names must
+// not be templated when they are depended on.
+func (m *Method) order(subgoals []*Goal) {
+	for i, subgoal := range m.Subgoals {
+		dependsOn := subgoal.DependsOn
+		if m.Ordered && len(dependsOn) == 0 && i > 0 {
+			dependsOn = []string{subgoals[i-1].Name()}
+		}
+		subgoals[i].SetDependsOn(dependsOn...)
+	}
+}
+
+// LoadMethodFile reads the methods in a YAML or JSON file, which holds
+// either one method or a list of them.
+func LoadMethodFile(path string) ([]Method, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read method file: %w", err)
+	}
+	return decodeMethods(path, data)
+}
+
+// decodeMethods decodes and validates the methods in a file.
+func decodeMethods(path string, data []byte) ([]Method, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(node.Content) == 0 {
+		return nil, nil
+	}
+
+	var methods []Method
+	var err error
+	if node.Content[0].Kind == yaml.SequenceNode {
+		err = node.Content[0].Decode(&methods)
+	} else {
+		var method Method
+		err = node.Content[0].Decode(&method)
+		methods = []Method{method}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	for i := range methods {
+		if err := methods[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid method in %s: %w", path, err)
+		}
+	}
+	return methods, nil
+}
+
+//go:embed methods/*.yaml
+var builtinMethods embed.FS
+
+// MethodRefiner refines goals with hand-authored methods, which gives
+// predictable plans for well-understood workflows. The methods of a goal are
+// tried in the order they were added and the first that applies is used.
+// Goals no method applies to are passed to the fallback refiner, typically
+// an LLMGoalRefiner; without one they are atomic. It is safe for concurrent
+// use.
+type MethodRefiner struct {
+	mu       sync.RWMutex
+	methods  []Method
+	fallback GoalRefiner
+}
+
+// NewMethodRefiner creates a refiner with the built-in methods, such as the
+// decomposition of DeliverQualityFeature. The fallback may be nil.
+func NewMethodRefiner(fallback GoalRefiner) *MethodRefiner {
+	mr := &MethodRefiner{fallback: fallback}
+
+	paths, _ := fs.Glob(builtinMethods, "methods/*.yaml")
+	for _, path := range paths {
+		data, err := builtinMethods.ReadFile(path)
+		if err != nil {
+			panic(fmt.Sprintf("failed to read built-in method %s: %v", path, err))
+		}
+		methods, err := decodeMethods(path, data)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in method: %v", err))
+		}
+		mr.methods = append(mr.methods, methods...)
+	}
+	return mr
+}
+
+// Add validates a method and adds it, replacing any method of the same name
+// in place.
+func (mr *MethodRefiner) Add(method Method) error {
+	if err := method.Validate(); err != nil {
+		return err
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	for i := range mr.methods {
+		if mr.methods[i].Name == method.Name {
+			mr.methods[i] = method
+			return nil
+		}
+	}
+	mr.methods = append(mr.methods, method)
+	return nil
+}
+
+// LoadDir adds the methods of every .yaml, .yml and .json file in dir, in
+// file name order. Methods replace built-in ones of the same name.
+func (mr *MethodRefiner) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read method directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+
+		methods, err := LoadMethodFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			if err := mr.Add(method); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Methods returns the methods in the order they are tried.
+func (mr *MethodRefiner) Methods() []Method {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	return append([]Method{}, mr.methods...)
+}
+
+// Method returns the first method that applies to the goal in the state.
+func (mr *MethodRefiner) Method(goal *Goal, current WorldState) (*Method, bool) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	for i := range mr.methods {
+		if mr.methods[i].Applies(goal, current) {
+			method := mr.methods[i]
+			return &method, true
+		}
+	}
+	return nil, false
+}
+
+// Refine decomposes the goal with the first applicable method, or with the
+// fallback refiner if no method applies.
+func (mr *MethodRefiner) Refine(ctx context.Context, goal *Goal, current WorldState) ([]*Goal, error) {
+	method, found := mr.Method(goal, current)
+	if !found {
+		if mr.fallback == nil {
+			return nil, nil
+		}
+		log.Info("No method applies, using fallback refiner", "goal", goal.Name())
+		return mr.fallback.Refine(ctx, goal, current)
+	}
+
+	log.Info("Refining goal with method", "goal", goal.Name(), "method", method.Name)
+	subgoals, err := method.Instantiate(goal, current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate method %s: %w", method.Name, err)
+	}
+	return subgoals, nil
+}
+
+// RefineWithFeedback refines a goal whose decomposition failed. The failure
+// is passed to the fallback refiner if it takes feedback, since applying the
+// same method again would repeat the failure; otherwise the goal is refined
+// as usual.
+func (mr *MethodRefiner) RefineWithFeedback(ctx context.Context, goal *Goal, current WorldState, failure string) ([]*Goal, error) {
+	if feedbackRefiner, ok := mr.fallback.(FeedbackRefiner); ok {
+		log.Info("Re-refining goal with fallback refiner", "goal", goal.Name())
+		return feedbackRefiner.RefineWithFeedback(ctx, goal, current, failure)
+	}
+	return mr.Refine(ctx, goal, current)
+}
+
+// IsAtomic reports goals a method applies to as composite and leaves the
+// others to the fallback refiner.
+func (mr *MethodRefiner) IsAtomic(goal *Goal, current WorldState) bool {
+	if _, found := mr.Method(goal, current); found {
+		return false
+	}
+	if mr.fallback == nil {
+		return true
+	}
+	return mr.fallback.IsAtomic(goal, current)
+}
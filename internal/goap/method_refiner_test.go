@@ -0,0 +1,209 @@
+package goap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMethodRefiner(t *testing.T) {
+	noop := func(ctx context.Context, ws WorldState) error { return nil }
+	goal := NewGoal("FixBug", "Fix the bug", WorldState{"bug_reproduced": true, "bug_fixed": true, "fix_committed": true}, 2.0)
+
+	fixBug := Method{
+		Name:    "ReproduceFixCommit",
+		Goal:    "FixBug",
+		When:    WorldState{"bug_reported": true},
+		Ordered: true,
+		Subgoals: []SubgoalTemplate{
+			{Name: "Reproduce", Description: "Reproduce {{.bug}}", DesiredState: WorldState{"bug_reproduced": true}},
+			{Name: "Fix", DesiredState: WorldState{"bug_fixed": true}},
+			{Name: "Commit", DesiredState: WorldState{"fix_committed": true}},
+		},
+	}
+
+	t.Run("AppliesOrderedMethod", func(t *testing.T) {
+		refiner := NewMethodRefiner(nil)
+		if err := refiner.Add(fixBug); err != nil {
+			t.Fatalf("Failed to add method: %v", err)
+		}
+		current := WorldState{"bug_reported": true, "bug": "issue-42"}
+
+		if refiner.IsAtomic(goal, current) {
+			t.Error("Expected goal with an applicable method not to be atomic")
+		}
+		subgoals, err := refiner.Refine(context.Background(), goal, current)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(subgoals) != 3 {
+			t.Fatalf("Expected 3 subgoals, got %d", len(subgoals))
+		}
+		if subgoals[0].Description() != "Reproduce issue-42" {
+			t.Errorf("Expected rendered description, got %q", subgoals[0].Description())
+		}
+		if subgoals[0].Priority() != goal.Priority() {
+			t.Errorf("Expected subgoals to inherit priority %v, got %v", goal.Priority(), subgoals[0].Priority())
+		}
+		if deps := subgoals[2].DependsOn(); len(deps) != 1 || deps[0] != "Fix" {
+			t.Errorf("Expected Commit to depend on Fix, got %v", deps)
+		}
+		if !subgoals[0].DeclaresDependencies() || len(subgoals[0].DependsOn()) != 0 {
+			t.Errorf("Expected Reproduce to be declared independent, got %v", subgoals[0].DependsOn())
+		}
+	})
+
+	t.Run("PartialOrder", func(t *testing.T) {
+		method := Method{
+			Name: "Parallel",
+			Goal: "FixBug",
+			Subgoals: []SubgoalTemplate{
+				{Name: "Reproduce", DesiredState: WorldState{"bug_reproduced": true}},
+				{Name: "Fix", DesiredState: WorldState{"bug_fixed": true}},
+				{Name: "Commit", DesiredState: WorldState{"fix_committed": true}, DependsOn: []string{"Reproduce", "Fix"}},
+			},
+		}
+		subgoals, err := method.Instantiate(goal, NewWorldState())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(subgoals[1].DependsOn()) != 0 || len(subgoals[2].DependsOn()) != 2 {
+			t.Errorf("Expected only Commit to have dependencies, got %v and %v", subgoals[1].DependsOn(), subgoals[2].DependsOn())
+		}
+	})
+
+	t.Run("FallsBackWhenNoMethodApplies", func(t *testing.T) {
+		fallback := NewMockGoalRefiner()
+		fallback.AddRefinement("FixBug", []*Goal{NewGoal("Everything", "Everything", goal.DesiredState(), 1.0)})
+		refiner := NewMethodRefiner(fallback)
+		refiner.Add(fixBug)
+
+		// The method's When does not hold
+		if refiner.IsAtomic(goal, NewWorldState()) {
+			t.Error("Expected the fallback to decide atomicity")
+		}
+		subgoals, err := refiner.Refine(context.Background(), goal, NewWorldState())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(subgoals) != 1 || subgoals[0].Name() != "Everything" {
+			t.Errorf("Expected the fallback's refinement, got %v", subgoals)
+		}
+
+		if !NewMethodRefiner(nil).IsAtomic(goal, NewWorldState()) {
+			t.Error("Expected goals without methods or fallback to be atomic")
+		}
+	})
+
+	t.Run("Conditions", func(t *testing.T) {
+		method := fixBug
+		method.When = nil
+		method.Conditions = []Condition{{Key: "severity", Op: ">=", Value: 3}}
+		refiner := NewMethodRefiner(nil)
+		refiner.Add(method)
+
+		for _, c := range []struct {
+			state    WorldState
+			expected bool
+		}{
+			{WorldState{"severity": 4}, true},
+			{WorldState{"severity": 1}, false},
+			{NewWorldState(), false},
+		} {
+			if _, found := refiner.Method(goal, c.state); found != c.expected {
+				t.Errorf("Expected method to apply in %v: %v, got %v", c.state, c.expected, found)
+			}
+		}
+	})
+
+	t.Run("RejectsInvalidMethods", func(t *testing.T) {
+		cyclic := Method{
+			Name: "Cyclic",
+			Goal: "FixBug",
+			Subgoals: []SubgoalTemplate{
+				{Name: "A", DesiredState: WorldState{"a": true}, DependsOn: []string{"B"}},
+				{Name: "B", DesiredState: WorldState{"b": true}, DependsOn: []string{"A"}},
+			},
+		}
+		unknown := Method{
+			Name:     "Unknown",
+			Goal:     "FixBug",
+			Subgoals: []SubgoalTemplate{{Name: "A", DesiredState: WorldState{"a": true}, DependsOn: []string{"C"}}},
+		}
+		for _, method := range []Method{cyclic, unknown, {Name: "Empty", Goal: "FixBug"}} {
+			if err := method.Validate(); err == nil {
+				t.Errorf("Expected validation error for method %s", method.Name)
+			}
+		}
+	})
+
+	t.Run("BuiltinMethodPlans", func(t *testing.T) {
+		actions := []Action{
+			NewSimpleAction("Design", "Design", WorldState{}, WorldState{"feature_designed": true}, 1.0, noop),
+			NewSimpleAction("Implement", "Implement", WorldState{"feature_designed": true}, WorldState{"code_implemented": true}, 1.0, noop),
+			NewSimpleAction("Test", "Test", WorldState{"code_implemented": true}, WorldState{"tests_written": true, "go_tests_passed": true, "target_coverage_achieved": true}, 1.0, noop),
+			NewSimpleAction("Gate", "Gate", WorldState{"go_tests_passed": true}, WorldState{"code_formatted": true, "lint_passed": true, "build_succeeded": true, "quality_gates_passed": true}, 1.0, noop),
+			NewSimpleAction("Commit", "Commit", WorldState{"quality_gates_passed": true}, WorldState{"changes_committed": true}, 1.0, noop),
+		}
+		deliver, err := NewGoalLibrary().Instantiate("DeliverQualityFeature", nil)
+		if err != nil {
+			t.Fatalf("Failed to instantiate goal: %v", err)
+		}
+
+		hp := NewHierarchicalPlanner(NewPlanner(actions), NewMethodRefiner(nil), 3)
+		plan, err := hp.PlanHierarchical(context.Background(), WorldState{"project_initialized": true}, deliver.Goal)
+		if err != nil {
+			t.Fatalf("Failed to plan: %v", err)
+		}
+
+		var phases []string
+		for _, subplan := range plan.Subplans {
+			phases = append(phases, subplan.Goal.Name())
+		}
+		expected := []string{"Design", "Implement", "Test", "Gate", "Commit"}
+		if len(phases) != len(expected) {
+			t.Fatalf("Expected phases %v, got %v", expected, phases)
+		}
+		for i := range expected {
+			if phases[i] != expected[i] {
+				t.Errorf("Expected phase %d to be %s, got %s", i, expected[i], phases[i])
+			}
+		}
+	})
+
+	t.Run("LoadsDirectory", func(t *testing.T) {
+		dir := t.TempDir()
+		methodYAML := `
+- name: DeliverQualityFeatureInPhases
+  goal: DeliverQualityFeature
+  subgoals:
+    - name: All
+      desired_state:
+        changes_committed: true
+- name: FixBugQuickly
+  goal: FixBug
+  subgoals:
+    - name: Fix
+      desired_state:
+        bug_fixed: true
+`
+		os.WriteFile(filepath.Join(dir, "methods.yaml"), []byte(methodYAML), 0644)
+		os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a method"), 0644)
+
+		refiner := NewMethodRefiner(nil)
+		builtins := len(refiner.Methods())
+		if err := refiner.LoadDir(dir); err != nil {
+			t.Fatalf("Failed to load methods: %v", err)
+		}
+		if len(refiner.Methods()) != builtins+1 {
+			t.Errorf("Expected the built-in method to be replaced and one added, got %d methods", len(refiner.Methods()))
+		}
+
+		deliver := NewGoal("DeliverQualityFeature", "Deliver", WorldState{"changes_committed": true}, 1.0)
+		method, found := refiner.Method(deliver, NewWorldState())
+		if !found || method.Subgoals[0].Name != "All" {
+			t.Errorf("Expected the loaded method to replace the built-in one, got %v", method)
+		}
+	})
+}
@@ -2,8 +2,10 @@ package goap
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -24,6 +26,14 @@ type Orchestrator struct {
 	persistence   *GraphPersistence
 	visualization *Visualizer
 	maxDepth      int
+	explain       bool
+	maxReplans    int
+	maxRerefines  int
+	maxWorkers    int
+	dryRun        bool
+	registry      *ActionRegistry
+	sensor        StateSensor
+	events        *EventBus
 }
 
 // NewOrchestrator creates the agentic reasoning agent's orchestrator.
@@ -35,9 +45,68 @@ func NewOrchestrator(planner *Planner, refiner GoalRefiner, persistence *GraphPe
 		persistence:   persistence,
 		visualization: NewVisualizer(),
 		maxDepth:      maxDepth,
+		events:        NewEventBus(),
 	}
 }
 
+// Events returns the bus on which the executors of every run the
+// orchestrator executes publish their lifecycle events. Subscribe to it to
+// observe execution without reading the persisted graph.
+func (o *Orchestrator) Events() *EventBus {
+	return o.events
+}
+
+// SetExplain controls whether the planner explains each atomic plan and the
+// orchestrator prints the explanations before execution.
+func (o *Orchestrator) SetExplain(explain bool) {
+	o.explain = explain
+	o.planner.SetExplain(explain)
+}
+
+// SetReplanLimit enables closed-loop replanning of failed atomic nodes during
+// execution, replanning each node at most maxReplans times. Zero disables it.
+func (o *Orchestrator) SetReplanLimit(maxReplans int) {
+	o.maxReplans = maxReplans
+}
+
+// SetRerefineLimit enables re-refinement of failed subtrees during execution.
+// When execution fails, the deepest failed node's goal is refined again with
+// the failure as feedback, its subtree is replaced in the persisted graph and
+// execution resumes from the current state, at most maxRerefines times. Zero
+// disables it.
+func (o *Orchestrator) SetRerefineLimit(maxRerefines int) {
+	o.maxRerefines = maxRerefines
+}
+
+// SetSensor sets the function executors use to re-sense the world before
+// replanning a failed node, so that the new plan starts from the actual state
+// rather than the state the executor believes in.
+func (o *Orchestrator) SetSensor(sensor StateSensor) {
+	o.sensor = sensor
+}
+
+// SetMaxWorkers sets how many atomic nodes may execute concurrently.
+// Independent subgoals run in parallel when it is greater than one.
+func (o *Orchestrator) SetMaxWorkers(maxWorkers int) {
+	o.maxWorkers = maxWorkers
+}
+
+// SetDryRun makes ExecuteGoal and ResumeGoal simulate the plan instead of
+// executing it: actions are not executed, their preconditions are checked
+// and effects applied symbolically, and nothing is persisted; ExecuteGoal
+// does not even create the run directory. Simulated events are marked as
+// such. The simulation report is shown instead of results.
+func (o *Orchestrator) SetDryRun(dryRun bool) {
+	o.dryRun = dryRun
+}
+
+// SetActionRegistry sets the registry executors use to rebuild serialized
+// actions from the plan graph, so that a run planned by another process can
+// be resumed.
+func (o *Orchestrator) SetActionRegistry(registry *ActionRegistry) {
+	o.registry = registry
+}
+
 // ExecuteGoal is the main entry point for the reasoning agent's goal execution.
 // The agent demonstrates the beautiful dance between GOFAI reasoning and LLM generation:
 //
@@ -59,6 +128,22 @@ func (o *Orchestrator) ExecuteGoal(ctx context.Context, initialState WorldState,
 		"priority", goal.Priority(),
 		"runID", runID)
 
+	// A dry run persists nothing, so it leaves no run directory behind
+	if !o.dryRun {
+		runLock, err := o.persistence.LockRun(runID)
+		if err != nil {
+			return err
+		}
+		defer runLock.Unlock()
+
+		journal, err := o.persistence.OpenJournal(runID)
+		if err != nil {
+			return err
+		}
+		defer journal.Close()
+		ctx = WithJournal(ctx, journal)
+	}
+
 	// PHASE 1: GOFAI REASONING - Hierarchical Planning
 	log.Info("📐 PHASE 1: GOFAI REASONING - Hierarchical Planning")
 	o.visualization.ShowPhase("GOFAI Planning & Reasoning", "Using classic AI to reason about goals")
@@ -70,6 +155,7 @@ func (o *Orchestrator) ExecuteGoal(ctx context.Context, initialState WorldState,
 	planDuration := time.Since(start)
 
 	if err != nil {
+		o.visualization.ShowPlanFailure(err)
 		return fmt.Errorf("GOFAI planning failed: %w", err)
 	}
 
@@ -78,13 +164,31 @@ func (o *Orchestrator) ExecuteGoal(ctx context.Context, initialState WorldState,
 		"nodes", o.countNodes(plan),
 		"depth", plan.Depth)
 
+	journalPlan(ctx, plan)
 	o.visualization.ShowPlanSummary(plan, planDuration)
+	if o.explain {
+		o.visualization.ShowPlanExplanation(plan)
+	}
+
+	// Catch broken plans before anything is persisted or executed
+	graph := BuildGraphFromPlan(plan, runID)
+	actions := o.executorActions(plan)
+	err = ValidateGraph(graph, actions, initialState)
+	if err != nil {
+		o.visualization.ShowValidationFailure(err)
+		return fmt.Errorf("plan validation failed: %w", err)
+	}
+
+	if o.dryRun {
+		o.visualization.ShowPhase("Dry Run", "Checking preconditions and applying effects symbolically")
+		report, err := SimulatePlan(ctx, plan, initialState)
+		return o.showSimulation(report, err)
+	}
 
 	// PHASE 2: GOFAI PERSISTENCE - Graph Database
 	log.Info("💾 PHASE 2: GOFAI PERSISTENCE - Storing Plan Graph")
 	o.visualization.ShowPhase("Plan Persistence", "Converting plan to graph database for minimal context")
 
-	graph := BuildGraphFromPlan(plan, runID)
 	err = o.persistence.SaveGraph(graph, runID)
 	if err != nil {
 		return fmt.Errorf("failed to persist plan: %w", err)
@@ -100,12 +204,121 @@ func (o *Orchestrator) ExecuteGoal(ctx context.Context, initialState WorldState,
 
 	executor := NewGraphExecutor(o.persistence, runID)
 
-	// Register all actions from the plan
-	allActions := plan.AllActions()
-	executor.RegisterActions(allActions)
+	executor.RegisterActions(actions)
+	if o.maxReplans > 0 {
+		executor.EnableReplanning(o.planner, o.maxReplans)
+	}
+	executor.SetSensor(o.sensor)
+	executor.SetMaxWorkers(o.maxWorkers)
+	executor.SetEventBus(o.events)
+	executor.SetActionRegistry(o.registry)
+
+	return o.runExecution(ctx, hierarchicalPlanner, executor, runID, func(ctx context.Context) error {
+		return executor.Execute(ctx, initialState)
+	})
+}
+
+// ResumeGoal resumes an interrupted run from its persisted plan graph and
+// latest WorldState checkpoint, without planning again. Completed and
+// skipped nodes are not executed again.
+func (o *Orchestrator) ResumeGoal(ctx context.Context, runID string) error {
+	o.visualization.ShowBanner()
+
+	log.Info("⏯️  Reasoning Agent resuming", "runID", runID)
+
+	runLock, err := o.persistence.LockRun(runID)
+	if err != nil {
+		return err
+	}
+	defer runLock.Unlock()
 
+	journal, err := o.persistence.OpenJournal(runID)
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+	ctx = WithJournal(ctx, journal)
+
+	o.visualization.ShowPhase("Resume", "Continuing from the persisted plan graph and state checkpoint")
+
+	hierarchicalPlanner := NewHierarchicalPlanner(o.planner, o.refiner, o.maxDepth)
+
+	// Persisted plans name the planner's actions, so register all of them
+	executor := NewGraphExecutor(o.persistence, runID)
+	executor.RegisterActions(o.planner.Actions())
+	if o.maxReplans > 0 {
+		executor.EnableReplanning(o.planner, o.maxReplans)
+	}
+	executor.SetSensor(o.sensor)
+	executor.SetMaxWorkers(o.maxWorkers)
+	executor.SetEventBus(o.events)
+	executor.SetActionRegistry(o.registry)
+
+	// The graph may have been planned by another process or with other
+	// actions, so check it against what this process can run
+	graph, err := o.persistence.LoadGraph(runID)
+	if err != nil {
+		return fmt.Errorf("failed to load plan graph: %w", err)
+	}
+	state, err := o.persistence.LoadLatestState(runID)
+	if err != nil {
+		return fmt.Errorf("failed to restore state: %w", err)
+	}
+	err = executor.ValidateGraph(graph, state)
+	if err != nil {
+		o.visualization.ShowValidationFailure(err)
+		return fmt.Errorf("plan validation failed: %w", err)
+	}
+
+	if o.dryRun {
+		o.visualization.ShowPhase("Dry Run", "Checking preconditions and applying effects symbolically")
+		executor.SetDryRun(true)
+		err := executor.Resume(ctx)
+		return o.showSimulation(executor.SimulationReport(), err)
+	}
+
+	return o.runExecution(ctx, hierarchicalPlanner, executor, runID, executor.Resume)
+}
+
+// executorActions returns the actions an executor of the plan can run: all
+// actions from the plan, including fallback alternatives, and the planner's
+// actions if replanning is enabled.
+func (o *Orchestrator) executorActions(plan *HierarchicalPlan) []Action {
+	actions := []Action{}
+	actions = append(actions, plan.AllActions()...)
+	actions = append(actions, plan.AlternativeActions()...)
+	if o.maxReplans > 0 {
+		actions = append(actions, o.planner.Actions()...)
+	}
+	return actions
+}
+
+// showSimulation shows a dry run's report and turns its failures into an
+// error.
+func (o *Orchestrator) showSimulation(report *SimulationReport, err error) error {
+	if report != nil {
+		o.visualization.ShowSimulation(report)
+	}
+	if err != nil {
+		return fmt.Errorf("dry run: %w", err)
+	}
+	return nil
+}
+
+// runExecution runs the executor with progress tracking, re-refining failed
+// subtrees up to the re-refinement limit, and shows the results.
+func (o *Orchestrator) runExecution(ctx context.Context, hierarchicalPlanner *HierarchicalPlanner, executor *GraphExecutor, runID string, execute func(context.Context) error) error {
 	// Execute with progress tracking
-	err = o.executeWithProgress(ctx, executor, initialState, runID)
+	err := o.executeWithProgress(ctx, executor, execute)
+	for attempt := 1; err != nil && attempt <= o.maxRerefines; attempt++ {
+		err = o.rerefineFailedSubtree(ctx, hierarchicalPlanner, executor, runID, err)
+		if err != nil {
+			break
+		}
+		err = o.executeWithProgress(ctx, executor, func(ctx context.Context) error {
+			return executor.Execute(ctx, executor.State())
+		})
+	}
 	if err != nil {
 		return fmt.Errorf("execution failed: %w", err)
 	}
@@ -118,31 +331,71 @@ func (o *Orchestrator) ExecuteGoal(ctx context.Context, initialState WorldState,
 	return nil
 }
 
-// executeWithProgress executes the plan with beautiful progress visualization
-func (o *Orchestrator) executeWithProgress(ctx context.Context, executor *GraphExecutor, initialState WorldState, runID string) error {
-	// Start a progress tracker
-	done := make(chan error, 1)
+// rerefineFailedSubtree re-refines the deepest failed node of the persisted
+// graph from the executor's current state and saves the updated graph.
+func (o *Orchestrator) rerefineFailedSubtree(ctx context.Context, hierarchicalPlanner *HierarchicalPlanner, executor *GraphExecutor, runID string, execErr error) error {
+	graph, err := o.persistence.LoadGraph(runID)
+	if err != nil {
+		return fmt.Errorf("failed to load graph for re-refinement: %w", err)
+	}
 
-	go func() {
-		done <- executor.Execute(ctx, initialState)
-	}()
+	failed, ok := graph.DeepestFailedNode()
+	if !ok {
+		return execErr
+	}
 
-	// Track progress
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	log.Warn("🔁 Re-refining failed subtree", "nodeID", failed.ID, "goal", failed.GoalName, "error", execErr)
 
-	for {
-		select {
-		case err := <-done:
-			return err
+	failure := execErr.Error()
+	if failed.Result != nil && failed.Result.ErrorMessage != "" {
+		failure = failed.Result.ErrorMessage
+	}
 
-		case <-ticker.C:
-			status, err := executor.GetGraphStatus()
-			if err == nil {
-				o.visualization.ShowProgress(status)
-			}
-		}
+	subplan, err := hierarchicalPlanner.RerefineNode(ctx, graph, failed.ID, executor.State(), failure)
+	if err != nil {
+		return fmt.Errorf("re-refinement of %s failed after %v: %w", failed.GoalName, execErr, err)
 	}
+
+	journalPlan(ctx, subplan)
+	executor.RegisterActions(subplan.AllActions())
+	executor.RegisterActions(subplan.AlternativeActions())
+
+	err = executor.ValidateGraph(graph, executor.State())
+	if err != nil {
+		o.visualization.ShowValidationFailure(err)
+		return fmt.Errorf("re-refined plan for %s failed validation: %w", failed.GoalName, err)
+	}
+
+	graph.ResetFailed()
+	if err := o.persistence.SaveGraph(graph, runID); err != nil {
+		return fmt.Errorf("failed to persist re-refined plan: %w", err)
+	}
+
+	log.Info("✓ Subtree re-refined", "nodeID", failed.ID, "nodes", len(graph.Nodes))
+	return nil
+}
+
+// executeWithProgress executes the plan with beautiful progress visualization,
+// updated from the executor's lifecycle events
+func (o *Orchestrator) executeWithProgress(ctx context.Context, executor *GraphExecutor, execute func(context.Context) error) error {
+	var mu sync.Mutex
+	progress := &GraphStatus{}
+
+	unsubscribe := executor.Events().Subscribe(func(event Event) {
+		switch event.Type {
+		case EventPlanStarted, EventNodeStarted, EventNodeFinished, EventRunFinished:
+		default:
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		progress.Apply(event)
+		o.visualization.ShowProgress(progress)
+	})
+	defer unsubscribe()
+
+	return execute(ctx)
 }
 
 // countNodes counts total nodes in hierarchical plan
@@ -214,6 +467,45 @@ func (v *Visualizer) ShowPlanSummary(plan *HierarchicalPlan, duration time.Durat
 	fmt.Println()
 }
 
+// ShowPlanExplanation prints why each atomic plan was chosen.
+func (v *Visualizer) ShowPlanExplanation(plan *HierarchicalPlan) {
+	fmt.Println("  🔎 Plan Explanation:")
+	for _, line := range strings.Split(strings.TrimRight(plan.Explain(), "\n"), "\n") {
+		fmt.Println("     " + line)
+	}
+	fmt.Println()
+}
+
+// ShowPlanFailure prints the planner's diagnostics when err carries a
+// PlanFailure. Other errors are left to the caller to report.
+func (v *Visualizer) ShowPlanFailure(err error) {
+	var failure *PlanFailure
+	if !errors.As(err, &failure) {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("  ❌ Planning Failed:")
+	for _, line := range strings.Split(strings.TrimRight(failure.Report(), "\n"), "\n") {
+		fmt.Println("     " + line)
+	}
+	fmt.Println()
+}
+
+func (v *Visualizer) ShowValidationFailure(err error) {
+	var invalid *PlanValidationError
+	if !errors.As(err, &invalid) {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("  ❌ Plan Validation Failed:")
+	for _, line := range strings.Split(strings.TrimRight(invalid.Report(), "\n"), "\n") {
+		fmt.Println("     " + line)
+	}
+	fmt.Println()
+}
+
 func (v *Visualizer) showPlanTree(plan *HierarchicalPlan, indent int) {
 	prefix := strings.Repeat("   ", indent)
 
@@ -256,6 +548,27 @@ func (v *Visualizer) makeProgressBar(percent float64, width int) string {
 	return bar
 }
 
+func (v *Visualizer) ShowSimulation(report *SimulationReport) {
+	fmt.Println()
+	fmt.Println(strings.Repeat("═", 80))
+	fmt.Println("  🧪 DRY RUN COMPLETE")
+	fmt.Println(strings.Repeat("═", 80))
+	fmt.Println()
+	for _, line := range strings.Split(strings.TrimRight(report.String(), "\n"), "\n") {
+		fmt.Println("  " + line)
+	}
+
+	fmt.Println()
+	if len(report.Failures()) > 0 {
+		fmt.Println("  ⚠️  STATUS: PLAN WOULD FAIL")
+	} else {
+		fmt.Println("  ✅ STATUS: PLAN IS CONSISTENT")
+	}
+	fmt.Println()
+	fmt.Println(strings.Repeat("═", 80))
+	fmt.Println()
+}
+
 func (v *Visualizer) ShowResults(status *GraphStatus) {
 	fmt.Println()
 	fmt.Println()
@@ -317,10 +630,7 @@ func (pa *PlanAnalyzer) analyzePlanRecursive(plan *HierarchicalPlan, analysis *P
 	if plan.IsAtomic() {
 		analysis.AtomicNodes++
 		analysis.TotalActions += len(plan.Actions)
-
-		for _, action := range plan.Actions {
-			analysis.TotalCost += action.Cost()
-		}
+		analysis.TotalCost += plan.TotalCost()
 	} else {
 		for _, subplan := range plan.Subplans {
 			pa.analyzePlanRecursive(subplan, analysis, depth+1)
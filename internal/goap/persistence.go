@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
-	"github.com/charmbracelet/log"
+	"upside-down-research.com/oss/agentic/internal/fsutil"
 )
 
 // PlanGraph represents the hierarchical plan as a graph structure suitable for
@@ -23,14 +26,34 @@ type GraphNode struct {
 	ID           string                 `json:"id"`
 	GoalName     string                 `json:"goal_name"`
 	GoalDesc     string                 `json:"goal_description"`
-	DesiredState map[string]interface{} `json:"desired_state"`
+	DesiredState WorldState             `json:"desired_state"`
 	ParentID     string                 `json:"parent_id,omitempty"`
 	ChildIDs     []string               `json:"child_ids,omitempty"`
+	DependsOn    []string               `json:"depends_on,omitempty"`
 	ActionNames  []string               `json:"action_names,omitempty"`
+	Alternatives [][]string             `json:"alternatives,omitempty"`
+	Cost         float64                `json:"cost,omitempty"`
 	IsAtomic     bool                   `json:"is_atomic"`
 	Depth        int                    `json:"depth"`
 	Status       NodeStatus             `json:"status"`
 	Result       *NodeResult            `json:"result,omitempty"`
+
+	// ActionSpecs and AlternativeSpecs parallel ActionNames and Alternatives
+	// with the serialized actions, so that executors can rebuild the exact
+	// instances. Actions that are not serializable have empty specs.
+	ActionSpecs      []ActionSpec   `json:"action_specs,omitempty"`
+	AlternativeSpecs [][]ActionSpec `json:"alternative_specs,omitempty"`
+
+	// Revision counts how many times the node's subtree has been re-refined
+	Revision int `json:"revision,omitempty"`
+
+	// Superseded lists the IDs of descendants replaced by re-refinement
+	Superseded []string `json:"superseded,omitempty"`
+
+	// ContentID is derived from the goal names on the path from the root and
+	// the desired state, so the same goal has the same ContentID across runs.
+	// The node's ID is derived from it.
+	ContentID string `json:"content_id,omitempty"`
 }
 
 // NodeStatus represents the execution status of a node.
@@ -48,7 +71,18 @@ const (
 type NodeResult struct {
 	Success      bool                   `json:"success"`
 	ErrorMessage string                 `json:"error_message,omitempty"`
-	StateChanges map[string]interface{} `json:"state_changes,omitempty"`
+	StateChanges WorldState             `json:"state_changes,omitempty"`
+	Replans      []ReplanRecord         `json:"replans,omitempty"`
+	Duration     time.Duration          `json:"duration,omitempty"`
+}
+
+// ReplanRecord records one attempt to replan a failed atomic node.
+type ReplanRecord struct {
+	Attempt     int      `json:"attempt"`
+	Timestamp   string   `json:"timestamp"`
+	Cause       string   `json:"cause"`
+	ActionNames []string `json:"action_names,omitempty"`
+	Error       string   `json:"error,omitempty"`
 }
 
 // GraphMetadata contains metadata about the plan graph.
@@ -70,36 +104,65 @@ func NewPlanGraph(agentID string) *PlanGraph {
 }
 
 // BuildGraphFromPlan converts a HierarchicalPlan into a PlanGraph for persistence.
+// Node IDs are derived from the node's content ID, so the same goal has the
+// same ID across runs.
 func BuildGraphFromPlan(plan *HierarchicalPlan, agentID string) *PlanGraph {
-	graph := NewPlanGraph(agentID)
-	nodeCounter := 0
+	graph := buildGraph(plan, agentID, plan.Goal.Name(), "")
+	graph.assignContentIDs()
+	return graph
+}
 
-	var buildNode func(*HierarchicalPlan, string) string
-	buildNode = func(hp *HierarchicalPlan, parentID string) string {
-		nodeCounter++
-		nodeID := fmt.Sprintf("node_%d", nodeCounter)
+// buildGraph converts a plan whose root goal is at the given path into a
+// graph. The root gets rootID if it is set; every other node gets an ID
+// derived from its path and desired state.
+func buildGraph(plan *HierarchicalPlan, agentID, rootPath, rootID string) *PlanGraph {
+	graph := NewPlanGraph(agentID)
 
+	var buildNode func(*HierarchicalPlan, string, string) string
+	buildNode = func(hp *HierarchicalPlan, parentID, path string) string {
 		// Convert desired state to map
 		desiredState := make(map[string]interface{})
 		for k, v := range hp.Goal.DesiredState() {
 			desiredState[k] = v
 		}
 
-		// Extract action names if atomic
+		nodeID := nodeIDFor(path, desiredState)
+		if parentID == "" && rootID != "" {
+			nodeID = rootID
+		}
+
+		// Extract action names and the planner's cost if atomic
 		actionNames := []string{}
+		cost := 0.0
 		if hp.IsAtomic() && hp.Actions != nil {
 			for _, action := range hp.Actions {
 				actionNames = append(actionNames, action.Name())
 			}
+			cost = hp.TotalCost()
+		}
+
+		// Extract fallback action sequences, cheapest first
+		var alternatives [][]string
+		var alternativeSpecs [][]ActionSpec
+		for _, alternative := range hp.Alternatives {
+			names := make([]string, 0, len(alternative.Actions))
+			for _, action := range alternative.Actions {
+				names = append(names, action.Name())
+			}
+			alternatives = append(alternatives, names)
+			alternativeSpecs = append(alternativeSpecs, actionSpecs(alternative.Actions))
 		}
 
 		// Build child nodes
 		childIDs := []string{}
 		if !hp.IsAtomic() {
+			seen := make(map[string]int)
 			for _, subplan := range hp.Subplans {
-				childID := buildNode(subplan, nodeID)
+				childID := buildNode(subplan, nodeID, childPath(path, subplan.Goal.Name(), seen))
 				childIDs = append(childIDs, childID)
+				cost += graph.Nodes[childID].Cost
 			}
+			linkDependencies(graph, hp.Subplans, childIDs)
 		}
 
 		// Create graph node
@@ -111,10 +174,21 @@ func BuildGraphFromPlan(plan *HierarchicalPlan, agentID string) *PlanGraph {
 			ParentID:     parentID,
 			ChildIDs:     childIDs,
 			ActionNames:  actionNames,
+			Alternatives: alternatives,
+			Cost:         cost,
 			IsAtomic:     hp.IsAtomic(),
 			Depth:        hp.Depth,
 			Status:       StatusPending,
 		}
+		if hp.IsAtomic() {
+			node.ActionSpecs = actionSpecs(hp.Actions)
+		}
+		for _, specs := range alternativeSpecs {
+			if specs != nil {
+				node.AlternativeSpecs = alternativeSpecs
+				break
+			}
+		}
 
 		graph.Nodes[nodeID] = node
 
@@ -122,16 +196,169 @@ func BuildGraphFromPlan(plan *HierarchicalPlan, agentID string) *PlanGraph {
 	}
 
 	// Build the graph starting from root
-	rootID := buildNode(plan, "")
-	graph.RootNodeID = rootID
+	graph.RootNodeID = buildNode(plan, "", rootPath)
 
 	// Update metadata
-	graph.Metadata.TotalNodes = nodeCounter
+	graph.Metadata.TotalNodes = len(graph.Nodes)
 	graph.Metadata.MaxDepth = calculateMaxDepth(graph)
 
 	return graph
 }
 
+// ReplaceSubtree replaces the subtree rooted at nodeID with a new plan for
+// the same goal, e.g. after re-refining a failed goal. The subtree root keeps
+// its ID, parent and depth so lineage is preserved; its revision is bumped
+// and the IDs of the descendants it loses are recorded as superseded. New
+// descendants get content-derived IDs, suffixed with the revision if a
+// superseded node had the same ID, and nodes outside the subtree are
+// untouched.
+func (g *PlanGraph) ReplaceSubtree(nodeID string, plan *HierarchicalPlan) error {
+	root, exists := g.Nodes[nodeID]
+	if !exists {
+		return fmt.Errorf("node not found: %s", nodeID)
+	}
+	path := g.goalKeys()[nodeID].path
+
+	// Remove the old descendants
+	var removeDescendants func(string)
+	removeDescendants = func(id string) {
+		node, exists := g.Nodes[id]
+		if !exists {
+			return
+		}
+		for _, childID := range node.ChildIDs {
+			removeDescendants(childID)
+			root.Superseded = append(root.Superseded, childID)
+			root.Superseded = append(root.Superseded, g.Nodes[childID].Superseded...)
+			delete(g.Nodes, childID)
+		}
+	}
+	removeDescendants(nodeID)
+
+	subtree := buildGraph(plan, g.Metadata.AgentID, path, nodeID)
+	revision := root.Revision + 1
+
+	// A descendant must not take the ID of one it replaced, whose events
+	// and checkpoints belong to the old node
+	superseded := make(map[string]bool, len(root.Superseded))
+	for _, id := range root.Superseded {
+		superseded[id] = true
+	}
+	newIDs := make(map[string]string, len(subtree.Nodes))
+	for id := range subtree.Nodes {
+		newIDs[id] = id
+		if superseded[id] {
+			newIDs[id] = fmt.Sprintf("%s_r%d", id, revision)
+		}
+	}
+
+	// Graft the subtree with the root's parent and dependency edges
+	newRoot := subtree.Nodes[subtree.RootNodeID]
+	for oldID, node := range subtree.Nodes {
+		node.ID = newIDs[oldID]
+		if oldID == subtree.RootNodeID {
+			node.ParentID = root.ParentID
+			node.DependsOn = root.DependsOn
+		} else {
+			node.ParentID = newIDs[node.ParentID]
+			for i, dependency := range node.DependsOn {
+				node.DependsOn[i] = newIDs[dependency]
+			}
+		}
+		for i, childID := range node.ChildIDs {
+			node.ChildIDs[i] = newIDs[childID]
+		}
+		g.Nodes[node.ID] = node
+	}
+	newRoot.Revision = revision
+	newRoot.Superseded = root.Superseded
+	newRoot.Depth = root.Depth
+
+	g.Metadata.TotalNodes = len(g.Nodes)
+	g.Metadata.MaxDepth = calculateMaxDepth(g)
+	g.assignContentIDs()
+
+	return nil
+}
+
+// DeepestFailedNode returns the deepest node marked as failed, which is where
+// re-refinement should start. Returns false if no node failed.
+func (g *PlanGraph) DeepestFailedNode() (*GraphNode, bool) {
+	var deepest *GraphNode
+	for _, id := range g.nodeIDs() {
+		node := g.Nodes[id]
+		if node.Status == StatusFailed && (deepest == nil || node.Depth > deepest.Depth) {
+			deepest = node
+		}
+	}
+	return deepest, deepest != nil
+}
+
+// ResetFailed marks every failed node as pending again so it will be
+// re-executed.
+func (g *PlanGraph) ResetFailed() {
+	for _, node := range g.Nodes {
+		if node.Status == StatusFailed {
+			node.Status = StatusPending
+		}
+	}
+}
+
+// nodeIDs returns the graph's node IDs in sorted order.
+func (g *PlanGraph) nodeIDs() []string {
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// linkDependencies adds dependency edges between sibling nodes. Declared
+// subgoal dependencies become edges to the named siblings; siblings without
+// declared dependencies each depend on the previous one, preserving the
+// sequential order.
+func linkDependencies(graph *PlanGraph, subplans []*HierarchicalPlan, childIDs []string) {
+	declared := false
+	idsByName := make(map[string]string, len(subplans))
+	for i, subplan := range subplans {
+		declared = declared || subplan.Goal.DeclaresDependencies()
+		idsByName[subplan.Goal.Name()] = childIDs[i]
+	}
+
+	for i, subplan := range subplans {
+		child := graph.Nodes[childIDs[i]]
+		if !declared {
+			if i > 0 {
+				child.DependsOn = []string{childIDs[i-1]}
+			}
+			continue
+		}
+		for _, dependency := range subplan.Goal.DependsOn() {
+			if id, exists := idsByName[dependency]; exists {
+				child.DependsOn = append(child.DependsOn, id)
+			}
+		}
+	}
+}
+
+// GraphEdge is a dependency edge: From cannot start before To completes.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Edges returns the graph's dependency edges, sorted by node ID.
+func (g *PlanGraph) Edges() []GraphEdge {
+	edges := []GraphEdge{}
+	for _, id := range g.nodeIDs() {
+		for _, dependency := range g.Nodes[id].DependsOn {
+			edges = append(edges, GraphEdge{From: id, To: dependency})
+		}
+	}
+	return edges
+}
+
 func calculateMaxDepth(graph *PlanGraph) int {
 	maxDepth := 0
 	for _, node := range graph.Nodes {
@@ -142,119 +369,153 @@ func calculateMaxDepth(graph *PlanGraph) int {
 	return maxDepth
 }
 
-// GraphPersistence handles saving and loading plan graphs to/from disk.
+// GraphPersistence handles saving and loading plan graphs and WorldState
+// checkpoints for runs. Graphs are kept in a GraphStore; checkpoints are files
+// in each run's directory. It is safe for concurrent use; node updates from
+// concurrently executing nodes are applied atomically by the store.
 type GraphPersistence struct {
 	basePath string
+	store    GraphStore
+	mu       sync.Mutex
+
+	// checkpointVersions holds the last checkpoint version of each run,
+	// read from the checkpoint files when the run is first checkpointed
+	checkpointVersions map[string]int
 }
 
-// NewGraphPersistence creates a new graph persistence handler.
+// NewGraphPersistence creates a new graph persistence handler that stores
+// graphs as JSON files under basePath.
 func NewGraphPersistence(basePath string) *GraphPersistence {
+	return NewGraphPersistenceWithStore(basePath, NewJSONGraphStore(basePath))
+}
+
+// NewGraphPersistenceWithStore creates a graph persistence handler that keeps
+// graphs in the given store and checkpoints under basePath.
+func NewGraphPersistenceWithStore(basePath string, store GraphStore) *GraphPersistence {
 	return &GraphPersistence{
-		basePath: basePath,
+		basePath:           basePath,
+		store:              store,
+		checkpointVersions: make(map[string]int),
 	}
 }
 
-// SaveGraph saves a plan graph to disk.
+// Store returns the store holding the plan graphs.
+func (gp *GraphPersistence) Store() GraphStore {
+	return gp.store
+}
+
+// SaveGraph saves a plan graph, replacing any graph stored for the run.
 func (gp *GraphPersistence) SaveGraph(graph *PlanGraph, runID string) error {
-	graphDir := filepath.Join(gp.basePath, runID, "graph")
-	err := os.MkdirAll(graphDir, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create graph directory: %w", err)
-	}
+	return gp.store.SaveGraph(graph, runID)
+}
 
-	// Save the main graph structure
-	graphPath := filepath.Join(graphDir, "plan_graph.json")
-	graphJSON, err := json.MarshalIndent(graph, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal graph: %w", err)
-	}
+// LoadGraph loads a plan graph.
+func (gp *GraphPersistence) LoadGraph(runID string) (*PlanGraph, error) {
+	return gp.store.LoadGraph(runID)
+}
 
-	err = os.WriteFile(graphPath, graphJSON, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write graph file: %w", err)
-	}
+// LoadNodeContext loads minimal context for a specific node.
+// This enables focused LLM execution without loading the entire plan.
+func (gp *GraphPersistence) LoadNodeContext(runID, nodeID string) (*NodeContext, error) {
+	return gp.store.LoadNodeContext(runID, nodeID)
+}
 
-	// Save individual node files for minimal context loading
-	nodesDir := filepath.Join(graphDir, "nodes")
-	err = os.MkdirAll(nodesDir, 0755)
-	if err != nil {
-		return fmt.Errorf("failed to create nodes directory: %w", err)
-	}
+// UpdateNodeStatus updates the status of a node in the graph.
+func (gp *GraphPersistence) UpdateNodeStatus(runID, nodeID string, status NodeStatus, result *NodeResult) error {
+	return gp.store.UpdateNode(runID, nodeID, func(node *GraphNode) error {
+		node.Status = status
+		node.Result = result
+		return nil
+	})
+}
 
-	for nodeID := range graph.Nodes {
-		nodeContext := gp.buildNodeContext(graph, nodeID)
-		nodePath := filepath.Join(nodesDir, nodeID+".json")
-		nodeJSON, err := json.MarshalIndent(nodeContext, "", "  ")
-		if err != nil {
-			log.Error("Failed to marshal node context", "nodeID", nodeID, "error", err)
-			continue
-		}
+// UpdateNodeActions replaces the action sequence of an atomic node in the
+// graph, for example after the node was replanned during execution. specs
+// parallels actionNames and may be nil.
+func (gp *GraphPersistence) UpdateNodeActions(runID, nodeID string, actionNames []string, specs []ActionSpec) error {
+	return gp.store.UpdateNode(runID, nodeID, func(node *GraphNode) error {
+		node.ActionNames = actionNames
+		node.ActionSpecs = specs
+		node.Alternatives = nil
+		node.AlternativeSpecs = nil
+		return nil
+	})
+}
 
-		err = os.WriteFile(nodePath, nodeJSON, 0644)
-		if err != nil {
-			log.Error("Failed to write node file", "nodeID", nodeID, "error", err)
-			continue
-		}
+// LockRun takes the advisory lock on a run's directory, so that two
+// processes cannot execute or resume the same run at once. A lock left by a
+// crashed process is taken over. The caller must Unlock it when done.
+func (gp *GraphPersistence) LockRun(runID string) (*fsutil.DirLock, error) {
+	lock, err := fsutil.LockDir(filepath.Join(gp.basePath, runID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock run %s: %w", runID, err)
 	}
-
-	log.Info("Plan graph saved", "path", graphDir, "nodes", len(graph.Nodes))
-	return nil
+	return lock, nil
 }
 
-// LoadGraph loads a plan graph from disk.
-func (gp *GraphPersistence) LoadGraph(runID string) (*PlanGraph, error) {
-	graphPath := filepath.Join(gp.basePath, runID, "graph", "plan_graph.json")
+// SaveStateCheckpoint saves a WorldState as the latest checkpoint of a run,
+// from which an interrupted execution can be resumed.
+func (gp *GraphPersistence) SaveStateCheckpoint(runID string, state WorldState) error {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+
+	graphDir := filepath.Join(gp.basePath, runID, "graph")
+	err := os.MkdirAll(graphDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create graph directory: %w", err)
+	}
 
-	data, err := os.ReadFile(graphPath)
+	stateJSON, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read graph file: %w", err)
+		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	var graph PlanGraph
-	err = json.Unmarshal(data, &graph)
+	err = fsutil.WriteFileAtomic(filepath.Join(graphDir, "state.json"), stateJSON, 0644)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal graph: %w", err)
+		return fmt.Errorf("failed to write state checkpoint: %w", err)
 	}
 
-	log.Info("Plan graph loaded", "nodes", len(graph.Nodes))
-	return &graph, nil
+	return nil
 }
 
-// LoadNodeContext loads minimal context for a specific node.
-// This enables focused LLM execution without loading the entire plan.
-func (gp *GraphPersistence) LoadNodeContext(runID, nodeID string) (*NodeContext, error) {
-	nodePath := filepath.Join(gp.basePath, runID, "graph", "nodes", nodeID+".json")
+// LoadLatestState loads the latest WorldState checkpoint of a run.
+func (gp *GraphPersistence) LoadLatestState(runID string) (WorldState, error) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
 
-	data, err := os.ReadFile(nodePath)
+	data, err := os.ReadFile(filepath.Join(gp.basePath, runID, "graph", "state.json"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read node context: %w", err)
+		return nil, fmt.Errorf("failed to read state checkpoint: %w", err)
 	}
 
-	var context NodeContext
-	err = json.Unmarshal(data, &context)
+	state := NewWorldState()
+	err = json.Unmarshal(data, &state)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal node context: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal state checkpoint: %w", err)
 	}
 
-	return &context, nil
+	return state, nil
 }
 
-// UpdateNodeStatus updates the status of a node in the graph.
-func (gp *GraphPersistence) UpdateNodeStatus(runID, nodeID string, status NodeStatus, result *NodeResult) error {
-	graph, err := gp.LoadGraph(runID)
+// ResetRunningNodes marks nodes left running by an interrupted execution as
+// pending again and returns their IDs.
+func (gp *GraphPersistence) ResetRunningNodes(runID string) ([]string, error) {
+	reset := []string{}
+	err := gp.store.UpdateGraph(runID, func(graph *PlanGraph) error {
+		for _, id := range graph.nodeIDs() {
+			node := graph.Nodes[id]
+			if node.Status == StatusRunning {
+				node.Status = StatusPending
+				node.Result = nil
+				reset = append(reset, id)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	node, exists := graph.Nodes[nodeID]
-	if !exists {
-		return fmt.Errorf("node not found: %s", nodeID)
-	}
-
-	node.Status = status
-	node.Result = result
-
-	return gp.SaveGraph(graph, runID)
+	return reset, nil
 }
 
 // NodeContext represents the minimal context needed to execute a single node.
@@ -268,7 +529,7 @@ type NodeContext struct {
 }
 
 // buildNodeContext creates minimal context for a node.
-func (gp *GraphPersistence) buildNodeContext(graph *PlanGraph, nodeID string) *NodeContext {
+func buildNodeContext(graph *PlanGraph, nodeID string) *NodeContext {
 	node, exists := graph.Nodes[nodeID]
 	if !exists {
 		return nil
@@ -304,12 +565,12 @@ func (gp *GraphPersistence) buildNodeContext(graph *PlanGraph, nodeID string) *N
 	}
 
 	// Build path from root
-	context.PathFromRoot = gp.buildPathFromRoot(graph, nodeID)
+	context.PathFromRoot = buildPathFromRoot(graph, nodeID)
 
 	return context
 }
 
-func (gp *GraphPersistence) buildPathFromRoot(graph *PlanGraph, nodeID string) []string {
+func buildPathFromRoot(graph *PlanGraph, nodeID string) []string {
 	path := []string{nodeID}
 	currentID := nodeID
 
@@ -1,6 +1,7 @@
 package goap
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -86,6 +87,39 @@ func TestPersistence(t *testing.T) {
 		}
 	})
 
+	t.Run("PlannedCosts", func(t *testing.T) {
+		// Testing is cheap once the code is built, whatever its static cost
+		build := NewSimpleAction("Build", "Build", WorldState{}, WorldState{"built": true}, 2.0, nil)
+		test := &stateCostAction{
+			SimpleAction: NewSimpleAction("Test", "Test", WorldState{}, WorldState{"tested": true}, 10.0, nil),
+			costIn: func(state WorldState) float64 {
+				if state.Get("built") == true {
+					return 1.0
+				}
+				return 10.0
+			},
+		}
+
+		refiner := NewMockGoalRefiner()
+		refiner.AddRefinement("Ship", []*Goal{
+			NewGoal("BuildIt", "Build", WorldState{"built": true}, 1.0),
+			NewGoal("TestIt", "Test", WorldState{"tested": true}, 1.0),
+		})
+		hp := NewHierarchicalPlanner(NewPlanner([]Action{build, test}), refiner, 3)
+		plan, err := hp.PlanHierarchical(context.Background(), NewWorldState(), NewGoal("Ship", "Ship", WorldState{"built": true, "tested": true}, 1.0))
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		if cost := nodeByGoal(t, graph, "TestIt").Cost; cost != 1.0 {
+			t.Errorf("Expected the planner's cost 1.0 in the built state, got %v", cost)
+		}
+		if cost := graph.Nodes[graph.RootNodeID].Cost; cost != 3.0 || plan.TotalCost() != 3.0 {
+			t.Errorf("Expected the root to sum the planned costs 3.0, got %v and %v", cost, plan.TotalCost())
+		}
+	})
+
 	t.Run("SaveAndLoadGraph", func(t *testing.T) {
 		goal := NewGoal("TestGoal", "A test", WorldState{"test": true}, 1.0)
 		action := NewSimpleAction("TestAction", "Test", WorldState{}, WorldState{"test": true}, 1.0, nil)
@@ -344,3 +378,50 @@ func TestGraphStatus(t *testing.T) {
 		}
 	})
 }
+
+func TestLockRun(t *testing.T) {
+	persistence := NewGraphPersistence(t.TempDir())
+
+	lock, err := persistence.LockRun("test-lock")
+	if err != nil {
+		t.Fatalf("Failed to lock run: %v", err)
+	}
+
+	if _, err := persistence.LockRun("test-lock"); err == nil {
+		t.Error("Locking a locked run should fail")
+	}
+	if other, err := persistence.LockRun("other-run"); err != nil {
+		t.Errorf("Locking another run should succeed, got %v", err)
+	} else {
+		other.Unlock()
+	}
+
+	lock.Unlock()
+	if relock, err := persistence.LockRun("test-lock"); err != nil {
+		t.Errorf("Locking after unlock should succeed, got %v", err)
+	} else {
+		relock.Unlock()
+	}
+}
+
+// nodeByGoal returns the node pursuing the named goal.
+func nodeByGoal(t *testing.T, graph *PlanGraph, goalName string) *GraphNode {
+	t.Helper()
+	for _, id := range graph.nodeIDs() {
+		if graph.Nodes[id].GoalName == goalName {
+			return graph.Nodes[id]
+		}
+	}
+	t.Fatalf("No node pursues goal %s", goalName)
+	return nil
+}
+
+// stateCostAction is a SimpleAction whose cost depends on the state.
+type stateCostAction struct {
+	*SimpleAction
+	costIn func(state WorldState) float64
+}
+
+func (a *stateCostAction) CostIn(state WorldState) float64 {
+	return a.costIn(state)
+}
@@ -0,0 +1,274 @@
+package goap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlanDiff describes how the plan for a goal changed between two runs.
+// Goals are matched by their content ID (path and desired state) first, then
+// by path alone, in which case their desired state changed.
+type PlanDiff struct {
+	Added     []GoalSummary `json:"added"`
+	Removed   []GoalSummary `json:"removed"`
+	Modified  []GoalChange  `json:"modified"`
+	Unchanged int           `json:"unchanged"`
+
+	CostBefore float64 `json:"cost_before"`
+	CostAfter  float64 `json:"cost_after"`
+	CostDelta  float64 `json:"cost_delta"`
+}
+
+// GoalSummary describes a goal present in only one of two plans.
+type GoalSummary struct {
+	ContentID    string                 `json:"content_id"`
+	Path         string                 `json:"path"`
+	DesiredState map[string]interface{} `json:"desired_state"`
+	ActionNames  []string               `json:"action_names,omitempty"`
+	Cost         float64                `json:"cost"`
+}
+
+// GoalChange describes a goal present in both plans whose desired state,
+// action sequence or cost changed.
+type GoalChange struct {
+	Path string `json:"path"`
+
+	// DesiredState is set when the goal was matched by path only
+	DesiredState *StateDiff `json:"desired_state,omitempty"`
+
+	ActionsBefore []string `json:"actions_before,omitempty"`
+	ActionsAfter  []string `json:"actions_after,omitempty"`
+
+	CostBefore float64 `json:"cost_before"`
+	CostAfter  float64 `json:"cost_after"`
+	CostDelta  float64 `json:"cost_delta"`
+}
+
+// ActionsChanged reports whether the goal's action sequence changed.
+func (gc *GoalChange) ActionsChanged() bool {
+	if len(gc.ActionsBefore) != len(gc.ActionsAfter) {
+		return true
+	}
+	for i := range gc.ActionsBefore {
+		if gc.ActionsBefore[i] != gc.ActionsAfter[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty reports whether the two plans are the same.
+func (pd *PlanDiff) IsEmpty() bool {
+	return len(pd.Added) == 0 && len(pd.Removed) == 0 && len(pd.Modified) == 0
+}
+
+// String returns a human-readable rendering of the diff.
+func (pd *PlanDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan cost: %.2f → %.2f (%+.2f)\n", pd.CostBefore, pd.CostAfter, pd.CostDelta)
+	if pd.IsEmpty() {
+		fmt.Fprintf(&b, "No goal changes (%d unchanged)\n", pd.Unchanged)
+		return b.String()
+	}
+
+	for _, goal := range pd.Added {
+		fmt.Fprintf(&b, "+ %s (cost: %.2f)\n", goal.Path, goal.Cost)
+		if len(goal.ActionNames) > 0 {
+			fmt.Fprintf(&b, "    actions: %s\n", strings.Join(goal.ActionNames, " → "))
+		}
+	}
+	for _, goal := range pd.Removed {
+		fmt.Fprintf(&b, "- %s (cost: %.2f)\n", goal.Path, goal.Cost)
+		if len(goal.ActionNames) > 0 {
+			fmt.Fprintf(&b, "    actions: %s\n", strings.Join(goal.ActionNames, " → "))
+		}
+	}
+	for _, change := range pd.Modified {
+		fmt.Fprintf(&b, "~ %s (cost: %.2f → %.2f, %+.2f)\n", change.Path, change.CostBefore, change.CostAfter, change.CostDelta)
+		if change.DesiredState != nil {
+			for _, line := range strings.Split(strings.TrimSuffix(change.DesiredState.String(), "\n"), "\n") {
+				fmt.Fprintf(&b, "    desired %s\n", line)
+			}
+		}
+		if change.ActionsChanged() {
+			fmt.Fprintf(&b, "    actions: %s\n", strings.Join(change.ActionsBefore, " → "))
+			fmt.Fprintf(&b, "          → %s\n", strings.Join(change.ActionsAfter, " → "))
+		}
+	}
+	fmt.Fprintf(&b, "%d added, %d removed, %d modified, %d unchanged\n",
+		len(pd.Added), len(pd.Removed), len(pd.Modified), pd.Unchanged)
+
+	return b.String()
+}
+
+// JSON returns the diff as indented JSON.
+func (pd *PlanDiff) JSON() ([]byte, error) {
+	return json.MarshalIndent(pd, "", "  ")
+}
+
+// DiffGraphs compares the plan graphs of two runs. Content IDs are computed
+// from the graphs rather than read from the nodes, so graphs persisted before
+// content IDs existed can be compared too. Nodes unreachable from the root,
+// such as superseded ones, are ignored.
+func DiffGraphs(before, after *PlanGraph) *PlanDiff {
+	diff := &PlanDiff{
+		Added:    []GoalSummary{},
+		Removed:  []GoalSummary{},
+		Modified: []GoalChange{},
+	}
+	if root, exists := before.Nodes[before.RootNodeID]; exists {
+		diff.CostBefore = root.Cost
+	}
+	if root, exists := after.Nodes[after.RootNodeID]; exists {
+		diff.CostAfter = root.Cost
+	}
+	diff.CostDelta = diff.CostAfter - diff.CostBefore
+
+	beforeKeys := before.goalKeys()
+	afterKeys := after.goalKeys()
+
+	// Match by content ID, then by path
+	beforeByContent := make(map[string]string, len(beforeKeys))
+	for nodeID, key := range beforeKeys {
+		beforeByContent[key.contentID] = nodeID
+	}
+	unmatchedAfter := []string{}
+	for nodeID, key := range afterKeys {
+		matchID, exists := beforeByContent[key.contentID]
+		if !exists {
+			unmatchedAfter = append(unmatchedAfter, nodeID)
+			continue
+		}
+		delete(beforeByContent, key.contentID)
+		if change, modified := compareGoals(before.Nodes[matchID], after.Nodes[nodeID], key.path, false); modified {
+			diff.Modified = append(diff.Modified, change)
+		} else {
+			diff.Unchanged++
+		}
+	}
+
+	unmatchedBefore := make(map[string]string, len(beforeByContent))
+	for _, nodeID := range beforeByContent {
+		unmatchedBefore[beforeKeys[nodeID].path] = nodeID
+	}
+	for _, nodeID := range unmatchedAfter {
+		key := afterKeys[nodeID]
+		matchID, exists := unmatchedBefore[key.path]
+		if !exists {
+			diff.Added = append(diff.Added, summarizeGoal(after.Nodes[nodeID], key))
+			continue
+		}
+		delete(unmatchedBefore, key.path)
+		change, _ := compareGoals(before.Nodes[matchID], after.Nodes[nodeID], key.path, true)
+		diff.Modified = append(diff.Modified, change)
+	}
+	for _, nodeID := range unmatchedBefore {
+		diff.Removed = append(diff.Removed, summarizeGoal(before.Nodes[nodeID], beforeKeys[nodeID]))
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Path < diff.Added[j].Path })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Path < diff.Removed[j].Path })
+	sort.Slice(diff.Modified, func(i, j int) bool { return diff.Modified[i].Path < diff.Modified[j].Path })
+
+	return diff
+}
+
+// compareGoals compares two matched nodes. The desired state is only
+// compared for nodes matched by path, since a content ID match implies it is
+// the same.
+func compareGoals(before, after *GraphNode, path string, byPath bool) (GoalChange, bool) {
+	change := GoalChange{
+		Path:          path,
+		ActionsBefore: before.ActionNames,
+		ActionsAfter:  after.ActionNames,
+		CostBefore:    before.Cost,
+		CostAfter:     after.Cost,
+		CostDelta:     after.Cost - before.Cost,
+	}
+	if byPath {
+		change.DesiredState = DiffStates(before.DesiredState, after.DesiredState)
+	}
+
+	modified := byPath || change.ActionsChanged() || change.CostDelta != 0
+	return change, modified
+}
+
+func summarizeGoal(node *GraphNode, key goalKey) GoalSummary {
+	return GoalSummary{
+		ContentID:    key.contentID,
+		Path:         key.path,
+		DesiredState: node.DesiredState,
+		ActionNames:  node.ActionNames,
+		Cost:         node.Cost,
+	}
+}
+
+// goalKey identifies a goal across runs. The path is the goal names from the
+// root, with "#n" appended to the nth sibling sharing a name; the content ID
+// additionally covers the desired state.
+type goalKey struct {
+	path      string
+	contentID string
+}
+
+// goalKeys computes the goal key of every node reachable from the root.
+func (g *PlanGraph) goalKeys() map[string]goalKey {
+	keys := make(map[string]goalKey, len(g.Nodes))
+
+	var visit func(nodeID, path string)
+	visit = func(nodeID, path string) {
+		node, exists := g.Nodes[nodeID]
+		if !exists {
+			return
+		}
+		keys[nodeID] = goalKey{path: path, contentID: contentID(path, node.DesiredState)}
+
+		seen := make(map[string]int)
+		for _, childID := range node.ChildIDs {
+			child, exists := g.Nodes[childID]
+			if !exists {
+				continue
+			}
+			visit(childID, childPath(path, child.GoalName, seen))
+		}
+	}
+	if root, exists := g.Nodes[g.RootNodeID]; exists {
+		visit(root.ID, root.GoalName)
+	}
+
+	return keys
+}
+
+// assignContentIDs stores each node's content ID on the node.
+func (g *PlanGraph) assignContentIDs() {
+	for nodeID, key := range g.goalKeys() {
+		g.Nodes[nodeID].ContentID = key.contentID
+	}
+}
+
+// childPath returns the path of a child goal, counting the siblings seen so
+// far with the same name.
+func childPath(path, name string, seen map[string]int) string {
+	seen[name]++
+	if seen[name] > 1 {
+		return fmt.Sprintf("%s/%s#%d", path, name, seen[name])
+	}
+	return path + "/" + name
+}
+
+// nodeIDFor derives a node ID from the goal's content ID.
+func nodeIDFor(path string, desiredState map[string]interface{}) string {
+	return "node_" + contentID(path, desiredState)
+}
+
+// contentID derives a stable ID from a goal's path and desired state. JSON
+// encoding sorts map keys, so equal states hash equally.
+func contentID(path string, desiredState map[string]interface{}) string {
+	stateJSON, _ := json.Marshal(desiredState)
+	sum := sha256.Sum256([]byte(path + "\x00" + string(stateJSON)))
+	return hex.EncodeToString(sum[:6])
+}
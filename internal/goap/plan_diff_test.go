@@ -0,0 +1,120 @@
+package goap
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// buildDiffPlan builds a plan with design, build and optionally docs subgoals.
+func buildDiffPlan(buildActions []Action, withDocs bool, designState WorldState) *HierarchicalPlan {
+	design := NewSimpleAction("Design", "Design", WorldState{}, WorldState{"designed": true}, 2.0, nil)
+	plan := &HierarchicalPlan{
+		Goal: NewGoal("Ship", "Ship", WorldState{"shipped": true}, 1.0),
+		Subplans: []*HierarchicalPlan{
+			{Goal: NewGoal("DesignIt", "Design it", designState, 1.0), Actions: []Action{design}, Depth: 1},
+			{Goal: NewGoal("BuildIt", "Build it", WorldState{"built": true}, 1.0), Actions: buildActions, Depth: 1},
+		},
+	}
+	if withDocs {
+		docs := NewSimpleAction("Docs", "Docs", WorldState{}, WorldState{"documented": true}, 1.0, nil)
+		plan.Subplans = append(plan.Subplans, &HierarchicalPlan{
+			Goal: NewGoal("DocumentIt", "Document it", WorldState{"documented": true}, 1.0), Actions: []Action{docs}, Depth: 1,
+		})
+	}
+	return plan
+}
+
+func TestPlanDiff(t *testing.T) {
+	compile := NewSimpleAction("Compile", "Compile", WorldState{}, WorldState{"built": true}, 3.0, nil)
+	link := NewSimpleAction("Link", "Link", WorldState{}, WorldState{"built": true}, 1.0, nil)
+
+	t.Run("StableContentIDs", func(t *testing.T) {
+		first := BuildGraphFromPlan(buildDiffPlan([]Action{compile}, false, WorldState{"designed": true}), "a")
+		second := BuildGraphFromPlan(buildDiffPlan([]Action{compile, link}, true, WorldState{"designed": true}), "b")
+
+		design := nodeByGoal(t, first, "DesignIt")
+		if design.ContentID == "" {
+			t.Fatal("Expected nodes to have content IDs")
+		}
+		if design.ID != "node_"+design.ContentID {
+			t.Errorf("Expected the node ID to be derived from content ID %s, got %s", design.ContentID, design.ID)
+		}
+		if _, exists := second.Nodes[design.ID]; !exists || nodeByGoal(t, second, "DesignIt").ContentID != design.ContentID {
+			t.Error("The same goal should have the same ID across runs")
+		}
+		if design.ContentID == nodeByGoal(t, first, "BuildIt").ContentID {
+			t.Error("Different goals should have different content IDs")
+		}
+	})
+
+	t.Run("IdenticalPlans", func(t *testing.T) {
+		graph := BuildGraphFromPlan(buildDiffPlan([]Action{compile}, false, WorldState{"designed": true}), "a")
+		diff := DiffGraphs(graph, BuildGraphFromPlan(buildDiffPlan([]Action{compile}, false, WorldState{"designed": true}), "b"))
+
+		if !diff.IsEmpty() || diff.Unchanged != 3 {
+			t.Errorf("Expected no changes and 3 unchanged goals, got %s", diff)
+		}
+	})
+
+	t.Run("AddedRemovedAndModified", func(t *testing.T) {
+		before := BuildGraphFromPlan(buildDiffPlan([]Action{compile}, true, WorldState{"designed": true}), "a")
+		after := BuildGraphFromPlan(buildDiffPlan([]Action{compile, link}, false, WorldState{"designed": true, "reviewed": true}), "b")
+		diff := DiffGraphs(before, after)
+
+		if len(diff.Removed) != 1 || diff.Removed[0].Path != "Ship/DocumentIt" {
+			t.Errorf("Expected DocumentIt to be removed, got %+v", diff.Removed)
+		}
+		if len(diff.Added) != 0 {
+			t.Errorf("Expected no added goals, got %+v", diff.Added)
+		}
+
+		changes := make(map[string]GoalChange)
+		for _, change := range diff.Modified {
+			changes[change.Path] = change
+		}
+		build, exists := changes["Ship/BuildIt"]
+		if !exists || !build.ActionsChanged() || build.CostDelta != 1.0 {
+			t.Errorf("Expected BuildIt's actions to change with cost delta 1, got %+v", build)
+		}
+		design, exists := changes["Ship/DesignIt"]
+		if !exists || design.DesiredState == nil || design.DesiredState.Added["reviewed"] != true {
+			t.Errorf("Expected DesignIt's desired state to gain reviewed, got %+v", design)
+		}
+		if diff.CostDelta != 0 {
+			t.Errorf("Expected the removed docs to offset the link cost, got delta %v", diff.CostDelta)
+		}
+
+		out := diff.String()
+		for _, want := range []string{"- Ship/DocumentIt", "~ Ship/BuildIt", "Compile → Link", "desired + reviewed: true"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+			}
+		}
+
+		data, err := diff.JSON()
+		if err != nil {
+			t.Fatalf("Failed to marshal diff: %v", err)
+		}
+		var decoded PlanDiff
+		if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Modified) != len(diff.Modified) {
+			t.Errorf("JSON output should round-trip, got %v", err)
+		}
+	})
+
+	t.Run("PersistedGraphs", func(t *testing.T) {
+		persistence := NewGraphPersistence(t.TempDir())
+		graph := BuildGraphFromPlan(buildDiffPlan([]Action{compile}, false, WorldState{"designed": true}), "a")
+		if err := persistence.SaveGraph(graph, "run-a"); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+		loaded, err := persistence.LoadGraph("run-a")
+		if err != nil {
+			t.Fatalf("Failed to load graph: %v", err)
+		}
+
+		if diff := DiffGraphs(graph, loaded); !diff.IsEmpty() {
+			t.Errorf("A graph should not differ from its persisted copy, got %s", diff)
+		}
+	})
+}
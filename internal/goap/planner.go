@@ -12,6 +12,9 @@ import (
 type Plan struct {
 	Actions []Action
 	Cost    float64
+
+	// Explanation records why the planner chose these actions
+	Explanation *PlanExplanation
 }
 
 // String returns a string representation of the plan.
@@ -28,9 +31,15 @@ func (p *Plan) String() string {
 	return fmt.Sprintf("Plan (cost: %.2f):\n%s", p.Cost, strings.Join(parts, "\n"))
 }
 
+// defaultMaxIterations bounds the number of node expansions per plan search.
+const defaultMaxIterations = 1000
+
 // Planner finds a sequence of actions to achieve a goal using A* pathfinding.
 type Planner struct {
-	actions []Action
+	actions       []Action
+	diverse       bool
+	explain       bool
+	maxIterations int
 }
 
 // NewPlanner creates a new Planner with the given available actions.
@@ -50,16 +59,86 @@ func (p *Planner) Actions() []Action {
 	return p.actions
 }
 
+// SetDiverse controls whether FindPlans filters near-duplicate plans.
+// When enabled, a candidate plan is rejected if it uses every action of an
+// already accepted plan, i.e. it only reorders those actions or pads them
+// with extra steps that the cheaper plan shows are unnecessary.
+func (p *Planner) SetDiverse(diverse bool) {
+	p.diverse = diverse
+}
+
+// SetExplain controls whether plans found carry an Explanation. Explaining
+// replays every plan and compares it with the other actions, so it is off by
+// default; Explain works either way.
+func (p *Planner) SetExplain(explain bool) {
+	p.explain = explain
+}
+
+// Explains reports whether plans found carry an Explanation.
+func (p *Planner) Explains() bool {
+	return p.explain
+}
+
+// SetMaxIterations bounds the node expansions of each plan search, per plan
+// requested. Zero restores the default of 1000.
+func (p *Planner) SetMaxIterations(n int) {
+	p.maxIterations = n
+}
+
 // FindPlan uses A* pathfinding to find the optimal sequence of actions
 // that will transform the current WorldState to satisfy the goal.
 // Returns nil if no plan can be found.
 func (p *Planner) FindPlan(current WorldState, goal *Goal) *Plan {
-	log.Info("Starting plan search", "goal", goal.Name(), "current", current.String())
+	plans := p.FindPlans(current, goal, 1)
+	if len(plans) == 0 {
+		return nil
+	}
+	return plans[0]
+}
+
+// FindPlans returns up to k distinct plans that achieve the goal, ordered by
+// increasing cost. The first plan is the one FindPlan would return; the rest
+// are precomputed alternatives an executor can fall back to when an action of
+// an earlier plan fails. Returns nil if no plan can be found.
+func (p *Planner) FindPlans(current WorldState, goal *Goal, k int) []*Plan {
+	return p.search(current, goal, k).plans
+}
+
+// FindPlansWithDiagnostics behaves like FindPlans but returns a *PlanFailure
+// error describing why the goal is unreachable when no plan can be found.
+func (p *Planner) FindPlansWithDiagnostics(current WorldState, goal *Goal, k int) ([]*Plan, error) {
+	result := p.search(current, goal, k)
+	if len(result.plans) > 0 {
+		return result.plans, nil
+	}
+	return nil, p.diagnose(current, goal, result)
+}
+
+// searchResult holds the outcome of a plan search along with the statistics
+// needed to explain a failure.
+type searchResult struct {
+	plans             []*Plan
+	closest           *Node
+	iterations        int
+	iterationLimitHit bool
+}
+
+// search runs the k-best A* search shared by the FindPlan variants.
+func (p *Planner) search(current WorldState, goal *Goal, k int) *searchResult {
+	log.Info("Starting plan search", "goal", goal.Name(), "current", current.String(), "k", k)
+
+	if k < 1 {
+		k = 1
+	}
 
 	// Check if goal is already satisfied
 	if goal.IsSatisfied(current) {
 		log.Info("Goal already satisfied, no actions needed")
-		return &Plan{Actions: []Action{}, Cost: 0}
+		plan := &Plan{Actions: []Action{}, Cost: 0}
+		if p.explain {
+			plan.Explanation = p.explainPlan(current, goal, plan, nil)
+		}
+		return &searchResult{plans: []*Plan{plan}}
 	}
 
 	// Initialize A* data structures
@@ -68,41 +147,66 @@ func (p *Planner) FindPlan(current WorldState, goal *Goal) *Plan {
 
 	// Create starting node
 	startNode := &Node{
-		state:    current.Clone(),
-		actions:  []Action{},
-		gCost:    0,
-		hCost:    float64(goal.Distance(current)),
-		parent:   nil,
+		state:   current.Clone(),
+		key:     current.String(),
+		actions: []Action{},
+		gCost:   0,
+		hCost:   float64(goal.Distance(current)),
+		parent:  nil,
 	}
 
 	heap.Push(openSet, startNode)
-	visited := make(map[string]bool)
+	closest := startNode
+
+	// Each state may be expanded up to k times so that the k cheapest paths
+	// through it can all be discovered. With k == 1 this is a plain closed set.
+	expanded := make(map[string]int)
+	plans := make([]*Plan, 0, k)
+	seen := make(map[string]bool)
 
 	iterations := 0
-	maxIterations := 1000 // Prevent infinite loops
+	maxIterations := defaultMaxIterations * k // Prevent infinite loops
+	if p.maxIterations > 0 {
+		maxIterations = p.maxIterations * k
+	}
 
-	for openSet.Len() > 0 && iterations < maxIterations {
+	for openSet.Len() > 0 && iterations < maxIterations && len(plans) < k {
 		iterations++
 
 		// Get node with lowest f-cost
 		currentNode := heap.Pop(openSet).(*Node)
-		stateKey := currentNode.state.String()
+		stateKey := currentNode.key
+
+		// Check if goal is satisfied. Goal nodes are never expanded, so only
+		// accepted plans count towards the state's expansion budget.
+		if goal.IsSatisfied(currentNode.state) {
+			plan := &Plan{
+				Actions: currentNode.actions,
+				Cost:    currentNode.gCost,
+			}
+			signature := planSignature(plan)
+			if seen[signature] || (p.diverse && isNearDuplicate(plan, plans)) {
+				log.Debug("Rejecting duplicate plan", "plan", signature)
+				continue
+			}
+			seen[signature] = true
+			expanded[stateKey]++
+			plans = append(plans, plan)
+			log.Info("Plan found", "rank", len(plans), "actions", len(plan.Actions), "cost", plan.Cost, "iterations", iterations)
+			continue
+		}
 
-		// Check if we've already visited this state
-		if visited[stateKey] {
+		// Check if we've already expanded this state often enough
+		if expanded[stateKey] >= k {
 			continue
 		}
-		visited[stateKey] = true
+		expanded[stateKey]++
 
 		log.Debug("Exploring node", "depth", len(currentNode.actions), "fCost", currentNode.FCost(), "state", stateKey)
 
-		// Check if goal is satisfied
-		if goal.IsSatisfied(currentNode.state) {
-			log.Info("Plan found", "actions", len(currentNode.actions), "cost", currentNode.gCost, "iterations", iterations)
-			return &Plan{
-				Actions: currentNode.actions,
-				Cost:    currentNode.gCost,
-			}
+		// Remember the node closest to the goal for failure diagnostics
+		if currentNode.hCost < closest.hCost {
+			closest = currentNode
 		}
 
 		// Expand neighbors by trying each available action
@@ -113,11 +217,11 @@ func (p *Planner) FindPlan(current WorldState, goal *Goal) *Plan {
 
 			// Create new state by applying action effects
 			newState := currentNode.state.Clone()
-			newState.Apply(action.Effects())
+			ApplyEffects(newState, action)
 
-			// Check if we've already visited this state
+			// Skip no-op actions and states we've already expanded enough
 			newStateKey := newState.String()
-			if visited[newStateKey] {
+			if newStateKey == stateKey || expanded[newStateKey] >= k || currentNode.onPath(newStateKey) {
 				continue
 			}
 
@@ -127,12 +231,13 @@ func (p *Planner) FindPlan(current WorldState, goal *Goal) *Plan {
 			newActions[len(currentNode.actions)] = action
 
 			// Calculate costs
-			newGCost := currentNode.gCost + action.Cost()
+			newGCost := currentNode.gCost + ActionCost(action, currentNode.state)
 			newHCost := float64(goal.Distance(newState))
 
 			// Create neighbor node
 			neighborNode := &Node{
 				state:   newState,
+				key:     newStateKey,
 				actions: newActions,
 				gCost:   newGCost,
 				hCost:   newHCost,
@@ -143,18 +248,72 @@ func (p *Planner) FindPlan(current WorldState, goal *Goal) *Plan {
 		}
 	}
 
-	if iterations >= maxIterations {
+	result := &searchResult{
+		closest:           closest,
+		iterations:        iterations,
+		iterationLimitHit: iterations >= maxIterations && len(plans) < k,
+	}
+
+	if len(plans) > 0 {
+		if p.explain {
+			for i, plan := range plans {
+				plan.Explanation = p.explainPlan(current, goal, plan, plans[i+1:])
+			}
+		}
+		result.plans = plans
+		return result
+	}
+
+	if result.iterationLimitHit {
 		log.Warn("Plan search reached max iterations", "maxIterations", maxIterations)
 	} else {
 		log.Warn("No plan found to achieve goal", "goal", goal.Name())
 	}
 
-	return nil
+	return result
+}
+
+// planSignature returns a key identifying a plan by its action sequence.
+func planSignature(plan *Plan) string {
+	names := make([]string, len(plan.Actions))
+	for i, action := range plan.Actions {
+		names[i] = action.Name()
+	}
+	return sequenceSignature(names)
+}
+
+// sequenceSignature returns the key of an action sequence given by name.
+func sequenceSignature(names []string) string {
+	return strings.Join(names, "\x00")
+}
+
+// isNearDuplicate reports whether the candidate plan uses every action of one
+// of the accepted plans, differing only in order or by additional actions.
+func isNearDuplicate(candidate *Plan, accepted []*Plan) bool {
+	names := make(map[string]bool, len(candidate.Actions))
+	for _, action := range candidate.Actions {
+		names[action.Name()] = true
+	}
+
+	for _, plan := range accepted {
+		covered := true
+		for _, action := range plan.Actions {
+			if !names[action.Name()] {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
 }
 
 // Node represents a state in the A* search.
 type Node struct {
 	state   WorldState
+	key     string // Cached state.String(), used for duplicate detection
 	actions []Action
 	gCost   float64 // Cost from start to this node
 	hCost   float64 // Heuristic cost from this node to goal
@@ -162,6 +321,16 @@ type Node struct {
 	index   int // Required for heap interface
 }
 
+// onPath reports whether the given state key was visited on the path to this node.
+func (n *Node) onPath(stateKey string) bool {
+	for node := n; node != nil; node = node.parent {
+		if node.key == stateKey {
+			return true
+		}
+	}
+	return false
+}
+
 // FCost returns the total estimated cost (g + h).
 func (n *Node) FCost() float64 {
 	return n.gCost + n.hCost
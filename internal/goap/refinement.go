@@ -3,6 +3,7 @@ package goap
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/log"
 )
@@ -20,13 +21,21 @@ type GoalRefiner interface {
 	IsAtomic(goal *Goal, current WorldState) bool
 }
 
+// FeedbackRefiner is an optional GoalRefiner capability for refining a goal
+// again after a previous decomposition of it failed. The failure describes
+// what went wrong so the refiner can avoid repeating it.
+type FeedbackRefiner interface {
+	RefineWithFeedback(ctx context.Context, goal *Goal, current WorldState, failure string) ([]*Goal, error)
+}
+
 // HierarchicalPlanner combines goal refinement with action planning to create
 // a hierarchical planning system. It recursively decomposes goals into subgoals
 // until reaching atomic goals that can be achieved by actions.
 type HierarchicalPlanner struct {
-	planner *Planner
-	refiner GoalRefiner
-	maxDepth int
+	planner      *Planner
+	refiner      GoalRefiner
+	maxDepth     int
+	alternatives int
 }
 
 // NewHierarchicalPlanner creates a new hierarchical planner.
@@ -38,6 +47,12 @@ func NewHierarchicalPlanner(planner *Planner, refiner GoalRefiner, maxDepth int)
 	}
 }
 
+// SetAlternatives sets how many fallback action plans to precompute for each
+// atomic goal, in addition to the cheapest one. Zero disables alternatives.
+func (hp *HierarchicalPlanner) SetAlternatives(n int) {
+	hp.alternatives = n
+}
+
 // PlanHierarchical creates a hierarchical plan to achieve a goal.
 // It recursively refines goals into subgoals until reaching atomic goals,
 // then uses the action planner to find action sequences for each atomic goal.
@@ -68,17 +83,29 @@ func (hp *HierarchicalPlanner) planRecursive(ctx context.Context, current WorldS
 	if hp.refiner.IsAtomic(goal, current) {
 		log.Info("Goal is atomic, finding action plan", "goal", goal.Name())
 
-		// Use the action planner to find a sequence of actions
-		actionPlan := hp.planner.FindPlan(current, goal)
-		if actionPlan == nil {
-			return nil, fmt.Errorf("no action plan found for atomic goal: %s", goal.Name())
+		// Use the action planner to find a sequence of actions. An
+		// explanation needs a runner-up to compare the plan with.
+		k := hp.alternatives + 1
+		if hp.planner.Explains() && k < 2 {
+			k = 2
+		}
+		actionPlans, err := hp.planner.FindPlansWithDiagnostics(current, goal, k)
+		if err != nil {
+			return nil, fmt.Errorf("no action plan found for atomic goal %s: %w", goal.Name(), err)
+		}
+		alternatives := actionPlans[1:]
+		if len(alternatives) > hp.alternatives {
+			alternatives = alternatives[:hp.alternatives]
 		}
 
 		return &HierarchicalPlan{
-			Goal:     goal,
-			Subplans: nil,
-			Actions:  actionPlan.Actions,
-			Depth:    depth,
+			Goal:         goal,
+			Subplans:     nil,
+			Actions:      actionPlans[0].Actions,
+			Alternatives: alternatives,
+			Explanation:  actionPlans[0].Explanation,
+			Cost:         actionPlans[0].Cost,
+			Depth:        depth,
 		}, nil
 	}
 
@@ -89,33 +116,86 @@ func (hp *HierarchicalPlanner) planRecursive(ctx context.Context, current WorldS
 		return nil, fmt.Errorf("failed to refine goal %s: %w", goal.Name(), err)
 	}
 
+	return hp.planSubgoals(ctx, current, goal, subgoals, depth)
+}
+
+// Rerefine plans a goal again after an earlier plan for it failed, without
+// touching the rest of the hierarchy. The goal is always refined, even if the
+// refiner considers it atomic, since its action plan is what failed. The
+// failure reason is passed to refiners implementing FeedbackRefiner so they
+// can propose a different decomposition.
+func (hp *HierarchicalPlanner) Rerefine(ctx context.Context, current WorldState, goal *Goal, depth int, failure string) (*HierarchicalPlan, error) {
+	log.Info("Re-refining failed goal", "goal", goal.Name(), "depth", depth, "failure", failure)
+
+	if depth > hp.maxDepth {
+		return nil, fmt.Errorf("maximum planning depth exceeded: %d", hp.maxDepth)
+	}
+
+	var subgoals []*Goal
+	var err error
+	if feedbackRefiner, ok := hp.refiner.(FeedbackRefiner); ok {
+		subgoals, err = feedbackRefiner.RefineWithFeedback(ctx, goal, current, failure)
+	} else {
+		subgoals, err = hp.refiner.Refine(ctx, goal, current)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-refine goal %s: %w", goal.Name(), err)
+	}
+
+	return hp.planSubgoals(ctx, current, goal, subgoals, depth)
+}
+
+// RerefineNode re-refines the goal of a failed node in a persisted plan graph
+// and replaces the node's subtree with the new plan, leaving the rest of the
+// graph, including completed siblings, intact. It returns the new subplan so
+// the caller can register its actions for execution.
+func (hp *HierarchicalPlanner) RerefineNode(ctx context.Context, graph *PlanGraph, nodeID string, current WorldState, failure string) (*HierarchicalPlan, error) {
+	node, exists := graph.Nodes[nodeID]
+	if !exists {
+		return nil, fmt.Errorf("node not found: %s", nodeID)
+	}
+
+	goal := NewGoal(node.GoalName, node.GoalDesc, WorldState(node.DesiredState), 1.0)
+
+	plan, err := hp.Rerefine(ctx, current, goal, node.Depth, failure)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := graph.ReplaceSubtree(nodeID, plan); err != nil {
+		return nil, fmt.Errorf("failed to replace subtree of node %s: %w", nodeID, err)
+	}
+
+	return plan, nil
+}
+
+// planSubgoals recursively plans each subgoal of a refined goal in order.
+func (hp *HierarchicalPlanner) planSubgoals(ctx context.Context, current WorldState, goal *Goal, subgoals []*Goal, depth int) (*HierarchicalPlan, error) {
 	if len(subgoals) == 0 {
 		return nil, fmt.Errorf("goal refinement produced no subgoals: %s", goal.Name())
 	}
 
 	log.Info("Goal refined", "goal", goal.Name(), "numSubgoals", len(subgoals))
 
+	// Plan subgoals in dependency order, so each is planned from a state
+	// that includes the effects of the subgoals it depends on
+	subgoals, err := orderSubgoals(subgoals)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subgoal dependencies for %s: %w", goal.Name(), err)
+	}
+
 	// Recursively plan for each subgoal
 	subplans := make([]*HierarchicalPlan, 0, len(subgoals))
-	workingState := current.Clone()
 
 	for i, subgoal := range subgoals {
 		log.Info("Planning subgoal", "index", i, "subgoal", subgoal.Name())
 
-		subplan, err := hp.planRecursive(ctx, workingState, subgoal, depth+1)
+		subplan, err := hp.planRecursive(ctx, subgoalState(current, subgoals, subplans, i), subgoal, depth+1)
 		if err != nil {
 			return nil, fmt.Errorf("failed to plan subgoal %s: %w", subgoal.Name(), err)
 		}
 
 		subplans = append(subplans, subplan)
-
-		// Update working state with the effects of this subplan
-		// This ensures subsequent subgoals can depend on earlier ones
-		if subplan.Actions != nil {
-			for _, action := range subplan.Actions {
-				workingState.Apply(action.Effects())
-			}
-		}
 	}
 
 	return &HierarchicalPlan{
@@ -126,14 +206,117 @@ func (hp *HierarchicalPlanner) planRecursive(ctx context.Context, current WorldS
 	}, nil
 }
 
+// subgoalState returns the state the i-th of the ordered subgoals is planned
+// from: the current state plus the effects of the subplans of the subgoals
+// it transitively depends on. Subgoals without declared dependencies run in
+// sequence, so they get the effects of every earlier sibling. Independent
+// siblings may run concurrently, so their effects cannot be assumed.
+func subgoalState(current WorldState, subgoals []*Goal, subplans []*HierarchicalPlan, i int) WorldState {
+	state := current.Clone()
+
+	declared := false
+	for _, subgoal := range subgoals {
+		declared = declared || subgoal.DeclaresDependencies()
+	}
+
+	required := make(map[string]bool)
+	var require func(goal *Goal)
+	require = func(goal *Goal) {
+		for _, dependency := range goal.DependsOn() {
+			if !required[dependency] {
+				required[dependency] = true
+				for _, sibling := range subgoals {
+					if sibling.Name() == dependency {
+						require(sibling)
+					}
+				}
+			}
+		}
+	}
+	require(subgoals[i])
+
+	// Earlier subplans are applied in order, which is a topological order
+	for j, subplan := range subplans[:i] {
+		if declared && !required[subgoals[j].Name()] {
+			continue
+		}
+		for _, action := range subplan.AllActions() {
+			ApplyEffects(state, action)
+		}
+	}
+	return state
+}
+
+// orderSubgoals sorts subgoals topologically by their declared dependencies,
+// keeping the given order among subgoals that are ready at the same time.
+// Subgoals without declared dependencies are returned unchanged.
+func orderSubgoals(subgoals []*Goal) ([]*Goal, error) {
+	declared := false
+	byName := make(map[string]*Goal, len(subgoals))
+	for _, subgoal := range subgoals {
+		declared = declared || subgoal.DeclaresDependencies()
+		if _, exists := byName[subgoal.Name()]; exists {
+			return nil, fmt.Errorf("duplicate subgoal name: %s", subgoal.Name())
+		}
+		byName[subgoal.Name()] = subgoal
+	}
+	if !declared {
+		return subgoals, nil
+	}
+
+	for _, subgoal := range subgoals {
+		for _, dependency := range subgoal.DependsOn() {
+			if _, exists := byName[dependency]; !exists {
+				return nil, fmt.Errorf("subgoal %s depends on unknown subgoal %s", subgoal.Name(), dependency)
+			}
+		}
+	}
+
+	ordered := make([]*Goal, 0, len(subgoals))
+	done := make(map[string]bool, len(subgoals))
+	for len(ordered) < len(subgoals) {
+		progressed := false
+		for _, subgoal := range subgoals {
+			if done[subgoal.Name()] || !dependenciesDone(subgoal, done) {
+				continue
+			}
+			ordered = append(ordered, subgoal)
+			done[subgoal.Name()] = true
+			progressed = true
+		}
+		if !progressed {
+			return nil, fmt.Errorf("subgoal dependencies contain a cycle")
+		}
+	}
+
+	return ordered, nil
+}
+
+// dependenciesDone reports whether every dependency of the goal is done.
+func dependenciesDone(goal *Goal, done map[string]bool) bool {
+	for _, dependency := range goal.DependsOn() {
+		if !done[dependency] {
+			return false
+		}
+	}
+	return true
+}
+
 // HierarchicalPlan represents a hierarchical plan that may contain subplans.
 // Leaf nodes (atomic goals) have Actions but no Subplans.
 // Internal nodes (composite goals) have Subplans but no Actions.
+// Leaf nodes may also carry Alternatives: costlier action plans for the same
+// goal, ranked by cost, to fall back to if Actions fails during execution.
+// Cost is the planner's cost of Actions in the state the goal was planned
+// from, which may differ from the actions' static costs.
 type HierarchicalPlan struct {
-	Goal     *Goal
-	Subplans []*HierarchicalPlan
-	Actions  []Action
-	Depth    int
+	Goal         *Goal
+	Subplans     []*HierarchicalPlan
+	Actions      []Action
+	Alternatives []*Plan
+	Explanation  *PlanExplanation
+	Cost         float64
+	Depth        int
 }
 
 // IsAtomic returns true if this plan node is atomic (has actions, no subplans).
@@ -141,6 +324,27 @@ func (hp *HierarchicalPlan) IsAtomic() bool {
 	return len(hp.Subplans) == 0
 }
 
+// TotalCost returns the cost of the plan: the planner's cost for atomic
+// plans and the sum of the subplans' costs for composite ones. Atomic plans
+// built without a planner cost fall back to the actions' static costs.
+func (hp *HierarchicalPlan) TotalCost() float64 {
+	if !hp.IsAtomic() {
+		total := 0.0
+		for _, subplan := range hp.Subplans {
+			total += subplan.TotalCost()
+		}
+		return total
+	}
+	if hp.Cost != 0 {
+		return hp.Cost
+	}
+	total := 0.0
+	for _, action := range hp.Actions {
+		total += action.Cost()
+	}
+	return total
+}
+
 // AllActions returns all actions in this plan and its subplans, in execution order.
 func (hp *HierarchicalPlan) AllActions() []Action {
 	if hp.IsAtomic() {
@@ -154,6 +358,19 @@ func (hp *HierarchicalPlan) AllActions() []Action {
 	return allActions
 }
 
+// AlternativeActions returns the actions of all fallback plans in this plan
+// and its subplans. These are not part of the primary execution order.
+func (hp *HierarchicalPlan) AlternativeActions() []Action {
+	var actions []Action
+	for _, alternative := range hp.Alternatives {
+		actions = append(actions, alternative.Actions...)
+	}
+	for _, subplan := range hp.Subplans {
+		actions = append(actions, subplan.AlternativeActions()...)
+	}
+	return actions
+}
+
 // Execute executes the hierarchical plan, running all actions in order.
 func (hp *HierarchicalPlan) Execute(ctx context.Context, current WorldState) error {
 	if hp.IsAtomic() {
@@ -163,6 +380,7 @@ func (hp *HierarchicalPlan) Execute(ctx context.Context, current WorldState) err
 			if err := action.Execute(ctx, current); err != nil {
 				return fmt.Errorf("action %s failed: %w", action.Name(), err)
 			}
+			current.Invalidate(ActionInvalidates(action))
 		}
 		return nil
 	}
@@ -177,6 +395,23 @@ func (hp *HierarchicalPlan) Execute(ctx context.Context, current WorldState) err
 	return nil
 }
 
+// Explain returns the planner's explanation for every atomic node in the
+// plan, in execution order.
+func (hp *HierarchicalPlan) Explain() string {
+	if hp.IsAtomic() {
+		if hp.Explanation == nil {
+			return fmt.Sprintf("Plan for %s: no explanation recorded\n", hp.Goal.Name())
+		}
+		return hp.Explanation.String()
+	}
+
+	var b strings.Builder
+	for _, subplan := range hp.Subplans {
+		b.WriteString(subplan.Explain())
+	}
+	return b.String()
+}
+
 // String returns a string representation of the hierarchical plan.
 func (hp *HierarchicalPlan) String() string {
 	return hp.stringWithIndent(0)
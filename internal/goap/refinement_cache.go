@@ -0,0 +1,203 @@
+package goap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"upside-down-research.com/oss/agentic/internal/fsutil"
+)
+
+// RefinementCacheDir is the conventional name of the refinement cache
+// directory below an output path.
+const RefinementCacheDir = "refinement-cache"
+
+// RefinementCacheKey identifies a refinement. Two refinement requests with
+// equal keys are answered with the same subgoals.
+type RefinementCacheKey struct {
+	GoalName        string                 `json:"goal_name"`
+	GoalDescription string                 `json:"goal_description"`
+	DesiredState    map[string]interface{} `json:"desired_state"`
+	// State holds the values of the world-state keys relevant to the goal;
+	// other keys, such as timestamps, do not affect the key
+	State map[string]interface{} `json:"state"`
+	// RefinerVersion changes whenever the refiner would answer differently,
+	// e.g. because its prompt, model or action vocabulary changed
+	RefinerVersion string `json:"refiner_version"`
+}
+
+// Hash returns the key's file name in the cache. Maps marshal with sorted
+// keys, so the hash is stable.
+func (k RefinementCacheKey) Hash() string {
+	data, err := json.Marshal(k)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", k))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CachedRefinement is a refinement stored in the cache.
+type CachedRefinement struct {
+	Key        RefinementCacheKey `json:"key"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Refinement GoalRefinement     `json:"refinement"`
+}
+
+// RefinementCache memoizes goal refinements on disk, one JSON file per key,
+// so that planning the same goal again, in this or a later run, does not ask
+// the LLM again. Entries expire after a TTL. It is safe for concurrent use.
+type RefinementCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+	mu  sync.Mutex
+}
+
+// NewRefinementCache creates a cache in dir. Entries older than ttl are
+// ignored and removed; a ttl of zero keeps them until invalidated.
+func NewRefinementCache(dir string, ttl time.Duration) *RefinementCache {
+	return &RefinementCache{
+		dir: dir,
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// Dir returns the cache directory.
+func (rc *RefinementCache) Dir() string {
+	return rc.dir
+}
+
+// Get returns the cached refinement for a key, if there is an unexpired one.
+func (rc *RefinementCache) Get(key RefinementCacheKey) (*GoalRefinement, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	path := rc.path(key)
+	entry, err := readCachedRefinement(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn("Ignoring unreadable refinement cache entry", "path", path, "error", err)
+		}
+		return nil, false
+	}
+	if rc.expired(entry) {
+		os.Remove(path)
+		return nil, false
+	}
+	return &entry.Refinement, true
+}
+
+// Put stores a refinement, replacing any cached one for the key.
+func (rc *RefinementCache) Put(key RefinementCacheKey, refinement *GoalRefinement) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if err := os.MkdirAll(rc.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create refinement cache directory: %w", err)
+	}
+	entryJSON, err := json.MarshalIndent(CachedRefinement{
+		Key:        key,
+		CreatedAt:  rc.now(),
+		Refinement: *refinement,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal refinement: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(rc.path(key), entryJSON, 0644); err != nil {
+		return fmt.Errorf("failed to write refinement cache entry: %w", err)
+	}
+	return nil
+}
+
+// Invalidate removes the cached refinements of the named goal, or all of
+// them if goalName is empty, and returns how many were removed.
+func (rc *RefinementCache) Invalidate(goalName string) (int, error) {
+	return rc.remove(func(entry *CachedRefinement) bool {
+		return goalName == "" || entry.Key.GoalName == goalName
+	})
+}
+
+// Prune removes expired refinements and returns how many were removed.
+func (rc *RefinementCache) Prune() (int, error) {
+	return rc.remove(rc.expired)
+}
+
+// remove deletes the entries matching a predicate. Unreadable entries are
+// removed too, since they can never be hit.
+func (rc *RefinementCache) remove(matches func(entry *CachedRefinement) bool) (int, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(rc.dir, "*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list refinement cache: %w", err)
+	}
+	sort.Strings(paths)
+
+	removed := 0
+	for _, path := range paths {
+		entry, err := readCachedRefinement(path)
+		if err == nil && !matches(entry) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove refinement cache entry: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (rc *RefinementCache) path(key RefinementCacheKey) string {
+	return filepath.Join(rc.dir, key.Hash()+".json")
+}
+
+func (rc *RefinementCache) expired(entry *CachedRefinement) bool {
+	return rc.ttl > 0 && rc.now().Sub(entry.CreatedAt) > rc.ttl
+}
+
+func readCachedRefinement(path string) (*CachedRefinement, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry CachedRefinement
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse refinement cache entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// relevantState returns the values current has for the given keys.
+func relevantState(current WorldState, keys []string) map[string]interface{} {
+	state := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if current.Has(key) {
+			state[key] = current.Get(key)
+		}
+	}
+	return state
+}
+
+// vocabularyFingerprint hashes the names, preconditions and effects of a set
+// of actions, so that cached refinements grounded in other actions miss.
+func vocabularyFingerprint(actions []Action) string {
+	lines := make([]string, len(actions))
+	for i, action := range actions {
+		lines[i] = fmt.Sprintf("%s|%s|%s", action.Name(), action.Preconditions().String(), action.Effects().String())
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
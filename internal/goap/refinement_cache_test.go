@@ -0,0 +1,132 @@
+package goap
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefinementCache(t *testing.T) {
+	goal := NewGoal("Deliver", "Deliver tested code", WorldState{"code_written": true, "tests_passed": true}, 1.0)
+	first := `{"subgoals": [
+		{"name": "Write", "description": "Write", "desired_state": {"code_written": true}},
+		{"name": "Test", "description": "Test", "desired_state": {"tests_passed": true}, "depends_on": ["Write"]}
+	]}`
+	second := `{"subgoals": [
+		{"name": "WriteAndTest", "description": "Both", "desired_state": {"code_written": true, "tests_passed": true}}
+	]}`
+
+	newRefiner := func(cache *RefinementCache, responses ...string) (*LLMGoalRefiner, *scriptedLLM) {
+		server := &scriptedLLM{responses: responses}
+		refiner := NewLLMGoalRefiner(server, "job", "agent")
+		refiner.SetCache(cache)
+		return refiner, server
+	}
+
+	t.Run("ReusesRefinementsAcrossRefiners", func(t *testing.T) {
+		cache := NewRefinementCache(t.TempDir(), time.Hour)
+		refiner, server := newRefiner(cache, first)
+		state := WorldState{"started_at": 1}
+
+		subgoals, err := refiner.Refine(context.Background(), goal, state)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// A new refiner, as in a rerun, and an irrelevant key that changed
+		rerun, rerunServer := newRefiner(cache, second)
+		cached, err := rerun.Refine(context.Background(), goal, WorldState{"started_at": 2})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(server.prompts)+len(rerunServer.prompts) != 1 {
+			t.Errorf("Expected a single LLM query, got %d", len(server.prompts)+len(rerunServer.prompts))
+		}
+		if len(cached) != len(subgoals) {
+			t.Fatalf("Expected %d cached subgoals, got %d", len(subgoals), len(cached))
+		}
+		for i := range subgoals {
+			if cached[i].Name() != subgoals[i].Name() || cached[i].DesiredState().String() != subgoals[i].DesiredState().String() {
+				t.Errorf("Expected cached subgoal %v, got %v", subgoals[i], cached[i])
+			}
+		}
+		if deps := cached[1].DependsOn(); len(deps) != 1 || deps[0] != "Write" {
+			t.Errorf("Expected cached dependencies, got %v", deps)
+		}
+	})
+
+	t.Run("MissesOnRelevantStateAndVersion", func(t *testing.T) {
+		cache := NewRefinementCache(t.TempDir(), time.Hour)
+		refiner, server := newRefiner(cache, first, first, first)
+
+		refiner.Refine(context.Background(), goal, NewWorldState())
+		refiner.Refine(context.Background(), goal, WorldState{"code_written": false})
+		refiner.SetVersion("llm-refiner/custom")
+		refiner.Refine(context.Background(), goal, NewWorldState())
+
+		if len(server.prompts) != 3 {
+			t.Errorf("Expected 3 LLM queries, got %d", len(server.prompts))
+		}
+	})
+
+	t.Run("ExpiresAfterTTL", func(t *testing.T) {
+		cache := NewRefinementCache(t.TempDir(), time.Hour)
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+		refiner, server := newRefiner(cache, first, first)
+
+		refiner.Refine(context.Background(), goal, NewWorldState())
+		now = now.Add(2 * time.Hour)
+
+		removed, err := cache.Prune()
+		if err != nil || removed != 1 {
+			t.Errorf("Expected to prune 1 expired refinement, got %d, %v", removed, err)
+		}
+		refiner.Refine(context.Background(), goal, NewWorldState())
+		if len(server.prompts) != 2 {
+			t.Errorf("Expected the expired refinement to be queried again, got %d queries", len(server.prompts))
+		}
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		cache := NewRefinementCache(t.TempDir(), 0)
+		other := NewGoal("Other", "Other", WorldState{"docs_written": true}, 1.0)
+		docs := `{"subgoals": [{"name": "Docs", "description": "Docs", "desired_state": {"docs_written": true}}]}`
+		refiner, server := newRefiner(cache, first, docs, first)
+
+		refiner.Refine(context.Background(), goal, NewWorldState())
+		refiner.Refine(context.Background(), other, NewWorldState())
+
+		removed, err := cache.Invalidate("Deliver")
+		if err != nil || removed != 1 {
+			t.Errorf("Expected to invalidate 1 refinement, got %d, %v", removed, err)
+		}
+		refiner.Refine(context.Background(), goal, NewWorldState())
+		refiner.Refine(context.Background(), other, NewWorldState())
+		if len(server.prompts) != 3 {
+			t.Errorf("Expected only the invalidated goal to be queried again, got %d queries", len(server.prompts))
+		}
+
+		removed, _ = cache.Invalidate("")
+		if removed != 2 {
+			t.Errorf("Expected to invalidate all 2 refinements, got %d", removed)
+		}
+	})
+
+	t.Run("FeedbackReplacesCachedRefinement", func(t *testing.T) {
+		cache := NewRefinementCache(t.TempDir(), time.Hour)
+		refiner, server := newRefiner(cache, first, second)
+
+		refiner.Refine(context.Background(), goal, NewWorldState())
+		refiner.RefineWithFeedback(context.Background(), goal, NewWorldState(), "Test failed")
+
+		subgoals, err := refiner.Refine(context.Background(), goal, NewWorldState())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(server.prompts) != 2 || len(subgoals) != 1 || subgoals[0].Name() != "WriteAndTest" {
+			t.Errorf("Expected the re-refinement to be cached, got %v after %d queries", subgoals, len(server.prompts))
+		}
+	})
+}
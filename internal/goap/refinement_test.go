@@ -2,6 +2,7 @@ package goap
 
 import (
 	"context"
+	"strings"
 	"testing"
 )
 
@@ -433,3 +434,231 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// FeedbackMockGoalRefiner records the failures passed to RefineWithFeedback
+type FeedbackMockGoalRefiner struct {
+	*MockGoalRefiner
+	failures []string
+}
+
+func (m *FeedbackMockGoalRefiner) RefineWithFeedback(ctx context.Context, goal *Goal, current WorldState, failure string) ([]*Goal, error) {
+	m.failures = append(m.failures, failure)
+	return m.Refine(ctx, goal, current)
+}
+
+func TestRerefinement(t *testing.T) {
+	noop := func(ctx context.Context, ws WorldState) error { return nil }
+
+	setup := func() (*HierarchicalPlanner, *FeedbackMockGoalRefiner, *PlanGraph) {
+		actionA := NewSimpleAction("DoA", "Do A", WorldState{}, WorldState{"a": true}, 1.0, noop)
+		actionB := NewSimpleAction("DoB", "Do B", WorldState{}, WorldState{"b": true}, 1.0, noop)
+		actionB1 := NewSimpleAction("DoB1", "Do B1", WorldState{}, WorldState{"b1": true}, 1.0, noop)
+		actionB2 := NewSimpleAction("DoB2", "Do B2", WorldState{"b1": true}, WorldState{"b": true}, 1.0, noop)
+
+		refiner := &FeedbackMockGoalRefiner{MockGoalRefiner: NewMockGoalRefiner()}
+		refiner.AddRefinement("Root", []*Goal{
+			NewGoal("GoalA", "Achieve A", WorldState{"a": true}, 2.0),
+			NewGoal("GoalB", "Achieve B", WorldState{"b": true}, 1.0),
+		})
+
+		hp := NewHierarchicalPlanner(NewPlanner([]Action{actionA, actionB, actionB1, actionB2}), refiner, 5)
+		plan, err := hp.PlanHierarchical(context.Background(), NewWorldState(),
+			NewGoal("Root", "Achieve A and B", WorldState{"a": true, "b": true}, 3.0))
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+
+		// GoalA completed, GoalB failed
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		nodeByGoal(t, graph, "Root").Status = StatusFailed
+		nodeByGoal(t, graph, "GoalA").Status = StatusCompleted
+		nodeByGoal(t, graph, "GoalB").Status = StatusFailed
+
+		refiner.AddRefinement("GoalB", []*Goal{
+			NewGoal("GoalB1", "Achieve B1", WorldState{"b1": true}, 2.0),
+			NewGoal("GoalB2", "Achieve B via B1", WorldState{"b": true}, 1.0),
+		})
+
+		return hp, refiner, graph
+	}
+
+	t.Run("ReplaceFailedSubtree", func(t *testing.T) {
+		hp, refiner, graph := setup()
+		goalB := nodeByGoal(t, graph, "GoalB").ID
+
+		failed, ok := graph.DeepestFailedNode()
+		if !ok || failed.ID != goalB {
+			t.Fatalf("Expected GoalB to be the deepest failed node, got %v", failed)
+		}
+
+		subplan, err := hp.RerefineNode(context.Background(), graph, failed.ID, WorldState{"a": true}, "action DoB failed")
+		if err != nil {
+			t.Fatalf("Re-refinement failed: %v", err)
+		}
+
+		if len(refiner.failures) != 1 || refiner.failures[0] != "action DoB failed" {
+			t.Errorf("Expected failure to be passed to the refiner, got %v", refiner.failures)
+		}
+		if len(subplan.Subplans) != 2 {
+			t.Errorf("Expected 2 subplans, got %d", len(subplan.Subplans))
+		}
+
+		node := graph.Nodes[goalB]
+		if node.IsAtomic || node.Revision != 1 || node.ParentID != graph.RootNodeID || node.Depth != 1 {
+			t.Errorf("Unexpected re-refined node: %+v", node)
+		}
+		goalB1 := nodeByGoal(t, graph, "GoalB1")
+		if len(node.ChildIDs) != 2 || node.ChildIDs[0] != goalB1.ID || node.ChildIDs[1] != nodeByGoal(t, graph, "GoalB2").ID {
+			t.Errorf("Expected new children GoalB1 and GoalB2, got %v", node.ChildIDs)
+		}
+		if goalB1.Depth != 2 || goalB1.ParentID != goalB || goalB1.ID != "node_"+goalB1.ContentID {
+			t.Errorf("Unexpected new child: %+v", goalB1)
+		}
+
+		if nodeByGoal(t, graph, "GoalA").Status != StatusCompleted {
+			t.Error("Completed sibling should be kept intact")
+		}
+		if len(graph.Nodes) != 5 || graph.Metadata.TotalNodes != 5 || graph.Metadata.MaxDepth != 2 {
+			t.Errorf("Unexpected graph size: %d nodes, metadata %+v", len(graph.Nodes), graph.Metadata)
+		}
+	})
+
+	t.Run("SupersededDescendants", func(t *testing.T) {
+		hp, _, graph := setup()
+		goalB := nodeByGoal(t, graph, "GoalB").ID
+
+		ctx := context.Background()
+		if _, err := hp.RerefineNode(ctx, graph, goalB, NewWorldState(), "first failure"); err != nil {
+			t.Fatalf("First re-refinement failed: %v", err)
+		}
+		first := append([]string{}, graph.Nodes[goalB].ChildIDs...)
+		if _, err := hp.RerefineNode(ctx, graph, goalB, NewWorldState(), "second failure"); err != nil {
+			t.Fatalf("Second re-refinement failed: %v", err)
+		}
+
+		node := graph.Nodes[goalB]
+		if node.Revision != 2 {
+			t.Errorf("Expected revision 2, got %d", node.Revision)
+		}
+		if len(node.Superseded) != 2 || node.Superseded[0] != first[0] || node.Superseded[1] != first[1] {
+			t.Errorf("Expected %v to be superseded, got %v", first, node.Superseded)
+		}
+		if _, exists := graph.Nodes[first[0]]; exists {
+			t.Error("Superseded node should be removed")
+		}
+		if node.ChildIDs[0] != first[0]+"_r2" {
+			t.Errorf("New children should not reuse superseded IDs, got %v", node.ChildIDs)
+		}
+	})
+
+	t.Run("PlainRefinerFallback", func(t *testing.T) {
+		refiner := NewMockGoalRefiner()
+		refiner.AddRefinement("Goal", []*Goal{NewGoal("Sub", "Sub", WorldState{"x": true}, 1.0)})
+		action := NewSimpleAction("DoX", "Do X", WorldState{}, WorldState{"x": true}, 1.0, noop)
+		hp := NewHierarchicalPlanner(NewPlanner([]Action{action}), refiner, 5)
+
+		plan, err := hp.Rerefine(context.Background(), NewWorldState(),
+			NewGoal("Goal", "Goal", WorldState{"x": true}, 1.0), 0, "failed")
+		if err != nil {
+			t.Fatalf("Re-refinement failed: %v", err)
+		}
+		if len(plan.Subplans) != 1 {
+			t.Errorf("Expected 1 subplan, got %d", len(plan.Subplans))
+		}
+	})
+}
+
+func TestSubgoalDependencies(t *testing.T) {
+	noop := func(ctx context.Context, ws WorldState) error { return nil }
+	actions := []Action{
+		NewSimpleAction("DoA", "Do A", WorldState{}, WorldState{"a": true}, 1.0, noop),
+		NewSimpleAction("DoB", "Do B", WorldState{"a": true}, WorldState{"b": true}, 1.0, noop),
+	}
+
+	t.Run("PlanInDependencyOrder", func(t *testing.T) {
+		goalA := NewGoal("GoalA", "A", WorldState{"a": true}, 1.0)
+		goalA.SetDependsOn()
+		goalB := NewGoal("GoalB", "B needs A", WorldState{"b": true}, 2.0)
+		goalB.SetDependsOn("GoalA")
+
+		refiner := NewMockGoalRefiner()
+		refiner.AddRefinement("Root", []*Goal{goalB, goalA})
+
+		hp := NewHierarchicalPlanner(NewPlanner(actions), refiner, 3)
+		plan, err := hp.PlanHierarchical(context.Background(), NewWorldState(),
+			NewGoal("Root", "Both", WorldState{"a": true, "b": true}, 1.0))
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+
+		if plan.Subplans[0].Goal.Name() != "GoalA" || plan.Subplans[1].Goal.Name() != "GoalB" {
+			t.Errorf("Expected GoalA before GoalB, got %s, %s", plan.Subplans[0].Goal.Name(), plan.Subplans[1].Goal.Name())
+		}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		edges := graph.Edges()
+		if len(edges) != 1 || graph.Nodes[edges[0].From].GoalName != "GoalB" || graph.Nodes[edges[0].To].GoalName != "GoalA" {
+			t.Errorf("Expected a single edge from GoalB to GoalA, got %v", edges)
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		goalA := NewGoal("GoalA", "A", WorldState{"a": true}, 1.0)
+		goalA.SetDependsOn("GoalB")
+		goalB := NewGoal("GoalB", "B", WorldState{"b": true}, 1.0)
+		goalB.SetDependsOn("GoalA")
+
+		refiner := NewMockGoalRefiner()
+		refiner.AddRefinement("Root", []*Goal{goalA, goalB})
+
+		hp := NewHierarchicalPlanner(NewPlanner(actions), refiner, 3)
+		_, err := hp.PlanHierarchical(context.Background(), NewWorldState(),
+			NewGoal("Root", "Both", WorldState{"a": true, "b": true}, 1.0))
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Errorf("Expected a cycle error, got %v", err)
+		}
+	})
+
+	t.Run("IndependentSiblingsAssumeNoEffects", func(t *testing.T) {
+		goalA := NewGoal("GoalA", "A", WorldState{"a": true}, 1.0)
+		goalA.SetDependsOn()
+		goalB := NewGoal("GoalB", "B, concurrently with A", WorldState{"b": true}, 1.0)
+		goalB.SetDependsOn()
+
+		refiner := NewMockGoalRefiner()
+		refiner.AddRefinement("Root", []*Goal{goalA, goalB})
+
+		hp := NewHierarchicalPlanner(NewPlanner(actions), refiner, 3)
+		plan, err := hp.PlanHierarchical(context.Background(), NewWorldState(),
+			NewGoal("Root", "Both", WorldState{"a": true, "b": true}, 1.0))
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+
+		// GoalA may not have run when GoalB starts, so GoalB produces a itself
+		if names := actionNames(plan.Subplans[1].Actions); strings.Join(names, ",") != "DoA,DoB" {
+			t.Errorf("Expected GoalB to be planned from the initial state, got %v", names)
+		}
+	})
+
+	t.Run("UndeclaredDependenciesAreSequential", func(t *testing.T) {
+		refiner := NewMockGoalRefiner()
+		refiner.AddRefinement("Root", []*Goal{
+			NewGoal("GoalA", "A", WorldState{"a": true}, 1.0),
+			NewGoal("GoalB", "B", WorldState{"b": true}, 1.0),
+		})
+
+		hp := NewHierarchicalPlanner(NewPlanner(actions), refiner, 3)
+		plan, err := hp.PlanHierarchical(context.Background(), NewWorldState(),
+			NewGoal("Root", "Both", WorldState{"a": true, "b": true}, 1.0))
+		if err != nil {
+			t.Fatalf("Planning failed: %v", err)
+		}
+
+		graph := BuildGraphFromPlan(plan, "test-agent")
+		first, second := nodeByGoal(t, graph, "GoalA"), nodeByGoal(t, graph, "GoalB")
+		if len(second.DependsOn) != 1 || second.DependsOn[0] != first.ID {
+			t.Errorf("Expected GoalB to depend on GoalA, got %v", second.DependsOn)
+		}
+	})
+}
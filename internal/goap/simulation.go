@@ -0,0 +1,158 @@
+package goap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PreconditionError reports an action whose preconditions do not hold in
+// the state it would execute in.
+type PreconditionError struct {
+	Action string
+
+	// Unmet maps each unmet precondition key to the required value
+	Unmet map[string]interface{}
+
+	// Actual maps each unmet precondition key to its value in the state, or
+	// nil if the key is not set
+	Actual map[string]interface{}
+}
+
+// Error implements the error interface.
+func (pe *PreconditionError) Error() string {
+	keys := make([]string, 0, len(pe.Unmet))
+	for key := range pe.Unmet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	unmet := make([]string, len(keys))
+	for i, key := range keys {
+		unmet[i] = fmt.Sprintf("%s=%v (have %v)", key, pe.Unmet[key], pe.Actual[key])
+	}
+	return fmt.Sprintf("preconditions of %s not met: %s", pe.Action, strings.Join(unmet, ", "))
+}
+
+// checkPreconditions returns a *PreconditionError if any precondition of the
+// action does not hold in state.
+func checkPreconditions(action Action, state WorldState) error {
+	if action.CanExecute(state) {
+		return nil
+	}
+
+	unmet := make(map[string]interface{})
+	actual := make(map[string]interface{})
+	for key, value := range action.Preconditions() {
+		if !state.Matches(WorldState{key: value}) {
+			unmet[key] = value
+			actual[key] = state.Get(key)
+		}
+	}
+	return &PreconditionError{Action: action.Name(), Unmet: unmet, Actual: actual}
+}
+
+// SimulatedNode is the outcome of one node in a dry run.
+type SimulatedNode struct {
+	NodeID   string     `json:"node_id"`
+	GoalName string     `json:"goal_name"`
+	IsAtomic bool       `json:"is_atomic"`
+	Status   NodeStatus `json:"status"`
+	Error    string     `json:"error,omitempty"`
+
+	// Precondition is set when an action's preconditions were not met
+	Precondition *PreconditionError `json:"precondition,omitempty"`
+}
+
+// SimulationReport is the outcome of a dry run: every node that was reached,
+// in the order it finished, and the state the plan would end in.
+type SimulationReport struct {
+	Nodes      []SimulatedNode `json:"nodes"`
+	FinalState WorldState      `json:"final_state"`
+}
+
+// Failures returns the atomic nodes that would fail.
+func (sr *SimulationReport) Failures() []SimulatedNode {
+	return sr.filter(func(node SimulatedNode) bool {
+		return node.IsAtomic && node.Status == StatusFailed
+	})
+}
+
+// Skipped returns the nodes whose goals would already be satisfied.
+func (sr *SimulationReport) Skipped() []SimulatedNode {
+	return sr.filter(func(node SimulatedNode) bool {
+		return node.Status == StatusSkipped
+	})
+}
+
+func (sr *SimulationReport) filter(keep func(SimulatedNode) bool) []SimulatedNode {
+	nodes := []SimulatedNode{}
+	for _, node := range sr.Nodes {
+		if keep(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// String returns a human-readable summary of the dry run.
+func (sr *SimulationReport) String() string {
+	var b strings.Builder
+
+	failures := sr.Failures()
+	skipped := sr.Skipped()
+	fmt.Fprintf(&b, "Dry run: %d nodes reached, %d would fail, %d would be skipped\n",
+		len(sr.Nodes), len(failures), len(skipped))
+
+	for _, node := range failures {
+		fmt.Fprintf(&b, "✗ %s (%s): %s\n", node.GoalName, node.NodeID, node.Error)
+	}
+	for _, node := range skipped {
+		fmt.Fprintf(&b, "⊘ %s (%s): already satisfied\n", node.GoalName, node.NodeID)
+	}
+
+	return b.String()
+}
+
+// record adds a node outcome from its EventNodeFinished event.
+func (sr *SimulationReport) record(event Event, graph *PlanGraph) {
+	node := SimulatedNode{
+		NodeID:   event.NodeID,
+		GoalName: event.GoalName,
+		Status:   event.Status,
+	}
+	if graphNode, exists := graph.Nodes[event.NodeID]; exists {
+		node.IsAtomic = graphNode.IsAtomic
+	}
+	if event.Err != nil {
+		node.Error = event.Err.Error()
+		var precondition *PreconditionError
+		if errors.As(event.Err, &precondition) {
+			node.Precondition = precondition
+		}
+	}
+	sr.Nodes = append(sr.Nodes, node)
+}
+
+// SimulatePlan dry-runs a plan that has not been persisted, e.g. to sanity
+// check it before spending LLM tokens on executing it. It returns the report
+// and, if any node would fail, the error execution would have returned.
+func SimulatePlan(ctx context.Context, plan *HierarchicalPlan, initialState WorldState) (*SimulationReport, error) {
+	const runID = "simulation"
+
+	persistence := NewGraphPersistenceWithStore("", NewMemoryGraphStore())
+	err := persistence.SaveGraph(BuildGraphFromPlan(plan, runID), runID)
+	if err != nil {
+		return nil, err
+	}
+
+	executor := NewGraphExecutor(persistence, runID)
+	executor.RegisterActions(plan.AllActions())
+	executor.RegisterActions(plan.AlternativeActions())
+	executor.SetDryRun(true)
+
+	err = executor.Execute(ctx, initialState)
+	return executor.SimulationReport(), err
+}
@@ -0,0 +1,134 @@
+package goap
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSimulation(t *testing.T) {
+	newPlan := func(executed *int) (*HierarchicalPlan, []Action) {
+		run := func(ctx context.Context, ws WorldState) error {
+			*executed++
+			return nil
+		}
+		build := NewSimpleAction("Build", "Build", WorldState{"code_written": true}, WorldState{"built": true}, 1.0, run)
+		write := NewSimpleAction("Write", "Write", WorldState{}, WorldState{"code_written": true}, 1.0, run)
+		plan := &HierarchicalPlan{
+			Goal: NewGoal("Root", "Root", WorldState{"built": true, "code_written": true}, 1.0),
+			Subplans: []*HierarchicalPlan{
+				{Goal: NewGoal("Build", "Build", WorldState{"built": true}, 1.0), Actions: []Action{build}, Depth: 1},
+				{Goal: NewGoal("Write", "Write", WorldState{"code_written": true}, 1.0), Actions: []Action{write}, Depth: 1},
+			},
+		}
+		return plan, []Action{build, write}
+	}
+
+	t.Run("CatchesUnmetPreconditions", func(t *testing.T) {
+		executed := 0
+		plan, _ := newPlan(&executed)
+
+		report, err := SimulatePlan(context.Background(), plan, NewWorldState())
+		if err == nil {
+			t.Fatal("Expected the misordered plan to fail")
+		}
+		if executed != 0 {
+			t.Errorf("Expected no actions to execute, got %d", executed)
+		}
+
+		failures := report.Failures()
+		if len(failures) != 1 {
+			t.Fatalf("Expected 1 failure, got %d", len(failures))
+		}
+		if failures[0].GoalName != "Build" {
+			t.Errorf("Expected Build to fail, got %s", failures[0].GoalName)
+		}
+		precondition := failures[0].Precondition
+		if precondition == nil {
+			t.Fatal("Expected a precondition error")
+		}
+		if precondition.Action != "Build" || precondition.Unmet["code_written"] != true {
+			t.Errorf("Expected Build's code_written precondition to be unmet, got %v", precondition)
+		}
+
+		// The rest of the plan is still simulated
+		if !report.FinalState.Matches(WorldState{"code_written": true}) {
+			t.Errorf("Expected Write's effects in the final state, got %v", report.FinalState)
+		}
+	})
+
+	t.Run("SkipsSatisfiedGoals", func(t *testing.T) {
+		executed := 0
+		plan, _ := newPlan(&executed)
+		plan.Subplans[0], plan.Subplans[1] = plan.Subplans[1], plan.Subplans[0]
+
+		report, err := SimulatePlan(context.Background(), plan, WorldState{"code_written": true})
+		if err != nil {
+			t.Fatalf("Expected the plan to succeed, got %v", err)
+		}
+		if len(report.Failures()) != 0 {
+			t.Errorf("Expected no failures, got %v", report.Failures())
+		}
+		skipped := report.Skipped()
+		if len(skipped) != 1 || skipped[0].GoalName != "Write" {
+			t.Errorf("Expected Write to be skipped, got %v", skipped)
+		}
+		if !report.FinalState.Matches(WorldState{"built": true}) {
+			t.Errorf("Expected Build's effects in the final state, got %v", report.FinalState)
+		}
+	})
+
+	t.Run("LeavesPersistedRunUntouched", func(t *testing.T) {
+		basePath := t.TempDir()
+		persistence := NewGraphPersistence(basePath)
+		runID := "test-dry-run"
+
+		executed := 0
+		plan, actions := newPlan(&executed)
+		plan.Subplans[0], plan.Subplans[1] = plan.Subplans[1], plan.Subplans[0]
+		if err := persistence.SaveGraph(BuildGraphFromPlan(plan, "test-agent"), runID); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, runID)
+		executor.RegisterActions(actions)
+		executor.SetDryRun(true)
+		published, simulated := 0, 0
+		executor.Events().Subscribe(func(event Event) {
+			published++
+			if event.Simulated {
+				simulated++
+			}
+		})
+		if err := executor.Execute(context.Background(), NewWorldState()); err != nil {
+			t.Fatalf("Dry run failed: %v", err)
+		}
+		if executed != 0 {
+			t.Errorf("Expected no actions to execute, got %d", executed)
+		}
+		if published == 0 || simulated != published {
+			t.Errorf("Expected all %d events to be marked simulated, got %d", published, simulated)
+		}
+
+		report := executor.SimulationReport()
+		if report == nil || len(report.Nodes) != 3 {
+			t.Fatalf("Expected a report of 3 nodes, got %v", report)
+		}
+
+		graph, err := persistence.LoadGraph(runID)
+		if err != nil {
+			t.Fatalf("Failed to load graph: %v", err)
+		}
+		for _, node := range graph.Nodes {
+			if node.Status != StatusPending {
+				t.Errorf("Expected node %s to remain pending, got %s", node.GoalName, node.Status)
+			}
+		}
+		_, err = os.Stat(filepath.Join(basePath, runID, "graph", "checkpoints"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected no checkpoints to be written, got %v", err)
+		}
+	})
+}
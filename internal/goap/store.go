@@ -0,0 +1,63 @@
+package goap
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/log"
+)
+
+// GraphStore stores plan graphs by run ID. Implementations must be safe for
+// concurrent use, and UpdateNode and UpdateGraph must apply their changes
+// atomically: either the whole update is stored or none of it.
+type GraphStore interface {
+	// SaveGraph stores a graph, replacing any graph stored for the run
+	SaveGraph(graph *PlanGraph, runID string) error
+
+	// LoadGraph loads the graph of a run
+	LoadGraph(runID string) (*PlanGraph, error)
+
+	// LoadNodeContext loads the minimal context for executing a node
+	LoadNodeContext(runID, nodeID string) (*NodeContext, error)
+
+	// UpdateNode atomically applies update to a single node. If update
+	// returns an error, nothing is stored.
+	UpdateNode(runID, nodeID string, update func(node *GraphNode) error) error
+
+	// UpdateGraph atomically applies update to a whole graph. If update
+	// returns an error, nothing is stored.
+	UpdateGraph(runID string, update func(graph *PlanGraph) error) error
+
+	// Runs lists the IDs of the runs with a stored graph
+	Runs() ([]string, error)
+
+	// Close releases the store's resources
+	Close() error
+}
+
+// MigrateRuns copies the graph of every run in one store to another, e.g. to
+// move existing JSON run directories into a BoltGraphStore. Runs that already
+// exist in the destination are overwritten. It returns the migrated run IDs.
+func MigrateRuns(from, to GraphStore) ([]string, error) {
+	runIDs, err := from.Runs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	migrated := []string{}
+	for _, runID := range runIDs {
+		graph, err := from.LoadGraph(runID)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to load graph of run %s: %w", runID, err)
+		}
+
+		err = to.SaveGraph(graph, runID)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to save graph of run %s: %w", runID, err)
+		}
+
+		log.Info("Migrated plan graph", "runID", runID, "nodes", len(graph.Nodes))
+		migrated = append(migrated, runID)
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,215 @@
+package goap
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// buildWideGraph builds a graph with a root and n atomic children.
+func buildWideGraph(n int) *PlanGraph {
+	noop := func(ctx context.Context, ws WorldState) error { return nil }
+	subplans := make([]*HierarchicalPlan, n)
+	all := NewWorldState()
+	for i := range subplans {
+		key := fmt.Sprintf("k%d", i)
+		all.Set(key, true)
+		action := NewSimpleAction("Set"+key, "Set "+key, WorldState{}, WorldState{key: true}, 1.0, noop)
+		subplans[i] = &HierarchicalPlan{
+			Goal:    NewGoal("Goal"+key, "Set "+key, WorldState{key: true}, 1.0),
+			Actions: []Action{action},
+			Depth:   1,
+		}
+	}
+	return BuildGraphFromPlan(&HierarchicalPlan{
+		Goal:     NewGoal("Root", "Set all keys", all, 1.0),
+		Subplans: subplans,
+	}, "test-agent")
+}
+
+func TestGraphStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) GraphStore{
+		"JSON": func(t *testing.T) GraphStore {
+			return NewJSONGraphStore(t.TempDir())
+		},
+		"Bolt": func(t *testing.T) GraphStore {
+			store, err := OpenBoltGraphStore(filepath.Join(t.TempDir(), BoltGraphFile))
+			if err != nil {
+				t.Fatalf("Failed to open store: %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("SaveLoadAndNodeContext", func(t *testing.T) {
+				store := open(t)
+				graph := buildWideGraph(3)
+				if err := store.SaveGraph(graph, "run-1"); err != nil {
+					t.Fatalf("Failed to save graph: %v", err)
+				}
+
+				loaded, err := store.LoadGraph("run-1")
+				if err != nil {
+					t.Fatalf("Failed to load graph: %v", err)
+				}
+				if len(loaded.Nodes) != 4 || loaded.RootNodeID != graph.RootNodeID || loaded.Metadata.TotalNodes != 4 {
+					t.Errorf("Loaded graph differs: %d nodes, root %s", len(loaded.Nodes), loaded.RootNodeID)
+				}
+
+				context, err := store.LoadNodeContext("run-1", nodeByGoal(t, graph, "Goalk1").ID)
+				if err != nil {
+					t.Fatalf("Failed to load node context: %v", err)
+				}
+				if context.Parent == nil || context.Parent.ID != graph.RootNodeID || len(context.Siblings) != 2 {
+					t.Errorf("Unexpected node context: %+v", context)
+				}
+				if len(context.PathFromRoot) != 2 || context.PathFromRoot[0] != graph.RootNodeID {
+					t.Errorf("Unexpected path from root: %v", context.PathFromRoot)
+				}
+
+				runs, err := store.Runs()
+				if err != nil || len(runs) != 1 || runs[0] != "run-1" {
+					t.Errorf("Expected [run-1], got %v (%v)", runs, err)
+				}
+			})
+
+			t.Run("ConcurrentNodeUpdates", func(t *testing.T) {
+				store := open(t)
+				graph := buildWideGraph(20)
+				if err := store.SaveGraph(graph, "run-1"); err != nil {
+					t.Fatalf("Failed to save graph: %v", err)
+				}
+
+				var wg sync.WaitGroup
+				for nodeID := range graph.Nodes {
+					wg.Add(1)
+					go func(nodeID string) {
+						defer wg.Done()
+						err := store.UpdateNode("run-1", nodeID, func(node *GraphNode) error {
+							node.Status = StatusCompleted
+							return nil
+						})
+						if err != nil {
+							t.Errorf("Failed to update %s: %v", nodeID, err)
+						}
+					}(nodeID)
+				}
+				wg.Wait()
+
+				loaded, err := store.LoadGraph("run-1")
+				if err != nil {
+					t.Fatalf("Failed to load graph: %v", err)
+				}
+				for nodeID, node := range loaded.Nodes {
+					if node.Status != StatusCompleted {
+						t.Errorf("Update of %s was lost", nodeID)
+					}
+				}
+
+				// Node contexts must reflect the siblings' updates too
+				context, err := store.LoadNodeContext("run-1", nodeByGoal(t, graph, "Goalk0").ID)
+				if err != nil {
+					t.Fatalf("Failed to load node context: %v", err)
+				}
+				for _, sibling := range context.Siblings {
+					if sibling.Status != StatusCompleted {
+						t.Errorf("Stale sibling %s in node context", sibling.ID)
+					}
+				}
+			})
+
+			t.Run("FailedUpdateStoresNothing", func(t *testing.T) {
+				store := open(t)
+				graph := buildWideGraph(1)
+				if err := store.SaveGraph(graph, "run-1"); err != nil {
+					t.Fatalf("Failed to save graph: %v", err)
+				}
+				child := nodeByGoal(t, graph, "Goalk0").ID
+
+				err := store.UpdateNode("run-1", child, func(node *GraphNode) error {
+					node.Status = StatusFailed
+					return fmt.Errorf("abort")
+				})
+				if err == nil {
+					t.Fatal("Expected the update error to be returned")
+				}
+
+				loaded, _ := store.LoadGraph("run-1")
+				if loaded.Nodes[child].Status != StatusPending {
+					t.Errorf("Aborted update should not be stored, got %s", loaded.Nodes[child].Status)
+				}
+			})
+		})
+	}
+
+	t.Run("MigrateJSONToBolt", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		from := NewJSONGraphStore(tmpDir)
+		for _, runID := range []string{"run-1", "run-2"} {
+			if err := from.SaveGraph(buildWideGraph(2), runID); err != nil {
+				t.Fatalf("Failed to save graph: %v", err)
+			}
+		}
+
+		to, err := OpenBoltGraphStore(filepath.Join(tmpDir, BoltGraphFile))
+		if err != nil {
+			t.Fatalf("Failed to open store: %v", err)
+		}
+		defer to.Close()
+
+		migrated, err := MigrateRuns(from, to)
+		if err != nil {
+			t.Fatalf("Migration failed: %v", err)
+		}
+		if len(migrated) != 2 {
+			t.Errorf("Expected 2 migrated runs, got %v", migrated)
+		}
+
+		graph, err := to.LoadGraph("run-2")
+		if err != nil || len(graph.Nodes) != 3 {
+			t.Errorf("Migrated graph not loadable: %v", err)
+		}
+	})
+
+	t.Run("ExecuteOnBoltStore", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		store, err := OpenBoltGraphStore(filepath.Join(tmpDir, BoltGraphFile))
+		if err != nil {
+			t.Fatalf("Failed to open store: %v", err)
+		}
+		defer store.Close()
+
+		persistence := NewGraphPersistenceWithStore(tmpDir, store)
+		graph := buildWideGraph(4)
+		if err := persistence.SaveGraph(graph, "run-1"); err != nil {
+			t.Fatalf("Failed to save graph: %v", err)
+		}
+
+		executor := NewGraphExecutor(persistence, "run-1")
+		for _, node := range graph.Nodes {
+			for _, name := range node.ActionNames {
+				key := name[len("Set"):]
+				executor.RegisterAction(NewSimpleAction(name, name, WorldState{}, WorldState{key: true}, 1.0,
+					func(ctx context.Context, ws WorldState) error { return nil }))
+			}
+		}
+		executor.SetMaxWorkers(4)
+
+		if err := executor.Execute(context.Background(), NewWorldState()); err != nil {
+			t.Fatalf("Execution failed: %v", err)
+		}
+
+		status, err := executor.GetGraphStatus()
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		if !status.IsComplete() || status.CompletedNodes != 5 {
+			t.Errorf("Expected all 5 nodes completed, got %+v", status)
+		}
+	})
+}
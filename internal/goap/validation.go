@@ -0,0 +1,250 @@
+package goap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlanProblem describes one defect found in a plan graph.
+type PlanProblem struct {
+	// NodeID is the node the problem was found on, or empty for problems
+	// with the graph as a whole
+	NodeID   string `json:"node_id,omitempty"`
+	GoalName string `json:"goal_name,omitempty"`
+	Message  string `json:"message"`
+}
+
+// String returns the problem prefixed with the node it was found on.
+func (pp PlanProblem) String() string {
+	if pp.NodeID == "" {
+		return pp.Message
+	}
+	return fmt.Sprintf("%s (%s): %s", pp.GoalName, pp.NodeID, pp.Message)
+}
+
+// PlanValidationError is returned when a plan graph fails validation. It
+// lists every problem found, not just the first, so that a broken plan can be
+// fixed in one go.
+type PlanValidationError struct {
+	Problems []PlanProblem
+}
+
+// Error implements the error interface.
+func (pve *PlanValidationError) Error() string {
+	messages := make([]string, len(pve.Problems))
+	for i, problem := range pve.Problems {
+		messages[i] = problem.String()
+	}
+	return fmt.Sprintf("invalid plan graph: %d problems: %s", len(pve.Problems), strings.Join(messages, "; "))
+}
+
+// Report returns a multi-line, human-readable list of the problems.
+func (pve *PlanValidationError) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan graph has %d problems:\n", len(pve.Problems))
+	for _, problem := range pve.Problems {
+		fmt.Fprintf(&b, "  - %s\n", problem)
+	}
+	return b.String()
+}
+
+// ValidateGraph checks a plan graph against the actions that will be
+// registered with its executor, before execution starts. Execution would
+// otherwise only discover most of these problems midway through a run:
+//
+//   - every action name, including alternatives, resolves to an action
+//   - parent and child links agree, and every node is reachable from the root
+//   - the hierarchy and the sibling dependencies contain no cycles
+//   - atomic nodes have actions, unless their goal already holds
+//   - composite nodes have children
+//   - every desired-state value holds initially or is produced by an action
+//
+// It returns a *PlanValidationError listing all problems, or nil.
+func ValidateGraph(graph *PlanGraph, actions []Action, initialState WorldState) error {
+	v := &graphValidator{
+		graph:        graph,
+		actions:      make(map[string]Action, len(actions)),
+		initialState: initialState,
+	}
+	for _, action := range actions {
+		v.actions[action.Name()] = action
+	}
+
+	v.checkStructure()
+	for _, id := range graph.nodeIDs() {
+		v.checkNode(graph.Nodes[id])
+	}
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &PlanValidationError{Problems: v.problems}
+}
+
+type graphValidator struct {
+	graph        *PlanGraph
+	actions      map[string]Action
+	initialState WorldState
+	problems     []PlanProblem
+}
+
+func (v *graphValidator) addProblem(node *GraphNode, format string, args ...interface{}) {
+	problem := PlanProblem{Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		problem.NodeID = node.ID
+		problem.GoalName = node.GoalName
+	}
+	v.problems = append(v.problems, problem)
+}
+
+// checkStructure walks the hierarchy from the root, checking links and
+// looking for cycles and unreachable nodes.
+func (v *graphValidator) checkStructure() {
+	root, exists := v.graph.Nodes[v.graph.RootNodeID]
+	if !exists {
+		v.addProblem(nil, "root node %q not found", v.graph.RootNodeID)
+		return
+	}
+	if root.ParentID != "" {
+		v.addProblem(root, "root node has parent %s", root.ParentID)
+	}
+
+	visited := make(map[string]bool, len(v.graph.Nodes))
+	onPath := make(map[string]bool)
+	var visit func(node *GraphNode)
+	visit = func(node *GraphNode) {
+		visited[node.ID] = true
+		onPath[node.ID] = true
+		defer delete(onPath, node.ID)
+
+		for _, childID := range node.ChildIDs {
+			child, exists := v.graph.Nodes[childID]
+			switch {
+			case !exists:
+				v.addProblem(node, "child %s not found", childID)
+			case onPath[childID]:
+				v.addProblem(node, "child %s is also an ancestor, forming a cycle", childID)
+			case child.ParentID != node.ID:
+				v.addProblem(child, "listed as child of %s but has parent %q", node.ID, child.ParentID)
+			case visited[childID]:
+				v.addProblem(child, "listed as child more than once")
+			default:
+				visit(child)
+			}
+		}
+	}
+	visit(root)
+
+	for _, id := range v.graph.nodeIDs() {
+		node := v.graph.Nodes[id]
+		if node.ID != id {
+			v.addProblem(node, "stored under ID %s", id)
+		}
+		if !visited[id] {
+			v.addProblem(node, "not reachable from the root")
+		}
+	}
+}
+
+// checkNode checks a single node's actions, children, dependencies and
+// desired state.
+func (v *graphValidator) checkNode(node *GraphNode) {
+	if node.IsAtomic {
+		if len(node.ChildIDs) > 0 {
+			v.addProblem(node, "atomic node has %d children", len(node.ChildIDs))
+		}
+		if len(node.ActionNames) == 0 && !v.initialState.Matches(node.DesiredState) {
+			v.addProblem(node, "atomic node has no actions and its goal does not hold initially")
+		}
+		for _, name := range node.ActionNames {
+			if _, exists := v.actions[name]; !exists {
+				v.addProblem(node, "action %s not found", name)
+			}
+		}
+		for i, alternative := range node.Alternatives {
+			for _, name := range alternative {
+				if _, exists := v.actions[name]; !exists {
+					v.addProblem(node, "action %s of alternative %d not found", name, i+1)
+				}
+			}
+		}
+	} else {
+		if len(node.ChildIDs) == 0 {
+			v.addProblem(node, "composite node has no children")
+		}
+		if len(node.ActionNames) > 0 {
+			v.addProblem(node, "composite node has %d actions", len(node.ActionNames))
+		}
+		v.checkDependencies(node)
+	}
+
+	for _, key := range sortedKeys(node.DesiredState) {
+		value := node.DesiredState[key]
+		if v.initialState.Has(key) && stateValuesEqual(v.initialState.Get(key), value) {
+			continue
+		}
+		if !v.produced(key, value) {
+			v.addProblem(node, "desired %s=%v does not hold initially and no action produces it", key, value)
+		}
+	}
+}
+
+// checkDependencies checks that a composite node's children only depend on
+// their siblings and that their dependencies contain no cycle, which would
+// leave the children waiting on each other forever.
+func (v *graphValidator) checkDependencies(node *GraphNode) {
+	siblings := make(map[string]bool, len(node.ChildIDs))
+	for _, childID := range node.ChildIDs {
+		siblings[childID] = true
+	}
+
+	pending := []*GraphNode{}
+	for _, childID := range node.ChildIDs {
+		child, exists := v.graph.Nodes[childID]
+		if !exists {
+			continue
+		}
+		for _, dependency := range child.DependsOn {
+			if !siblings[dependency] {
+				v.addProblem(child, "depends on %s, which is not a sibling", dependency)
+			}
+		}
+		pending = append(pending, child)
+	}
+
+	done := make(map[string]bool, len(pending))
+	for progressed := true; progressed; {
+		progressed = false
+		remaining := pending[:0]
+		for _, child := range pending {
+			if dependenciesCompleted(child, siblings, done) {
+				done[child.ID] = true
+				progressed = true
+			} else {
+				remaining = append(remaining, child)
+			}
+		}
+		pending = remaining
+	}
+
+	if len(pending) > 0 {
+		ids := make([]string, len(pending))
+		for i, child := range pending {
+			ids[i] = child.ID
+		}
+		sort.Strings(ids)
+		v.addProblem(node, "dependencies of children %s contain a cycle", strings.Join(ids, ", "))
+	}
+}
+
+// produced reports whether any action sets key to value.
+func (v *graphValidator) produced(key string, value interface{}) bool {
+	for _, action := range v.actions {
+		effect, exists := action.Effects()[key]
+		if exists && stateValuesEqual(effect, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package goap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestValidateGraph(t *testing.T) {
+	noop := func(ctx context.Context, ws WorldState) error { return nil }
+	write := NewSimpleAction("Write", "Write", WorldState{}, WorldState{"written": true}, 1.0, noop)
+	build := NewSimpleAction("Build", "Build", WorldState{"written": true}, WorldState{"built": true}, 1.0, noop)
+
+	newGraph := func() *PlanGraph {
+		plan := &HierarchicalPlan{
+			Goal: NewGoal("Root", "Root", WorldState{"written": true, "built": true}, 1.0),
+			Subplans: []*HierarchicalPlan{
+				{Goal: NewGoal("Write", "Write", WorldState{"written": true}, 1.0), Actions: []Action{write}, Depth: 1},
+				{Goal: NewGoal("Build", "Build", WorldState{"built": true}, 1.0), Actions: []Action{build}, Depth: 1},
+			},
+		}
+		return BuildGraphFromPlan(plan, "test-agent")
+	}
+
+	problems := func(t *testing.T, err error) []PlanProblem {
+		t.Helper()
+		var invalid *PlanValidationError
+		if !errors.As(err, &invalid) {
+			t.Fatalf("Expected a PlanValidationError, got %v", err)
+		}
+		return invalid.Problems
+	}
+
+	t.Run("ValidGraph", func(t *testing.T) {
+		err := ValidateGraph(newGraph(), []Action{write, build}, NewWorldState())
+		if err != nil {
+			t.Errorf("Expected a valid graph, got %v", err)
+		}
+	})
+
+	t.Run("ReportsAllProblems", func(t *testing.T) {
+		graph := newGraph()
+		nodeByGoal(t, graph, "Build").DesiredState["deployed"] = true
+
+		// Build is not registered, so built is unproducible too
+		found := problems(t, ValidateGraph(graph, []Action{write}, NewWorldState()))
+
+		expected := []string{"action Build not found", "desired built=true", "desired deployed=true"}
+		for _, message := range expected {
+			matched := false
+			for _, problem := range found {
+				matched = matched || strings.Contains(problem.Message, message)
+			}
+			if !matched {
+				t.Errorf("Expected a problem containing %q, got %v", message, found)
+			}
+		}
+	})
+
+	t.Run("BrokenLinks", func(t *testing.T) {
+		graph := newGraph()
+		writeNode := nodeByGoal(t, graph, "Write")
+		writeNode.ParentID = nodeByGoal(t, graph, "Build").ID
+		graph.Nodes["orphan"] = &GraphNode{ID: "orphan", GoalName: "Orphan", IsAtomic: true, ActionNames: []string{"Write"}}
+
+		found := problems(t, ValidateGraph(graph, []Action{write, build}, NewWorldState()))
+		byNode := make(map[string]string)
+		for _, problem := range found {
+			byNode[problem.NodeID] += problem.Message
+		}
+		if !strings.Contains(byNode[writeNode.ID], "has parent") {
+			t.Errorf("Expected Write's parent link to be reported, got %v", found)
+		}
+		if !strings.Contains(byNode["orphan"], "not reachable") {
+			t.Errorf("Expected the orphan to be reported, got %v", found)
+		}
+	})
+
+	t.Run("Cycles", func(t *testing.T) {
+		graph := newGraph()
+		writeNode, buildNode := nodeByGoal(t, graph, "Write"), nodeByGoal(t, graph, "Build")
+		writeNode.DependsOn = []string{buildNode.ID}
+		buildNode.IsAtomic = false
+		buildNode.ActionNames = nil
+		buildNode.ChildIDs = []string{graph.RootNodeID}
+
+		found := problems(t, ValidateGraph(graph, []Action{write, build}, NewWorldState()))
+		var messages []string
+		for _, problem := range found {
+			messages = append(messages, problem.Message)
+		}
+		joined := strings.Join(messages, "\n")
+		if !strings.Contains(joined, "forming a cycle") {
+			t.Errorf("Expected the hierarchy cycle to be reported, got %v", found)
+		}
+		children := []string{writeNode.ID, buildNode.ID}
+		sort.Strings(children)
+		if !strings.Contains(joined, "dependencies of children "+strings.Join(children, ", ")+" contain a cycle") {
+			t.Errorf("Expected the dependency cycle to be reported, got %v", found)
+		}
+	})
+
+	t.Run("AtomicAndCompositeShape", func(t *testing.T) {
+		graph := newGraph()
+		nodeByGoal(t, graph, "Write").ActionNames = nil
+		nodeByGoal(t, graph, "Build").IsAtomic = false
+		nodeByGoal(t, graph, "Build").ActionNames = nil
+
+		found := problems(t, ValidateGraph(graph, []Action{write, build}, NewWorldState()))
+		if len(found) != 2 {
+			t.Errorf("Expected 2 problems, got %v", found)
+		}
+
+		// An atomic node without actions is fine if its goal already holds
+		graph = newGraph()
+		nodeByGoal(t, graph, "Write").ActionNames = nil
+		err := ValidateGraph(graph, []Action{write, build}, WorldState{"written": true})
+		if err != nil {
+			t.Errorf("Expected a valid graph, got %v", err)
+		}
+	})
+
+	t.Run("ExecutorResolvesSpecs", func(t *testing.T) {
+		var ran []string
+		var mu sync.Mutex
+		plan := &HierarchicalPlan{
+			Goal:    NewGoal("Write", "Write", WorldState{"wrote_a": true}, 1.0),
+			Actions: []Action{newFileAction("a", &ran, &mu)},
+		}
+		graph := BuildGraphFromPlan(plan, "test-agent")
+
+		// Without the action or a registry to rebuild it, the graph cannot run
+		executor := NewGraphExecutor(NewGraphPersistence(t.TempDir()), "test-validate")
+		found := problems(t, executor.ValidateGraph(graph, NewWorldState()))
+		if len(found) != 2 {
+			t.Errorf("Expected the missing action and its unproduced effect, got %v", found)
+		}
+
+		registry := NewActionRegistry()
+		registry.Register("test.WriteFile", func(params json.RawMessage) (Action, error) {
+			var p struct{ Path string }
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, err
+			}
+			return newFileAction(p.Path, &ran, &mu), nil
+		})
+		executor.SetActionRegistry(registry)
+		if err := executor.ValidateGraph(graph, NewWorldState()); err != nil {
+			t.Errorf("Expected the registry to rebuild the action, got %v", err)
+		}
+	})
+}
@@ -1,11 +1,42 @@
 package goap
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateMarker is a special effect value that changes how WorldState.Apply
+// treats a key instead of storing the value itself.
+type StateMarker string
+
+const (
+	// Deleted, used as an effect value, removes the key from the state.
+	Deleted StateMarker = "<deleted>"
+
+	// Unknown, used as an effect value, marks the key's value as unknown.
+	// An unknown key is present but satisfies no condition other than Unknown.
+	Unknown StateMarker = "<unknown>"
 )
 
+// stateValue returns the marker a plain string spells, since markers come
+// back from JSON and YAML as strings, and any other value unchanged.
+func stateValue(value interface{}) interface{} {
+	if text, ok := value.(string); ok {
+		switch StateMarker(text) {
+		case Deleted, Unknown:
+			return StateMarker(text)
+		}
+	}
+	return value
+}
+
 // WorldState represents the current state of the world as a set of key-value pairs.
 // Keys are strings (state variables) and values are interface{} to support any type.
 type WorldState map[string]interface{}
@@ -49,7 +80,7 @@ func (ws WorldState) Matches(conditions WorldState) bool {
 		if !exists {
 			return false
 		}
-		if actualValue != expectedValue {
+		if !stateValuesEqual(actualValue, expectedValue) {
 			return false
 		}
 	}
@@ -57,12 +88,106 @@ func (ws WorldState) Matches(conditions WorldState) bool {
 }
 
 // Apply merges another WorldState into this one, overwriting existing values.
+// A change whose value is Deleted removes the key instead.
 func (ws WorldState) Apply(changes WorldState) {
 	for key, value := range changes {
+		value = stateValue(value)
+		if value == Deleted {
+			delete(ws, key)
+			continue
+		}
 		ws[key] = value
 	}
 }
 
+// UnmarshalJSON decodes a WorldState, restoring the markers among its values
+// and decoding integral numbers as int, as YAML does, so that they still
+// match the int values actions set.
+func (ws *WorldState) UnmarshalJSON(data []byte) error {
+	var values map[string]interface{}
+	if err := decodeStateJSON(data, &values); err != nil {
+		return err
+	}
+	*ws = restoreMarkers(values)
+	return nil
+}
+
+// decodeStateJSON decodes JSON into v like json.Unmarshal, except that
+// numbers in interface{} values become int when integral and float64
+// otherwise.
+func decodeStateJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	switch target := v.(type) {
+	case *map[string]interface{}:
+		for key, value := range *target {
+			(*target)[key] = restoreNumbers(value)
+		}
+	case *interface{}:
+		*target = restoreNumbers(*target)
+	}
+	return nil
+}
+
+// restoreNumbers replaces the json.Numbers in a decoded value, including
+// those nested in slices and maps.
+func restoreNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(v.String(), 10, 0); err == nil {
+			return int(i)
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = restoreNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = restoreNumbers(v[key])
+		}
+	}
+	return value
+}
+
+// UnmarshalYAML decodes a WorldState, restoring the markers among its values.
+func (ws *WorldState) UnmarshalYAML(node *yaml.Node) error {
+	var values map[string]interface{}
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*ws = restoreMarkers(values)
+	return nil
+}
+
+func restoreMarkers(values map[string]interface{}) WorldState {
+	if values == nil {
+		return nil
+	}
+	ws := make(WorldState, len(values))
+	for key, value := range values {
+		ws[key] = stateValue(value)
+	}
+	return ws
+}
+
+// Delete removes a key from the WorldState.
+func (ws WorldState) Delete(key string) {
+	delete(ws, key)
+}
+
+// Invalidate removes each of the given keys, so that conditions depending on
+// them must be re-established.
+func (ws WorldState) Invalidate(keys []string) {
+	for _, key := range keys {
+		delete(ws, key)
+	}
+}
+
 // Diff returns the keys that differ between this WorldState and another.
 func (ws WorldState) Diff(other WorldState) []string {
 	differences := []string{}
@@ -70,7 +195,7 @@ func (ws WorldState) Diff(other WorldState) []string {
 	// Check keys in ws that differ from other
 	for key, value := range ws {
 		otherValue, exists := other[key]
-		if !exists || otherValue != value {
+		if !exists || !stateValuesEqual(otherValue, value) {
 			differences = append(differences, key)
 		}
 	}
@@ -85,13 +210,37 @@ func (ws WorldState) Diff(other WorldState) []string {
 	return differences
 }
 
+// Changes returns the changes that turn before into this WorldState, with
+// removed keys mapped to Deleted, so that applying them to before yields ws.
+func (ws WorldState) Changes(before WorldState) WorldState {
+	changes := NewWorldState()
+	for key, value := range ws {
+		if beforeValue, exists := before[key]; !exists || !stateValuesEqual(beforeValue, value) {
+			changes[key] = value
+		}
+	}
+	for key := range before {
+		if _, exists := ws[key]; !exists {
+			changes[key] = Deleted
+		}
+	}
+	return changes
+}
+
+// stateValuesEqual compares state values exactly, so that int 80 and
+// float64 80 differ, but without panicking on values that are not
+// comparable, such as slices and maps.
+func stateValuesEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
 // Distance calculates a heuristic distance to a goal state.
 // This is used for A* pathfinding. Returns the number of mismatched conditions.
 func (ws WorldState) Distance(goal WorldState) int {
 	distance := 0
 	for key, goalValue := range goal {
 		currentValue, exists := ws[key]
-		if !exists || currentValue != goalValue {
+		if !exists || !stateValuesEqual(currentValue, goalValue) {
 			distance++
 		}
 	}